@@ -0,0 +1,7 @@
+-- +goose Up
+alter table groups
+    add column external_id varchar(255) null default null;
+
+-- +goose Down
+alter table groups
+    drop column external_id;
@@ -0,0 +1,7 @@
+-- +goose Up
+alter table users
+    add column enterprise_user jsonb null default null;
+
+-- +goose Down
+alter table users
+    drop column enterprise_user;
@@ -0,0 +1,31 @@
+-- +goose Up
+alter table users
+    add column nickname           varchar(255) null default null,
+    add column profile_url        varchar(255) null default null,
+    add column title              varchar(255) null default null,
+    add column user_type          varchar(255) null default null,
+    add column preferred_language varchar(255) null default null,
+    add column timezone           varchar(255) null default null,
+    add column phone_numbers      jsonb        null default null,
+    add column ims                jsonb        null default null,
+    add column photos             jsonb        null default null,
+    add column addresses          jsonb        null default null,
+    add column entitlements       jsonb        null default null,
+    add column roles              jsonb        null default null,
+    add column x509_certificates  jsonb        null default null;
+
+-- +goose Down
+alter table users
+    drop column nickname,
+    drop column profile_url,
+    drop column title,
+    drop column user_type,
+    drop column preferred_language,
+    drop column timezone,
+    drop column phone_numbers,
+    drop column ims,
+    drop column photos,
+    drop column addresses,
+    drop column entitlements,
+    drop column roles,
+    drop column x509_certificates;
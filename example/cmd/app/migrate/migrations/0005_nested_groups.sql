@@ -0,0 +1,11 @@
+-- +goose Up
+create table group_groups
+(
+    group_id        uuid not null references groups (id) on delete cascade,
+    member_group_id uuid not null references groups (id) on delete cascade,
+    unique (group_id, member_group_id),
+    check (group_id <> member_group_id)
+);
+
+-- +goose Down
+drop table group_groups;
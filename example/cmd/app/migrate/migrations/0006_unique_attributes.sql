@@ -0,0 +1,59 @@
+-- +goose Up
+-- empty external ids are unassigned, they must not conflict with each other
+update users
+set external_id = null
+where external_id = '';
+
+update groups
+set external_id = null
+where external_id = '';
+
+-- other duplicates can't be resolved automatically, as clients identify the resources by these
+-- values: the migration fails and lists them, so that they can be fixed before running it again
+-- +goose StatementBegin
+do
+$$
+    declare
+        duplicates text;
+    begin
+        select string_agg(format('%s %s %L', resource, attribute, value), ', ')
+        into duplicates
+        from (select 'user' as resource, 'externalId' as attribute, external_id as value
+              from users
+              where external_id is not null
+              group by external_id
+              having count(*) > 1
+              union all
+              select 'group', 'displayName', display_name
+              from groups
+              group by display_name
+              having count(*) > 1
+              union all
+              select 'group', 'externalId', external_id
+              from groups
+              where external_id is not null
+              group by external_id
+              having count(*) > 1) as duplicate;
+
+        if duplicates is not null then
+            raise exception 'cannot add unique constraints, the following values are not unique: %', duplicates
+                using hint = 'change or remove the duplicate values, then run the migration again';
+        end if;
+    end
+$$;
+-- +goose StatementEnd
+
+alter table users
+    add constraint users_external_id_key unique (external_id);
+
+alter table groups
+    add constraint groups_display_name_key unique (display_name),
+    add constraint groups_external_id_key unique (external_id);
+
+-- +goose Down
+alter table groups
+    drop constraint groups_external_id_key,
+    drop constraint groups_display_name_key;
+
+alter table users
+    drop constraint users_external_id_key;
@@ -0,0 +1,13 @@
+-- +goose Up
+alter table users
+    add column extensions jsonb null default null;
+
+alter table groups
+    add column extensions jsonb null default null;
+
+-- +goose Down
+alter table groups
+    drop column extensions;
+
+alter table users
+    drop column extensions;
@@ -33,6 +33,18 @@ func NewCmd(app *application.App) *cobra.Command {
 
 			db := scimbridgedb.New(app)
 			b := bridge.New(&db, baseURL)
+			b.SortSupported = true
+			b.ETagSupported = true
+			b.IndirectGroups = true
+			b.AuthenticationSchemes = []bridge.AuthenticationScheme{
+				{
+					Type:        "oauthbearertoken",
+					Name:        "OAuth Bearer Token",
+					Description: "Authentication with the API key created by the scim gen-api-key command",
+					SpecURI:     "https://www.rfc-editor.org/info/rfc6750",
+					Primary:     true,
+				},
+			}
 			router.Hook(r, &b, authMiddleware)
 
 			s := &http.Server{
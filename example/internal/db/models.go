@@ -27,6 +27,13 @@ type Group struct {
 	DisplayName string
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
+	ExternalID  sql.NullString
+	Extensions  pgtype.JSONB
+}
+
+type GroupGroup struct {
+	GroupID       uuid.UUID
+	MemberGroupID uuid.UUID
 }
 
 type GroupUser struct {
@@ -43,14 +50,29 @@ type ScimApiKey struct {
 }
 
 type User struct {
-	ID          uuid.UUID
-	Username    string
-	ExternalID  sql.NullString
-	Name        pgtype.JSONB
-	DisplayName sql.NullString
-	Locale      sql.NullString
-	Active      bool
-	Emails      pgtype.JSONB
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
+	ID                uuid.UUID
+	Username          string
+	ExternalID        sql.NullString
+	Name              pgtype.JSONB
+	DisplayName       sql.NullString
+	Locale            sql.NullString
+	Active            bool
+	Emails            pgtype.JSONB
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	EnterpriseUser    pgtype.JSONB
+	Nickname          sql.NullString
+	ProfileUrl        sql.NullString
+	Title             sql.NullString
+	UserType          sql.NullString
+	PreferredLanguage sql.NullString
+	Timezone          sql.NullString
+	PhoneNumbers      pgtype.JSONB
+	Ims               pgtype.JSONB
+	Photos            pgtype.JSONB
+	Addresses         pgtype.JSONB
+	Entitlements      pgtype.JSONB
+	Roles             pgtype.JSONB
+	X509Certificates  pgtype.JSONB
+	Extensions        pgtype.JSONB
 }
@@ -6,12 +6,14 @@ package db
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 type Querier interface {
-	CreateGroup(ctx context.Context, displayName string) (Group, error)
+	CreateGroup(ctx context.Context, arg CreateGroupParams) (Group, error)
+	CreateMembershipForGroupAndGroup(ctx context.Context, arg CreateMembershipForGroupAndGroupParams) error
 	CreateMembershipForUserAndGroup(ctx context.Context, arg CreateMembershipForUserAndGroupParams) error
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
 	DeleteAPIKey(ctx context.Context, id uuid.UUID) error
@@ -19,6 +21,7 @@ type Querier interface {
 	DeleteScimAPIKey(ctx context.Context) error
 	DeleteUser(ctx context.Context, id uuid.UUID) error
 	DropMembershipForGroup(ctx context.Context, groupID uuid.UUID) error
+	DropMembershipForGroupAndGroup(ctx context.Context, arg DropMembershipForGroupAndGroupParams) error
 	DropMembershipForUserAndGroup(ctx context.Context, arg DropMembershipForUserAndGroupParams) error
 	FindAPIKey(ctx context.Context, id uuid.UUID) (ApiKey, error)
 	FindAPIKeysById(ctx context.Context, dollar_1 []uuid.UUID) ([]ApiKey, error)
@@ -36,6 +39,10 @@ type Querier interface {
 	// Groups
 	//------------------------------------------------------------------------------------------------------------------
 	GetGroups(ctx context.Context, arg GetGroupsParams) ([]Group, error)
+	GetGroupsForUser(ctx context.Context, userID uuid.UUID) ([]GetGroupsForUserRow, error)
+	GetIndirectGroupsForUser(ctx context.Context, userID uuid.UUID) ([]GetIndirectGroupsForUserRow, error)
+	GetNestedGroupIDs(ctx context.Context, groupID uuid.UUID) ([]uuid.UUID, error)
+	GetNestedGroupMembership(ctx context.Context, groupID uuid.UUID) ([]GetNestedGroupMembershipRow, error)
 	GetUser(ctx context.Context, id uuid.UUID) (User, error)
 	GetUserCount(ctx context.Context) (int64, error)
 	//------------------------------------------------------------------------------------------------------------------
@@ -48,8 +55,18 @@ type Querier interface {
 	//------------------------------------------------------------------------------------------------------------------
 	InsertAPIKey(ctx context.Context, arg InsertAPIKeyParams) (ApiKey, error)
 	InsertScimAPIKey(ctx context.Context, apiKeyID uuid.UUID) (ScimApiKey, error)
-	PatchGroupDisplayName(ctx context.Context, arg PatchGroupDisplayNameParams) error
+	LockGroup(ctx context.Context, id uuid.UUID) (time.Time, error)
+	LockUser(ctx context.Context, id uuid.UUID) (time.Time, error)
 	PatchUser(ctx context.Context, arg PatchUserParams) error
+	// nesting a group changes the indirect groups of its users, directly or through nested groups
+	TouchGroupUsers(ctx context.Context, id uuid.UUID) error
+	// deleting a user removes it from the members of its groups
+	TouchGroupsForUser(ctx context.Context, userID uuid.UUID) error
+	// deleting a group removes it from the members of its parent groups
+	TouchParentGroups(ctx context.Context, memberGroupID uuid.UUID) error
+	// users list their groups, so membership changes update them
+	TouchUser(ctx context.Context, id uuid.UUID) error
+	UpdateGroup(ctx context.Context, arg UpdateGroupParams) error
 	UpdateUser(ctx context.Context, arg UpdateUserParams) error
 }
 
@@ -8,29 +8,54 @@ package db
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgtype"
 )
 
 const createGroup = `-- name: CreateGroup :one
-insert into groups (display_name, created_at, updated_at)
-values ($1, now(), now())
-returning id, display_name, created_at, updated_at
+insert into groups (display_name, external_id, extensions, created_at, updated_at)
+values ($1, $2, $3, now(), now())
+returning id, display_name, created_at, updated_at, external_id, extensions
 `
 
-func (q *Queries) CreateGroup(ctx context.Context, displayName string) (Group, error) {
-	row := q.db.QueryRow(ctx, createGroup, displayName)
+type CreateGroupParams struct {
+	DisplayName string
+	ExternalID  sql.NullString
+	Extensions  pgtype.JSONB
+}
+
+func (q *Queries) CreateGroup(ctx context.Context, arg CreateGroupParams) (Group, error) {
+	row := q.db.QueryRow(ctx, createGroup, arg.DisplayName, arg.ExternalID, arg.Extensions)
 	var i Group
 	err := row.Scan(
 		&i.ID,
 		&i.DisplayName,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ExternalID,
+		&i.Extensions,
 	)
 	return i, err
 }
 
+const createMembershipForGroupAndGroup = `-- name: CreateMembershipForGroupAndGroup :exec
+insert into group_groups (member_group_id, group_id)
+values ($1, $2)
+on conflict (group_id, member_group_id) do nothing
+`
+
+type CreateMembershipForGroupAndGroupParams struct {
+	MemberGroupID uuid.UUID
+	GroupID       uuid.UUID
+}
+
+func (q *Queries) CreateMembershipForGroupAndGroup(ctx context.Context, arg CreateMembershipForGroupAndGroupParams) error {
+	_, err := q.db.Exec(ctx, createMembershipForGroupAndGroup, arg.MemberGroupID, arg.GroupID)
+	return err
+}
+
 const createMembershipForUserAndGroup = `-- name: CreateMembershipForUserAndGroup :exec
 insert into group_users (user_id, group_id)
 values ($1, $2)
@@ -48,19 +73,36 @@ func (q *Queries) CreateMembershipForUserAndGroup(ctx context.Context, arg Creat
 }
 
 const createUser = `-- name: CreateUser :one
-insert into users (username, name, display_name, emails, active, locale, external_id, created_at, updated_at)
-values ($1, $2, $3, $4, $5, $6, $7, now(), now())
-returning id, username, external_id, name, display_name, locale, active, emails, created_at, updated_at
+insert into users (username, name, display_name, emails, active, locale, external_id, enterprise_user, nickname,
+                   profile_url, title, user_type, preferred_language, timezone, phone_numbers, ims, photos, addresses,
+                   entitlements, roles, x509_certificates, extensions, created_at, updated_at)
+values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, now(), now())
+returning id, username, external_id, name, display_name, locale, active, emails, created_at, updated_at, enterprise_user, nickname, profile_url, title, user_type, preferred_language, timezone, phone_numbers, ims, photos, addresses, entitlements, roles, x509_certificates, extensions
 `
 
 type CreateUserParams struct {
-	Username    string
-	Name        pgtype.JSONB
-	DisplayName sql.NullString
-	Emails      pgtype.JSONB
-	Active      bool
-	Locale      sql.NullString
-	ExternalID  sql.NullString
+	Username          string
+	Name              pgtype.JSONB
+	DisplayName       sql.NullString
+	Emails            pgtype.JSONB
+	Active            bool
+	Locale            sql.NullString
+	ExternalID        sql.NullString
+	EnterpriseUser    pgtype.JSONB
+	Nickname          sql.NullString
+	ProfileUrl        sql.NullString
+	Title             sql.NullString
+	UserType          sql.NullString
+	PreferredLanguage sql.NullString
+	Timezone          sql.NullString
+	PhoneNumbers      pgtype.JSONB
+	Ims               pgtype.JSONB
+	Photos            pgtype.JSONB
+	Addresses         pgtype.JSONB
+	Entitlements      pgtype.JSONB
+	Roles             pgtype.JSONB
+	X509Certificates  pgtype.JSONB
+	Extensions        pgtype.JSONB
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
@@ -72,6 +114,21 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		arg.Active,
 		arg.Locale,
 		arg.ExternalID,
+		arg.EnterpriseUser,
+		arg.Nickname,
+		arg.ProfileUrl,
+		arg.Title,
+		arg.UserType,
+		arg.PreferredLanguage,
+		arg.Timezone,
+		arg.PhoneNumbers,
+		arg.Ims,
+		arg.Photos,
+		arg.Addresses,
+		arg.Entitlements,
+		arg.Roles,
+		arg.X509Certificates,
+		arg.Extensions,
 	)
 	var i User
 	err := row.Scan(
@@ -85,6 +142,21 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Emails,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EnterpriseUser,
+		&i.Nickname,
+		&i.ProfileUrl,
+		&i.Title,
+		&i.UserType,
+		&i.PreferredLanguage,
+		&i.Timezone,
+		&i.PhoneNumbers,
+		&i.Ims,
+		&i.Photos,
+		&i.Addresses,
+		&i.Entitlements,
+		&i.Roles,
+		&i.X509Certificates,
+		&i.Extensions,
 	)
 	return i, err
 }
@@ -144,6 +216,23 @@ func (q *Queries) DropMembershipForGroup(ctx context.Context, groupID uuid.UUID)
 	return err
 }
 
+const dropMembershipForGroupAndGroup = `-- name: DropMembershipForGroupAndGroup :exec
+delete
+from group_groups
+where member_group_id = $1
+  and group_id = $2
+`
+
+type DropMembershipForGroupAndGroupParams struct {
+	MemberGroupID uuid.UUID
+	GroupID       uuid.UUID
+}
+
+func (q *Queries) DropMembershipForGroupAndGroup(ctx context.Context, arg DropMembershipForGroupAndGroupParams) error {
+	_, err := q.db.Exec(ctx, dropMembershipForGroupAndGroup, arg.MemberGroupID, arg.GroupID)
+	return err
+}
+
 const dropMembershipForUserAndGroup = `-- name: DropMembershipForUserAndGroup :exec
 delete
 from group_users
@@ -218,7 +307,7 @@ func (q *Queries) FindAPIKeysById(ctx context.Context, dollar_1 []uuid.UUID) ([]
 }
 
 const findByUsername = `-- name: FindByUsername :one
-select id, username, external_id, name, display_name, locale, active, emails, created_at, updated_at
+select id, username, external_id, name, display_name, locale, active, emails, created_at, updated_at, enterprise_user, nickname, profile_url, title, user_type, preferred_language, timezone, phone_numbers, ims, photos, addresses, entitlements, roles, x509_certificates, extensions
 from users
 where username = $1
 `
@@ -237,6 +326,21 @@ func (q *Queries) FindByUsername(ctx context.Context, username string) (User, er
 		&i.Emails,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EnterpriseUser,
+		&i.Nickname,
+		&i.ProfileUrl,
+		&i.Title,
+		&i.UserType,
+		&i.PreferredLanguage,
+		&i.Timezone,
+		&i.PhoneNumbers,
+		&i.Ims,
+		&i.Photos,
+		&i.Addresses,
+		&i.Entitlements,
+		&i.Roles,
+		&i.X509Certificates,
+		&i.Extensions,
 	)
 	return i, err
 }
@@ -299,7 +403,7 @@ func (q *Queries) GetAPIKeys(ctx context.Context) ([]ApiKey, error) {
 }
 
 const getGroup = `-- name: GetGroup :one
-select id, display_name, created_at, updated_at
+select id, display_name, created_at, updated_at, external_id, extensions
 from groups
 where id = $1
 `
@@ -312,6 +416,8 @@ func (q *Queries) GetGroup(ctx context.Context, id uuid.UUID) (Group, error) {
 		&i.DisplayName,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ExternalID,
+		&i.Extensions,
 	)
 	return i, err
 }
@@ -393,7 +499,7 @@ func (q *Queries) GetGroupMembershipForUser(ctx context.Context, arg GetGroupMem
 
 const getGroups = `-- name: GetGroups :many
 
-select id, display_name, created_at, updated_at
+select id, display_name, created_at, updated_at, external_id, extensions
 from groups
 order by id
 LIMIT $1 OFFSET $2
@@ -421,6 +527,8 @@ func (q *Queries) GetGroups(ctx context.Context, arg GetGroupsParams) ([]Group,
 			&i.DisplayName,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.ExternalID,
+			&i.Extensions,
 		); err != nil {
 			return nil, err
 		}
@@ -432,8 +540,145 @@ func (q *Queries) GetGroups(ctx context.Context, arg GetGroupsParams) ([]Group,
 	return items, nil
 }
 
+const getGroupsForUser = `-- name: GetGroupsForUser :many
+select groups.id, groups.display_name
+from group_users
+         join groups on groups.id = group_users.group_id
+where group_users.user_id = $1
+`
+
+type GetGroupsForUserRow struct {
+	ID          uuid.UUID
+	DisplayName string
+}
+
+func (q *Queries) GetGroupsForUser(ctx context.Context, userID uuid.UUID) ([]GetGroupsForUserRow, error) {
+	rows, err := q.db.Query(ctx, getGroupsForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetGroupsForUserRow
+	for rows.Next() {
+		var i GetGroupsForUserRow
+		if err := rows.Scan(&i.ID, &i.DisplayName); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getIndirectGroupsForUser = `-- name: GetIndirectGroupsForUser :many
+with recursive parents (group_id) as (select group_groups.group_id
+                                      from group_groups
+                                               join group_users on group_users.group_id = group_groups.member_group_id
+                                      where group_users.user_id = $1
+                                      union
+                                      select group_groups.group_id
+                                      from group_groups
+                                               join parents on parents.group_id = group_groups.member_group_id)
+select groups.id, groups.display_name
+from parents
+         join groups on groups.id = parents.group_id
+where groups.id not in (select group_users.group_id from group_users where group_users.user_id = $1)
+`
+
+type GetIndirectGroupsForUserRow struct {
+	ID          uuid.UUID
+	DisplayName string
+}
+
+func (q *Queries) GetIndirectGroupsForUser(ctx context.Context, userID uuid.UUID) ([]GetIndirectGroupsForUserRow, error) {
+	rows, err := q.db.Query(ctx, getIndirectGroupsForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetIndirectGroupsForUserRow
+	for rows.Next() {
+		var i GetIndirectGroupsForUserRow
+		if err := rows.Scan(&i.ID, &i.DisplayName); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getNestedGroupIDs = `-- name: GetNestedGroupIDs :many
+with recursive nested (member_group_id) as (select group_groups.member_group_id
+                                             from group_groups
+                                             where group_groups.group_id = $1
+                                             union
+                                             select group_groups.member_group_id
+                                             from group_groups
+                                                      join nested on nested.member_group_id = group_groups.group_id)
+select member_group_id
+from nested
+`
+
+func (q *Queries) GetNestedGroupIDs(ctx context.Context, groupID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := q.db.Query(ctx, getNestedGroupIDs, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var member_group_id uuid.UUID
+		if err := rows.Scan(&member_group_id); err != nil {
+			return nil, err
+		}
+		items = append(items, member_group_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getNestedGroupMembership = `-- name: GetNestedGroupMembership :many
+select group_groups.group_id, group_groups.member_group_id, groups.display_name as display_name
+from group_groups
+         left join groups on groups.id = group_groups.member_group_id
+where group_groups.group_id = $1
+`
+
+type GetNestedGroupMembershipRow struct {
+	GroupID       uuid.UUID
+	MemberGroupID uuid.UUID
+	DisplayName   sql.NullString
+}
+
+func (q *Queries) GetNestedGroupMembership(ctx context.Context, groupID uuid.UUID) ([]GetNestedGroupMembershipRow, error) {
+	rows, err := q.db.Query(ctx, getNestedGroupMembership, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetNestedGroupMembershipRow
+	for rows.Next() {
+		var i GetNestedGroupMembershipRow
+		if err := rows.Scan(&i.GroupID, &i.MemberGroupID, &i.DisplayName); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getUser = `-- name: GetUser :one
-select id, username, external_id, name, display_name, locale, active, emails, created_at, updated_at
+select id, username, external_id, name, display_name, locale, active, emails, created_at, updated_at, enterprise_user, nickname, profile_url, title, user_type, preferred_language, timezone, phone_numbers, ims, photos, addresses, entitlements, roles, x509_certificates, extensions
 from users
 where id = $1
 `
@@ -452,6 +697,21 @@ func (q *Queries) GetUser(ctx context.Context, id uuid.UUID) (User, error) {
 		&i.Emails,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EnterpriseUser,
+		&i.Nickname,
+		&i.ProfileUrl,
+		&i.Title,
+		&i.UserType,
+		&i.PreferredLanguage,
+		&i.Timezone,
+		&i.PhoneNumbers,
+		&i.Ims,
+		&i.Photos,
+		&i.Addresses,
+		&i.Entitlements,
+		&i.Roles,
+		&i.X509Certificates,
+		&i.Extensions,
 	)
 	return i, err
 }
@@ -470,7 +730,7 @@ func (q *Queries) GetUserCount(ctx context.Context) (int64, error) {
 
 const getUsers = `-- name: GetUsers :many
 
-select id, username, external_id, name, display_name, locale, active, emails, created_at, updated_at
+select id, username, external_id, name, display_name, locale, active, emails, created_at, updated_at, enterprise_user, nickname, profile_url, title, user_type, preferred_language, timezone, phone_numbers, ims, photos, addresses, entitlements, roles, x509_certificates, extensions
 from users
 order by created_at
 LIMIT $1 OFFSET $2
@@ -504,6 +764,21 @@ func (q *Queries) GetUsers(ctx context.Context, arg GetUsersParams) ([]User, err
 			&i.Emails,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.EnterpriseUser,
+			&i.Nickname,
+			&i.ProfileUrl,
+			&i.Title,
+			&i.UserType,
+			&i.PreferredLanguage,
+			&i.Timezone,
+			&i.PhoneNumbers,
+			&i.Ims,
+			&i.Photos,
+			&i.Addresses,
+			&i.Entitlements,
+			&i.Roles,
+			&i.X509Certificates,
+			&i.Extensions,
 		); err != nil {
 			return nil, err
 		}
@@ -516,7 +791,7 @@ func (q *Queries) GetUsers(ctx context.Context, arg GetUsersParams) ([]User, err
 }
 
 const getUsersById = `-- name: GetUsersById :many
-select id, username, external_id, name, display_name, locale, active, emails, created_at, updated_at
+select id, username, external_id, name, display_name, locale, active, emails, created_at, updated_at, enterprise_user, nickname, profile_url, title, user_type, preferred_language, timezone, phone_numbers, ims, photos, addresses, entitlements, roles, x509_certificates, extensions
 from users
 where id = ANY ($1::uuid[])
 order by display_name
@@ -542,6 +817,21 @@ func (q *Queries) GetUsersById(ctx context.Context, dollar_1 []uuid.UUID) ([]Use
 			&i.Emails,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.EnterpriseUser,
+			&i.Nickname,
+			&i.ProfileUrl,
+			&i.Title,
+			&i.UserType,
+			&i.PreferredLanguage,
+			&i.Timezone,
+			&i.PhoneNumbers,
+			&i.Ims,
+			&i.Photos,
+			&i.Addresses,
+			&i.Entitlements,
+			&i.Roles,
+			&i.X509Certificates,
+			&i.Extensions,
 		); err != nil {
 			return nil, err
 		}
@@ -609,21 +899,32 @@ func (q *Queries) InsertScimAPIKey(ctx context.Context, apiKeyID uuid.UUID) (Sci
 	return i, err
 }
 
-const patchGroupDisplayName = `-- name: PatchGroupDisplayName :exec
-update groups
-set display_name = $2,
-    updated_at   = now()
+const lockGroup = `-- name: LockGroup :one
+select updated_at
+from groups
 where id = $1
+    for update
 `
 
-type PatchGroupDisplayNameParams struct {
-	ID          uuid.UUID
-	DisplayName string
+func (q *Queries) LockGroup(ctx context.Context, id uuid.UUID) (time.Time, error) {
+	row := q.db.QueryRow(ctx, lockGroup, id)
+	var updated_at time.Time
+	err := row.Scan(&updated_at)
+	return updated_at, err
 }
 
-func (q *Queries) PatchGroupDisplayName(ctx context.Context, arg PatchGroupDisplayNameParams) error {
-	_, err := q.db.Exec(ctx, patchGroupDisplayName, arg.ID, arg.DisplayName)
-	return err
+const lockUser = `-- name: LockUser :one
+select updated_at
+from users
+where id = $1
+    for update
+`
+
+func (q *Queries) LockUser(ctx context.Context, id uuid.UUID) (time.Time, error) {
+	row := q.db.QueryRow(ctx, lockUser, id)
+	var updated_at time.Time
+	err := row.Scan(&updated_at)
+	return updated_at, err
 }
 
 const patchUser = `-- name: PatchUser :exec
@@ -643,28 +944,140 @@ func (q *Queries) PatchUser(ctx context.Context, arg PatchUserParams) error {
 	return err
 }
 
-const updateUser = `-- name: UpdateUser :exec
+const touchGroupUsers = `-- name: TouchGroupUsers :exec
 update users
-set username     =$2,
-    name         = $3,
-    display_name = $4,
-    emails       = $5,
-    active       = $6,
-    external_id  = $7,
-    locale       = $8,
+set updated_at = now()
+where id in (with recursive nested (id) as (select groups.id
+                                            from groups
+                                            where groups.id = $1
+                                            union
+                                            select group_groups.member_group_id
+                                            from group_groups
+                                                     join nested on nested.id = group_groups.group_id)
+             select group_users.user_id
+             from group_users
+                      join nested on nested.id = group_users.group_id)
+`
+
+// nesting a group changes the indirect groups of its users, directly or through nested groups
+func (q *Queries) TouchGroupUsers(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.Exec(ctx, touchGroupUsers, id)
+	return err
+}
+
+const touchGroupsForUser = `-- name: TouchGroupsForUser :exec
+update groups
+set updated_at = now()
+where id in (select group_users.group_id
+             from group_users
+             where group_users.user_id = $1)
+`
+
+// deleting a user removes it from the members of its groups
+func (q *Queries) TouchGroupsForUser(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.Exec(ctx, touchGroupsForUser, userID)
+	return err
+}
+
+const touchParentGroups = `-- name: TouchParentGroups :exec
+update groups
+set updated_at = now()
+where id in (select group_groups.group_id
+             from group_groups
+             where group_groups.member_group_id = $1)
+`
+
+// deleting a group removes it from the members of its parent groups
+func (q *Queries) TouchParentGroups(ctx context.Context, memberGroupID uuid.UUID) error {
+	_, err := q.db.Exec(ctx, touchParentGroups, memberGroupID)
+	return err
+}
+
+const touchUser = `-- name: TouchUser :exec
+update users
+set updated_at = now()
+where id = $1
+`
+
+// users list their groups, so membership changes update them
+func (q *Queries) TouchUser(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.Exec(ctx, touchUser, id)
+	return err
+}
+
+const updateGroup = `-- name: UpdateGroup :exec
+update groups
+set display_name = $2,
+    external_id  = $3,
+    extensions   = $4,
     updated_at   = now()
 where id = $1
 `
 
-type UpdateUserParams struct {
+type UpdateGroupParams struct {
 	ID          uuid.UUID
-	Username    string
-	Name        pgtype.JSONB
-	DisplayName sql.NullString
-	Emails      pgtype.JSONB
-	Active      bool
+	DisplayName string
 	ExternalID  sql.NullString
-	Locale      sql.NullString
+	Extensions  pgtype.JSONB
+}
+
+func (q *Queries) UpdateGroup(ctx context.Context, arg UpdateGroupParams) error {
+	_, err := q.db.Exec(ctx, updateGroup, arg.ID, arg.DisplayName, arg.ExternalID, arg.Extensions)
+	return err
+}
+
+const updateUser = `-- name: UpdateUser :exec
+update users
+set username           = $2,
+    name               = $3,
+    display_name       = $4,
+    emails             = $5,
+    active             = $6,
+    external_id        = $7,
+    locale             = $8,
+    enterprise_user    = $9,
+    nickname           = $10,
+    profile_url        = $11,
+    title              = $12,
+    user_type          = $13,
+    preferred_language = $14,
+    timezone           = $15,
+    phone_numbers      = $16,
+    ims                = $17,
+    photos             = $18,
+    addresses          = $19,
+    entitlements       = $20,
+    roles              = $21,
+    x509_certificates  = $22,
+    extensions         = $23,
+    updated_at         = now()
+where id = $1
+`
+
+type UpdateUserParams struct {
+	ID                uuid.UUID
+	Username          string
+	Name              pgtype.JSONB
+	DisplayName       sql.NullString
+	Emails            pgtype.JSONB
+	Active            bool
+	ExternalID        sql.NullString
+	Locale            sql.NullString
+	EnterpriseUser    pgtype.JSONB
+	Nickname          sql.NullString
+	ProfileUrl        sql.NullString
+	Title             sql.NullString
+	UserType          sql.NullString
+	PreferredLanguage sql.NullString
+	Timezone          sql.NullString
+	PhoneNumbers      pgtype.JSONB
+	Ims               pgtype.JSONB
+	Photos            pgtype.JSONB
+	Addresses         pgtype.JSONB
+	Entitlements      pgtype.JSONB
+	Roles             pgtype.JSONB
+	X509Certificates  pgtype.JSONB
+	Extensions        pgtype.JSONB
 }
 
 func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) error {
@@ -677,6 +1090,21 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) error {
 		arg.Active,
 		arg.ExternalID,
 		arg.Locale,
+		arg.EnterpriseUser,
+		arg.Nickname,
+		arg.ProfileUrl,
+		arg.Title,
+		arg.UserType,
+		arg.PreferredLanguage,
+		arg.Timezone,
+		arg.PhoneNumbers,
+		arg.Ims,
+		arg.Photos,
+		arg.Addresses,
+		arg.Entitlements,
+		arg.Roles,
+		arg.X509Certificates,
+		arg.Extensions,
 	)
 	return err
 }
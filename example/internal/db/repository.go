@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v4/pgxpool"
@@ -20,15 +21,22 @@ type RepositoryQueries interface {
 	Rollback(ctx context.Context) error
 
 	FindGroup(ctx context.Context, id string) (Group, error)
-	CreateGroup(ctx context.Context, displayName string) (Group, error)
+	CreateGroup(ctx context.Context, input CreateGroupParams) (Group, error)
 	DeleteGroup(ctx context.Context, id string) error
-	UpdateGroup(ctx context.Context, input PatchGroupDisplayNameParams) (Group, error)
+	UpdateGroup(ctx context.Context, input UpdateGroupParams) (Group, error)
 	RemoveUserFromGroup(ctx context.Context, userID, groupID uuid.UUID) error
 	AddUserToGroup(ctx context.Context, userID, groupID uuid.UUID) error
 	ReplaceUsersInGroup(ctx context.Context, groupID uuid.UUID, members []uuid.UUID) error
 	AddUsersToGroup(ctx context.Context, groupID uuid.UUID, members []uuid.UUID) error
 	GetGroupMembership(ctx context.Context, idString string) ([]GetGroupMembershipRow, error)
-	GetGroups(ctx context.Context, params GetGroupsParams) (int64, []Group, error)
+	AddGroupToGroup(ctx context.Context, memberGroupID, groupID uuid.UUID) error
+	RemoveGroupFromGroup(ctx context.Context, memberGroupID, groupID uuid.UUID) error
+	GetNestedGroupMembership(ctx context.Context, groupID uuid.UUID) ([]GetNestedGroupMembershipRow, error)
+	GetNestedGroupIDs(ctx context.Context, groupID uuid.UUID) ([]uuid.UUID, error)
+	LockGroup(ctx context.Context, groupID uuid.UUID) (time.Time, error)
+	GetGroupsForUser(ctx context.Context, userID uuid.UUID) ([]GetGroupsForUserRow, error)
+	GetIndirectGroupsForUser(ctx context.Context, userID uuid.UUID) ([]GetIndirectGroupsForUserRow, error)
+	GetScimGroups(ctx context.Context, input GetScimGroupsInput) (int64, []Group, error)
 
 	FindUser(ctx context.Context, id string) (User, error)
 	FindUserByUsername(ctx context.Context, username string) (User, error)
@@ -37,7 +45,7 @@ type RepositoryQueries interface {
 	CreateUser(ctx context.Context, input CreateUserParams) (User, error)
 	DeleteUser(ctx context.Context, id uuid.UUID) error
 	UpdateUser(ctx context.Context, id uuid.UUID, input UpdateUserParams) (User, error)
-	ScimPatchUser(ctx context.Context, input PatchUserParams) error
+	LockUser(ctx context.Context, userID uuid.UUID) (time.Time, error)
 
 	InsertScimAPIKey(ctx context.Context, encodedHash string) (ApiKey, error)
 	DeleteScimAPIKey(ctx context.Context) error
@@ -4,15 +4,61 @@ import (
 	"context"
 	"database/sql"
 	"errors"
-	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
-	"github.com/suse-skyscraper/openfga-scim-bridge/v2/filters"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/database"
 )
 
-var ErrConflict = errors.New("duplicate key value violates unique constraint")
+// The SQLSTATE of violated unique and foreign key constraints.
+const (
+	uniqueViolation     = "23505"
+	foreignKeyViolation = "23503"
+)
+
+// uniqueAttributes maps the unique constraints of the users and groups to the attribute they enforce.
+var uniqueAttributes = map[string]string{
+	"users_username_key":      "userName",
+	"users_external_id_key":   "externalId",
+	"groups_display_name_key": "displayName",
+	"groups_external_id_key":  "externalId",
+}
+
+// membershipTables hold the members of groups, their foreign keys reference the members.
+var membershipTables = map[string]bool{
+	"group_users":  true,
+	"group_groups": true,
+}
+
+// errUnknownMember is returned when a member of a group references a user or group that doesn't exist.
+var errUnknownMember = &database.InvalidValueError{Attribute: "members", Detail: "a member does not exist"}
+
+// constraintError returns a *database.ConflictError if err is the violation of a unique constraint of
+// a user or group, errUnknownMember if it is the violation of a foreign key of the members of a group,
+// and err otherwise.
+func constraintError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+
+	switch {
+	case pgErr.Code == foreignKeyViolation && membershipTables[pgErr.TableName]:
+		return errUnknownMember
+	case pgErr.Code != uniqueViolation:
+		return err
+	}
+
+	attribute, ok := uniqueAttributes[pgErr.ConstraintName]
+	if !ok {
+		return database.ErrConflict
+	}
+
+	return &database.ConflictError{Attribute: attribute}
+}
 
 var _ RepositoryQueries = (*Repository)(nil)
 
@@ -77,14 +123,10 @@ func (r *Repository) InsertScimAPIKey(ctx context.Context, encodedHash string) (
 	return apiKey, nil
 }
 
-func (r *Repository) ScimPatchUser(ctx context.Context, input PatchUserParams) error {
-	return r.db.PatchUser(ctx, input)
-}
-
 func (r *Repository) UpdateUser(ctx context.Context, id uuid.UUID, input UpdateUserParams) (User, error) {
 	err := r.db.UpdateUser(ctx, input)
 	if err != nil {
-		return User{}, err
+		return User{}, constraintError(err)
 	}
 
 	user, err := r.db.GetUser(ctx, id)
@@ -95,61 +137,29 @@ func (r *Repository) UpdateUser(ctx context.Context, id uuid.UUID, input UpdateU
 	return user, nil
 }
 
+// DeleteUser deletes the user, and updates the groups it was a member of.
 func (r *Repository) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	err := r.db.TouchGroupsForUser(ctx, id)
+	if err != nil {
+		return err
+	}
+
 	return r.db.DeleteUser(ctx, id)
 }
 
 func (r *Repository) CreateUser(ctx context.Context, input CreateUserParams) (User, error) {
 	user, err := r.db.CreateUser(ctx, input)
-	if err != nil && strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
-		return User{}, ErrConflict
-	} else if err != nil {
-		return User{}, err
+	if err != nil {
+		return User{}, constraintError(err)
 	}
 
 	return user, nil
 }
 
-func (r *Repository) GetScimUsers(ctx context.Context, input GetScimUsersInput) (int64, []User, error) {
-	if len(input.Filters) == 0 {
-		totalCount, err := r.db.GetUserCount(ctx)
-		if err != nil {
-			return 0, nil, err
-		}
-
-		users, err := r.db.GetUsers(ctx, GetUsersParams{
-			Offset: input.Offset,
-			Limit:  input.Limit,
-		})
-		if err != nil {
-			return 0, nil, err
-		}
-
-		return totalCount, users, nil
-	}
-
-	// we only support the userName filter for now
-	// Okta uses this to see if a userName already exists
-	filter := input.Filters[0]
-	if filter.FilterField == filters.Username && filter.FilterOperator == filters.Eq {
-		user, err := r.db.FindByUsername(ctx, filter.FilterValue)
-		switch err {
-		case nil:
-			return 1, []User{user}, nil
-		case pgx.ErrNoRows:
-			return 0, []User{}, nil
-		default:
-			return 0, nil, err
-		}
-	} else {
-		return 0, nil, errors.New("unsupported filter")
-	}
-}
-
-func (r *Repository) CreateGroup(ctx context.Context, displayName string) (Group, error) {
-	group, err := r.db.CreateGroup(ctx, displayName)
+func (r *Repository) CreateGroup(ctx context.Context, input CreateGroupParams) (Group, error) {
+	group, err := r.db.CreateGroup(ctx, input)
 	if err != nil {
-		return Group{}, err
+		return Group{}, constraintError(err)
 	}
 
 	return group, nil
@@ -164,22 +174,19 @@ func (r *Repository) GetGroupMembership(ctx context.Context, idString string) ([
 	return r.db.GetGroupMembership(ctx, id)
 }
 
-func (r *Repository) GetGroups(ctx context.Context, params GetGroupsParams) (int64, []Group, error) {
-	totalCount, err := r.db.GetGroupCount(ctx)
+// DeleteGroup deletes the group, and updates its parent groups and the users it lists.
+func (r *Repository) DeleteGroup(ctx context.Context, idString string) error {
+	id, err := uuid.Parse(idString)
 	if err != nil {
-		return 0, nil, err
+		return err
 	}
 
-	groups, err := r.db.GetGroups(ctx, params)
+	err = r.db.TouchParentGroups(ctx, id)
 	if err != nil {
-		return 0, nil, err
+		return err
 	}
 
-	return totalCount, groups, nil
-}
-
-func (r *Repository) DeleteGroup(ctx context.Context, idString string) error {
-	id, err := uuid.Parse(idString)
+	err = r.db.TouchGroupUsers(ctx, id)
 	if err != nil {
 		return err
 	}
@@ -192,10 +199,10 @@ func (r *Repository) DeleteGroup(ctx context.Context, idString string) error {
 	return nil
 }
 
-func (r *Repository) UpdateGroup(ctx context.Context, input PatchGroupDisplayNameParams) (Group, error) {
-	err := r.db.PatchGroupDisplayName(ctx, input)
+func (r *Repository) UpdateGroup(ctx context.Context, input UpdateGroupParams) (Group, error) {
+	err := r.db.UpdateGroup(ctx, input)
 	if err != nil {
-		return Group{}, err
+		return Group{}, constraintError(err)
 	}
 
 	return r.FindGroup(ctx, input.ID.String())
@@ -266,7 +273,12 @@ func (r *Repository) AddUsersToGroup(ctx context.Context, groupID uuid.UUID, mem
 }
 
 func (r *Repository) ReplaceUsersInGroup(ctx context.Context, groupID uuid.UUID, members []uuid.UUID) error {
-	err := r.db.DropMembershipForGroup(ctx, groupID)
+	err := r.db.TouchGroupUsers(ctx, groupID)
+	if err != nil {
+		return err
+	}
+
+	err = r.db.DropMembershipForGroup(ctx, groupID)
 	if err != nil {
 		return err
 	}
@@ -287,10 +299,10 @@ func (r *Repository) AddUserToGroup(ctx context.Context, userID, groupID uuid.UU
 		GroupID: groupID,
 	})
 	if err != nil {
-		return err
+		return constraintError(err)
 	}
 
-	return nil
+	return r.db.TouchUser(ctx, userID)
 }
 
 func (r *Repository) RemoveUserFromGroup(ctx context.Context, userID, groupID uuid.UUID) error {
@@ -302,5 +314,57 @@ func (r *Repository) RemoveUserFromGroup(ctx context.Context, userID, groupID uu
 		return err
 	}
 
-	return nil
+	return r.db.TouchUser(ctx, userID)
+}
+
+func (r *Repository) AddGroupToGroup(ctx context.Context, memberGroupID, groupID uuid.UUID) error {
+	err := r.db.CreateMembershipForGroupAndGroup(ctx, CreateMembershipForGroupAndGroupParams{
+		MemberGroupID: memberGroupID,
+		GroupID:       groupID,
+	})
+	if err != nil {
+		return constraintError(err)
+	}
+
+	return r.db.TouchGroupUsers(ctx, memberGroupID)
+}
+
+func (r *Repository) RemoveGroupFromGroup(ctx context.Context, memberGroupID, groupID uuid.UUID) error {
+	err := r.db.DropMembershipForGroupAndGroup(ctx, DropMembershipForGroupAndGroupParams{
+		MemberGroupID: memberGroupID,
+		GroupID:       groupID,
+	})
+	if err != nil {
+		return err
+	}
+
+	return r.db.TouchGroupUsers(ctx, memberGroupID)
+}
+
+func (r *Repository) GetNestedGroupMembership(ctx context.Context, groupID uuid.UUID) ([]GetNestedGroupMembershipRow, error) {
+	return r.db.GetNestedGroupMembership(ctx, groupID)
+}
+
+// GetNestedGroupIDs returns the IDs of the groups nested in the group, directly or indirectly.
+func (r *Repository) GetNestedGroupIDs(ctx context.Context, groupID uuid.UUID) ([]uuid.UUID, error) {
+	return r.db.GetNestedGroupIDs(ctx, groupID)
+}
+
+// LockGroup locks the group until the end of the transaction, and returns when it was last updated.
+func (r *Repository) LockGroup(ctx context.Context, groupID uuid.UUID) (time.Time, error) {
+	return r.db.LockGroup(ctx, groupID)
+}
+
+// LockUser locks the user until the end of the transaction, and returns when it was last updated.
+func (r *Repository) LockUser(ctx context.Context, userID uuid.UUID) (time.Time, error) {
+	return r.db.LockUser(ctx, userID)
+}
+
+func (r *Repository) GetGroupsForUser(ctx context.Context, userID uuid.UUID) ([]GetGroupsForUserRow, error) {
+	return r.db.GetGroupsForUser(ctx, userID)
+}
+
+// GetIndirectGroupsForUser returns the groups the user belongs to through nested groups only.
+func (r *Repository) GetIndirectGroupsForUser(ctx context.Context, userID uuid.UUID) ([]GetIndirectGroupsForUserRow, error) {
+	return r.db.GetIndirectGroupsForUser(ctx, userID)
 }
@@ -1,11 +1,22 @@
 package db
 
 import (
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/database"
 	"github.com/suse-skyscraper/openfga-scim-bridge/v2/filters"
 )
 
 type GetScimUsersInput struct {
-	Filters []filters.Filter
-	Offset  int32
-	Limit   int32
+	Filter    filters.Expression
+	SortBy    string
+	SortOrder database.SortOrder
+	Offset    int32
+	Limit     int32
+}
+
+type GetScimGroupsInput struct {
+	Filter    filters.Expression
+	SortBy    string
+	SortOrder database.SortOrder
+	Offset    int32
+	Limit     int32
 }
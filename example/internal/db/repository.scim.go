@@ -0,0 +1,239 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/database"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/filters"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/schema"
+)
+
+const userColumns = "id, username, external_id, name, display_name, locale, active, emails, created_at, updated_at, enterprise_user, " +
+	"nickname, profile_url, title, user_type, preferred_language, timezone, phone_numbers, ims, photos, addresses, entitlements, roles, x509_certificates, extensions"
+
+const groupColumns = "id, display_name, created_at, updated_at, external_id, extensions"
+
+// usersFilter maps the SCIM user attributes to the columns of the users table.
+var usersFilter = filters.PostgresTranslator{
+	Columns: map[string]filters.Column{
+		"id":                {Name: "id::text", CaseExact: true},
+		"userName":          {Name: "username"},
+		"externalId":        {Name: "external_id", CaseExact: true},
+		"displayName":       {Name: "display_name"},
+		"nickName":          {Name: "nickname"},
+		"profileUrl":        {Name: "profile_url"},
+		"title":             {Name: "title"},
+		"userType":          {Name: "user_type"},
+		"preferredLanguage": {Name: "preferred_language"},
+		"locale":            {Name: "locale"},
+		"timezone":          {Name: "timezone"},
+		"active":            {Name: "active", Type: filters.BooleanColumn},
+		"name":              {Name: "name", Type: filters.JSONBObjectColumn},
+		"emails":            {Name: "emails", Type: filters.JSONBArrayColumn},
+		"phoneNumbers":      {Name: "phone_numbers", Type: filters.JSONBArrayColumn},
+		"ims":               {Name: "ims", Type: filters.JSONBArrayColumn},
+		"photos":            {Name: "photos", Type: filters.JSONBArrayColumn},
+		"addresses":         {Name: "addresses", Type: filters.JSONBArrayColumn},
+		"entitlements":      {Name: "entitlements", Type: filters.JSONBArrayColumn},
+		"roles":             {Name: "roles", Type: filters.JSONBArrayColumn},
+		"x509Certificates":  {Name: "x509_certificates", Type: filters.JSONBArrayColumn, CaseExact: true},
+		"meta.created":      {Name: "created_at", Type: filters.TimestampColumn},
+		"meta.lastModified": {Name: "updated_at", Type: filters.TimestampColumn},
+
+		schema.EnterpriseUserURN + ":employeeNumber": {Name: "(enterprise_user->>'employeeNumber')"},
+		schema.EnterpriseUserURN + ":costCenter":     {Name: "(enterprise_user->>'costCenter')"},
+		schema.EnterpriseUserURN + ":organization":   {Name: "(enterprise_user->>'organization')"},
+		schema.EnterpriseUserURN + ":division":       {Name: "(enterprise_user->>'division')"},
+		schema.EnterpriseUserURN + ":department":     {Name: "(enterprise_user->>'department')"},
+		schema.EnterpriseUserURN + ":manager":        {Name: "(enterprise_user->'manager')", Type: filters.JSONBObjectColumn},
+	},
+}
+
+// groupsFilter maps the SCIM group attributes to the columns of the groups table.
+var groupsFilter = filters.PostgresTranslator{
+	Columns: map[string]filters.Column{
+		"id":                {Name: "id::text", CaseExact: true},
+		"displayName":       {Name: "display_name"},
+		"externalId":        {Name: "external_id", CaseExact: true},
+		"meta.created":      {Name: "created_at", Type: filters.TimestampColumn},
+		"meta.lastModified": {Name: "updated_at", Type: filters.TimestampColumn},
+		// members lists both the users and the nested groups of a group
+		"members": {
+			Name: "(select jsonb_agg(jsonb_build_object('value', members.id::text)) from (" +
+				"select group_users.user_id as id from group_users where group_users.group_id = groups.id " +
+				"union all " +
+				"select group_groups.member_group_id from group_groups where group_groups.group_id = groups.id" +
+				") as members)",
+			Type: filters.JSONBArrayColumn,
+		},
+	},
+}
+
+func (r *Repository) GetScimUsers(ctx context.Context, input GetScimUsersInput) (int64, []User, error) {
+	condition, args, err := usersFilter.Translate(input.Filter, 1)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var totalCount int64
+	countQuery := fmt.Sprintf("select count(*) from users where %s", condition)
+	err = r.conn().QueryRow(ctx, countQuery, args...).Scan(&totalCount)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	orderBy, err := orderBy(usersFilter, input.SortBy, input.SortOrder, "created_at")
+	if err != nil {
+		return 0, nil, err
+	}
+
+	query := fmt.Sprintf(
+		"select %s from users where %s order by %s limit $%d offset $%d",
+		userColumns,
+		condition,
+		orderBy,
+		len(args)+1,
+		len(args)+2,
+	)
+	rows, err := r.conn().Query(ctx, query, append(args, input.Limit, input.Offset)...)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer rows.Close()
+
+	var users []User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return 0, nil, err
+	}
+
+	return totalCount, users, nil
+}
+
+func (r *Repository) GetScimGroups(ctx context.Context, input GetScimGroupsInput) (int64, []Group, error) {
+	condition, args, err := groupsFilter.Translate(input.Filter, 1)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var totalCount int64
+	countQuery := fmt.Sprintf("select count(*) from groups where %s", condition)
+	err = r.conn().QueryRow(ctx, countQuery, args...).Scan(&totalCount)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	orderBy, err := orderBy(groupsFilter, input.SortBy, input.SortOrder, "id")
+	if err != nil {
+		return 0, nil, err
+	}
+
+	query := fmt.Sprintf(
+		"select %s from groups where %s order by %s limit $%d offset $%d",
+		groupColumns,
+		condition,
+		orderBy,
+		len(args)+1,
+		len(args)+2,
+	)
+	rows, err := r.conn().Query(ctx, query, append(args, input.Limit, input.Offset)...)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer rows.Close()
+
+	var groups []Group
+	for rows.Next() {
+		group, err := scanGroup(rows)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		groups = append(groups, group)
+	}
+
+	if err := rows.Err(); err != nil {
+		return 0, nil, err
+	}
+
+	return totalCount, groups, nil
+}
+
+// orderBy returns the ORDER BY clause for the requested sort. The id is always the last sort key, so
+// that pages are stable when sorting by a non-unique attribute.
+func orderBy(translator filters.PostgresTranslator, sortBy string, sortOrder database.SortOrder, defaultOrder string) (string, error) {
+	if sortBy == "" {
+		return defaultOrder + ", id", nil
+	}
+
+	order, err := translator.OrderBy(sortBy, sortOrder == database.Descending)
+	if err != nil {
+		return "", err
+	}
+
+	return order + ", id", nil
+}
+
+// conn returns the transaction in progress, or the pool if there is none.
+func (r *Repository) conn() DBTX {
+	if r.tx != nil {
+		return r.tx
+	}
+
+	return r.postgresPool
+}
+
+func scanUser(row pgx.Row) (User, error) {
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.ExternalID,
+		&i.Name,
+		&i.DisplayName,
+		&i.Locale,
+		&i.Active,
+		&i.Emails,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.EnterpriseUser,
+		&i.Nickname,
+		&i.ProfileUrl,
+		&i.Title,
+		&i.UserType,
+		&i.PreferredLanguage,
+		&i.Timezone,
+		&i.PhoneNumbers,
+		&i.Ims,
+		&i.Photos,
+		&i.Addresses,
+		&i.Entitlements,
+		&i.Roles,
+		&i.X509Certificates,
+		&i.Extensions,
+	)
+	return i, err
+}
+
+func scanGroup(row pgx.Row) (Group, error) {
+	var i Group
+	err := row.Scan(
+		&i.ID,
+		&i.DisplayName,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ExternalID,
+		&i.Extensions,
+	)
+	return i, err
+}
@@ -0,0 +1,93 @@
+package db
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/database"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/filters"
+)
+
+func TestConstraintError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{
+			name: "unique userName",
+			err:  &pgconn.PgError{Code: "23505", ConstraintName: "users_username_key"},
+			want: &database.ConflictError{Attribute: "userName"},
+		},
+		{
+			name: "unique group displayName",
+			err:  &pgconn.PgError{Code: "23505", ConstraintName: "groups_display_name_key"},
+			want: &database.ConflictError{Attribute: "displayName"},
+		},
+		{
+			name: "other unique constraint",
+			err:  &pgconn.PgError{Code: "23505", ConstraintName: "group_users_group_id_user_id_key"},
+			want: database.ErrConflict,
+		},
+		{
+			name: "unknown user member",
+			err:  &pgconn.PgError{Code: "23503", TableName: "group_users", ConstraintName: "group_users_user_id_fkey"},
+			want: errUnknownMember,
+		},
+		{
+			name: "unknown group member",
+			err:  &pgconn.PgError{Code: "23503", TableName: "group_groups", ConstraintName: "group_groups_member_group_id_fkey"},
+			want: errUnknownMember,
+		},
+		{
+			name: "other foreign key",
+			err:  &pgconn.PgError{Code: "23503", TableName: "scim_api_keys", ConstraintName: "scim_api_keys_api_key_id_fkey"},
+			want: &pgconn.PgError{Code: "23503", TableName: "scim_api_keys", ConstraintName: "scim_api_keys_api_key_id_fkey"},
+		},
+		{
+			name: "not a postgres error",
+			err:  errors.New("connection refused"),
+			want: errors.New("connection refused"),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, constraintError(tc.err))
+		})
+	}
+}
+
+func TestGroupsFilterMembers(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+		want   string
+	}{
+		{
+			name:   "user member",
+			filter: `members.value eq "2819c223-7f76-453a-919d-413861904646"`,
+			want:   "group_users.user_id",
+		},
+		{
+			name:   "nested group member",
+			filter: `members[value eq "e9e30dba-f08f-4109-8486-d5c6a331660a"]`,
+			want:   "group_groups.member_group_id",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expression, err := filters.ParseFilter(tt.filter)
+			assert.NoError(t, err)
+
+			condition, args, err := groupsFilter.Translate(expression, 1)
+			assert.NoError(t, err)
+			assert.Contains(t, condition, tt.want)
+			assert.Contains(t, condition, "union all")
+			assert.Len(t, args, 1)
+		})
+	}
+}
@@ -21,6 +21,10 @@ type Authorizer interface {
 	RemoveUserFromGroup(ctx context.Context, userID uuid.UUID, groupID uuid.UUID) error
 	RemoveUsersInGroup(ctx context.Context, groupID uuid.UUID) error
 	ReplaceUsersInGroup(ctx context.Context, userIDs []uuid.UUID, groupID uuid.UUID) error
+
+	AddGroupsToGroup(ctx context.Context, groupIDs []uuid.UUID, groupID uuid.UUID) error
+	RemoveGroupFromGroup(ctx context.Context, memberGroupID uuid.UUID, groupID uuid.UUID) error
+	RemoveGroup(ctx context.Context, groupID uuid.UUID) error
 }
 
 func NewClient(fgaAPI *openfga.APIClient) Authorizer {
@@ -132,7 +136,7 @@ func (c *Client) RemoveUserFromGroup(ctx context.Context, userID uuid.UUID, grou
 	alreadyExists, err := c.CheckUserAlreadyExistsInGroup(ctx, userID, groupID)
 	if err != nil {
 		return err
-	} else if alreadyExists {
+	} else if !alreadyExists {
 		return nil
 	}
 
@@ -156,27 +160,117 @@ func (c *Client) RemoveUserFromGroup(ctx context.Context, userID uuid.UUID, grou
 	return nil
 }
 
+// RemoveUsersInGroup removes every member of the group, users as well as nested groups.
 func (c *Client) RemoveUsersInGroup(ctx context.Context, groupID uuid.UUID) error {
-	body := openfga.ReadRequest{
-		TupleKey: &openfga.TupleKey{
+	return c.deleteTuples(ctx, openfga.TupleKey{
+		Relation: openfga.PtrString("member"),
+		Object:   openfga.PtrString(groupObject(groupID)),
+	})
+}
+
+func (c *Client) ReplaceUsersInGroup(ctx context.Context, userIDs []uuid.UUID, groupID uuid.UUID) error {
+	err := c.RemoveUsersInGroup(ctx, groupID)
+	if err != nil {
+		return err
+	}
+
+	return c.AddUsersToGroup(ctx, userIDs, groupID)
+}
+
+func (c *Client) AddGroupsToGroup(ctx context.Context, groupIDs []uuid.UUID, groupID uuid.UUID) error {
+	memberTuples := make([]openfga.TupleKey, 0, len(groupIDs))
+	for _, member := range groupIDs {
+		memberTuples = append(memberTuples, openfga.TupleKey{
+			User:     openfga.PtrString(groupMembers(member)),
 			Relation: openfga.PtrString("member"),
-			Object:   openfga.PtrString(fmt.Sprintf("group:%s", groupID.String())),
+			Object:   openfga.PtrString(groupObject(groupID)),
+		})
+	}
+
+	if len(memberTuples) == 0 {
+		return nil
+	}
+
+	// tuples that already exist are skipped, as writing them again fails
+	memberTuples, err := c.missingTuples(ctx, memberTuples)
+	if err != nil || len(memberTuples) == 0 {
+		return err
+	}
+
+	body := openfga.WriteRequest{
+		Writes: &openfga.TupleKeys{
+			TupleKeys: memberTuples,
 		},
 	}
 
+	_, _, err = c.fgaAPI.OpenFgaApi.Write(ctx).Body(body).Execute()
+	return err
+}
+
+func (c *Client) RemoveGroupFromGroup(ctx context.Context, memberGroupID uuid.UUID, groupID uuid.UUID) error {
+	return c.deleteTuples(ctx, openfga.TupleKey{
+		User:     openfga.PtrString(groupMembers(memberGroupID)),
+		Relation: openfga.PtrString("member"),
+		Object:   openfga.PtrString(groupObject(groupID)),
+	})
+}
+
+// RemoveGroup removes the members of the group, and the group from the groups it is nested in.
+func (c *Client) RemoveGroup(ctx context.Context, groupID uuid.UUID) error {
+	err := c.RemoveUsersInGroup(ctx, groupID)
+	if err != nil {
+		return err
+	}
+
+	return c.deleteTuples(ctx, openfga.TupleKey{
+		User:     openfga.PtrString(groupMembers(groupID)),
+		Relation: openfga.PtrString("member"),
+		Object:   openfga.PtrString("group:"),
+	})
+}
+
+// missingTuples returns the tuples that are not stored yet.
+func (c *Client) missingTuples(ctx context.Context, tuples []openfga.TupleKey) ([]openfga.TupleKey, error) {
+	missing := make([]openfga.TupleKey, 0, len(tuples))
+	for _, tuple := range tuples {
+		tuple := tuple
+		resp, _, err := c.fgaAPI.OpenFgaApi.Read(ctx).Body(openfga.ReadRequest{TupleKey: &tuple}).Execute()
+		if err != nil {
+			return nil, err
+		}
+
+		if len(resp.GetTuples()) == 0 {
+			missing = append(missing, tuple)
+		}
+	}
+
+	return missing, nil
+}
+
+// deleteTuples deletes every tuple matching the key.
+func (c *Client) deleteTuples(ctx context.Context, key openfga.TupleKey) error {
+	body := openfga.ReadRequest{
+		TupleKey: &key,
+	}
+
 	for {
 		resp, _, err := c.fgaAPI.OpenFgaApi.Read(ctx).Body(body).Execute()
 		if err != nil {
 			return err
 		}
 
-		for _, tuple := range *resp.Tuples {
-			userID, err := uuid.Parse(*tuple.Key.User)
-			if err != nil {
-				return err
+		tuples := resp.GetTuples()
+		if len(tuples) > 0 {
+			deletes := make([]openfga.TupleKey, 0, len(tuples))
+			for _, tuple := range tuples {
+				deletes = append(deletes, tuple.GetKey())
 			}
 
-			err = c.RemoveUserFromGroup(ctx, userID, groupID)
+			_, _, err = c.fgaAPI.OpenFgaApi.Write(ctx).Body(openfga.WriteRequest{
+				Deletes: &openfga.TupleKeys{
+					TupleKeys: deletes,
+				},
+			}).Execute()
 			if err != nil {
 				return err
 			}
@@ -192,11 +286,11 @@ func (c *Client) RemoveUsersInGroup(ctx context.Context, groupID uuid.UUID) erro
 	return nil
 }
 
-func (c *Client) ReplaceUsersInGroup(ctx context.Context, userIDs []uuid.UUID, groupID uuid.UUID) error {
-	err := c.RemoveUsersInGroup(ctx, groupID)
-	if err != nil {
-		return err
-	}
+func groupObject(groupID uuid.UUID) string {
+	return fmt.Sprintf("group:%s", groupID.String())
+}
 
-	return c.AddUsersToGroup(ctx, userIDs, groupID)
+// groupMembers is the userset of the members of the group, used to nest it in other groups.
+func groupMembers(groupID uuid.UUID) string {
+	return fmt.Sprintf("group:%s#member", groupID.String())
 }
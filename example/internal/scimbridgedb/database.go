@@ -5,19 +5,28 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"reflect"
+	"strconv"
+	"time"
+
 	"github.com/suse-skyscraper/openfga-scim-bridge/example/internal/application"
 	"github.com/suse-skyscraper/openfga-scim-bridge/example/internal/db"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgtype"
+	"github.com/jackc/pgx/v4"
 	"github.com/suse-skyscraper/openfga-scim-bridge/v2/database"
-	"github.com/suse-skyscraper/openfga-scim-bridge/v2/payloads"
 )
 
 var _ database.Bridge = (*DB)(nil)
 
 type DB struct {
 	app *application.App
+
+	// tx is the transaction the DB is bound to, if any.
+	tx db.RepositoryQueries
+	// fgaWrites are the OpenFGA changes made by the transaction, applied once it is committed.
+	fgaWrites []func(ctx context.Context) error
 }
 
 func New(app *application.App) DB {
@@ -26,135 +35,297 @@ func New(app *application.App) DB {
 	}
 }
 
-func (d *DB) PatchGroup(ctx context.Context, groupID uuid.UUID, operations []payloads.GroupPatchOperation) error {
-	tx, err := d.app.Repository.Begin(ctx)
+func (d *DB) Transaction(ctx context.Context, fn func(tx database.Bridge) error) error {
+	return d.transaction(ctx, func(tx *DB) error {
+		return fn(tx)
+	})
+}
+
+// transaction runs fn with a DB bound to a transaction, joining the current one if there is one.
+// The OpenFGA changes are written once the transaction is committed, as they cannot be rolled back.
+func (d *DB) transaction(ctx context.Context, fn func(tx *DB) error) error {
+	if d.tx != nil {
+		return fn(d)
+	}
+
+	repositoryTx, err := d.app.Repository.Begin(ctx)
 	if err != nil {
-		return errors.New("failed to begin transaction")
+		return err
 	}
 
 	defer func(tx db.RepositoryQueries, ctx context.Context) {
 		_ = tx.Rollback(ctx)
-	}(tx, ctx)
+	}(repositoryTx, ctx)
 
-	for _, op := range operations {
-		switch op.Op {
-		case "add":
-			err = d.patchAdd(ctx, tx, groupID, op)
-			if err != nil {
-				return err
-			}
-		case "remove":
-			err = d.patchRemove(ctx, tx, groupID, op)
-			if err != nil {
-				return err
-			}
-		case "replace":
-			err = d.patchReplace(ctx, tx, groupID, op)
-			if err != nil {
-				return err
-			}
-		default:
-			return errors.New("unknown operation")
-		}
+	tx := &DB{
+		app: d.app,
+		tx:  repositoryTx,
 	}
 
-	return tx.Commit(ctx)
-}
-
-func (d *DB) patchAdd(ctx context.Context, tx db.RepositoryQueries, groupID uuid.UUID, op payloads.GroupPatchOperation) error {
-	newMembers, err := op.GetAddMembersPatch()
+	err = fn(tx)
 	if err != nil {
-		return errors.New("failed to get add members patch")
+		return err
 	}
 
-	err = d.app.FGAClient.AddUsersToGroup(ctx, newMembers, groupID)
+	err = repositoryTx.Commit(ctx)
 	if err != nil {
-		return errors.New("failed to add members to FGA group")
+		return err
 	}
 
-	err = tx.AddUsersToGroup(ctx, groupID, newMembers)
-	if err != nil {
-		return errors.New("failed to add members")
+	for _, write := range tx.fgaWrites {
+		err = write(ctx)
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-func (d *DB) patchRemove(ctx context.Context, tx db.RepositoryQueries, groupID uuid.UUID, op payloads.GroupPatchOperation) error {
-	id, err := op.ParseIDFromPath()
-	if err != nil {
-		return errors.New("failed to parse id from path")
+// afterCommit schedules an OpenFGA change of the transaction.
+func (d *DB) afterCommit(write func(ctx context.Context) error) {
+	d.fgaWrites = append(d.fgaWrites, write)
+}
+
+// repository returns the queries of the current transaction, or of the pool outside of one.
+func (d *DB) repository() db.RepositoryQueries {
+	if d.tx != nil {
+		return d.tx
 	}
 
-	err = d.app.FGAClient.RemoveUserFromGroup(ctx, id, groupID)
+	return d.app.Repository
+}
+
+func (d *DB) PatchGroup(ctx context.Context, groupID uuid.UUID, patch database.GroupPatch) error {
+	extensions, err := parseJSONB(patch.Extensions)
 	if err != nil {
 		return err
 	}
 
-	return tx.RemoveUserFromGroup(ctx, id, groupID)
+	err = d.transaction(ctx, func(tx *DB) error {
+		_, err := tx.repository().UpdateGroup(ctx, db.UpdateGroupParams{
+			ID:          groupID,
+			DisplayName: patch.DisplayName,
+			ExternalID:  nullString(patch.ExternalID),
+			Extensions:  extensions,
+		})
+		if err != nil {
+			return err
+		}
+
+		return tx.updateMembers(ctx, groupID, patch.AddedMembers, patch.RemovedMembers)
+	})
+
+	return translateError(err)
 }
 
-func (d *DB) patchReplace(ctx context.Context, tx db.RepositoryQueries, groupID uuid.UUID, op payloads.GroupPatchOperation) error {
-	switch op.Path {
-	case "members":
-		newMembers, err := op.GetAddMembersPatch()
+func (d *DB) ReplaceGroup(ctx context.Context, groupID uuid.UUID, arg database.GroupParams) (database.Group, error) {
+	extensions, err := parseJSONB(arg.Extensions)
+	if err != nil {
+		return database.Group{}, err
+	}
+
+	var group db.Group
+	err = d.transaction(ctx, func(tx *DB) error {
+		var err error
+		group, err = tx.repository().UpdateGroup(ctx, db.UpdateGroupParams{
+			ID:          groupID,
+			DisplayName: arg.DisplayName,
+			ExternalID:  nullString(arg.ExternalID),
+			Extensions:  extensions,
+		})
 		if err != nil {
-			return errors.New("failed to get add members patch")
+			return err
 		}
 
-		err = d.app.FGAClient.ReplaceUsersInGroup(ctx, newMembers, groupID)
+		members, err := tx.GetGroupMembership(ctx, groupID)
 		if err != nil {
-			return errors.New("failed to replace members in FGA")
+			return err
+		}
+
+		current := map[database.Member]bool{}
+		for _, member := range members {
+			current[database.Member{ID: member.MemberID, Type: member.Type}] = true
+		}
+
+		replaced := map[database.Member]bool{}
+		var added, removed []database.Member
+		for _, member := range arg.Members {
+			if !current[member] && !replaced[member] {
+				added = append(added, member)
+			}
+			replaced[member] = true
+		}
+		for _, member := range members {
+			member := database.Member{ID: member.MemberID, Type: member.Type}
+			if !replaced[member] {
+				removed = append(removed, member)
+			}
+		}
+
+		return tx.updateMembers(ctx, groupID, added, removed)
+	})
+	if err != nil {
+		return database.Group{}, translateError(err)
+	}
+
+	return toScimGroup(group)
+}
+
+// updateMembers adds and removes members of the group, in the database and in OpenFGA. Nested groups
+// are members through the group#member userset of OpenFGA.
+func (d *DB) updateMembers(ctx context.Context, groupID uuid.UUID, added, removed []database.Member) error {
+	for _, member := range removed {
+		member := member
+
+		if member.Type == database.GroupMember {
+			err := d.repository().RemoveGroupFromGroup(ctx, member.ID, groupID)
+			if err != nil {
+				return err
+			}
+
+			d.afterCommit(func(ctx context.Context) error {
+				return d.app.FGAClient.RemoveGroupFromGroup(ctx, member.ID, groupID)
+			})
+			continue
 		}
 
-		err = tx.ReplaceUsersInGroup(ctx, groupID, newMembers)
+		err := d.repository().RemoveUserFromGroup(ctx, member.ID, groupID)
 		if err != nil {
-			return errors.New("failed to replace members")
+			return err
 		}
-	default:
-		patch, err := op.GetPatch()
+
+		d.afterCommit(func(ctx context.Context) error {
+			return d.app.FGAClient.RemoveUserFromGroup(ctx, member.ID, groupID)
+		})
+	}
+
+	var users, groups []uuid.UUID
+	for _, member := range added {
+		if member.Type == database.GroupMember {
+			groups = append(groups, member.ID)
+		} else {
+			users = append(users, member.ID)
+		}
+	}
+
+	if len(users) > 0 {
+		err := d.repository().AddUsersToGroup(ctx, groupID, users)
 		if err != nil {
-			return errors.New("failed to get patch")
+			return err
 		}
 
-		_, err = tx.UpdateGroup(ctx, db.PatchGroupDisplayNameParams{
-			ID:          groupID,
-			DisplayName: patch.DisplayName,
+		d.afterCommit(func(ctx context.Context) error {
+			return d.app.FGAClient.AddUsersToGroup(ctx, users, groupID)
 		})
+	}
+
+	for _, memberGroupID := range groups {
+		err := d.checkCycle(ctx, memberGroupID, groupID)
+		if err != nil {
+			return err
+		}
+
+		err = d.repository().AddGroupToGroup(ctx, memberGroupID, groupID)
 		if err != nil {
-			return errors.New("failed to patch display name")
+			return err
 		}
 	}
 
+	if len(groups) > 0 {
+		d.afterCommit(func(ctx context.Context) error {
+			return d.app.FGAClient.AddGroupsToGroup(ctx, groups, groupID)
+		})
+	}
+
 	return nil
 }
 
-func (d *DB) DeleteGroup(ctx context.Context, groupID uuid.UUID) error {
-	err := d.app.FGAClient.RemoveUsersInGroup(ctx, groupID)
+// checkCycle returns database.ErrMembershipCycle if nesting the member group in the group would make
+// the group a member of itself.
+func (d *DB) checkCycle(ctx context.Context, memberGroupID, groupID uuid.UUID) error {
+	if memberGroupID == groupID {
+		return database.ErrMembershipCycle
+	}
+
+	nested, err := d.repository().GetNestedGroupIDs(ctx, memberGroupID)
 	if err != nil {
 		return err
 	}
 
-	err = d.app.Repository.DeleteGroup(ctx, groupID.String())
-	if err != nil {
+	for _, id := range nested {
+		if id == groupID {
+			return database.ErrMembershipCycle
+		}
+	}
+
+	return nil
+}
+
+func (d *DB) CheckGroupVersion(ctx context.Context, groupID uuid.UUID, version string) error {
+	updatedAt, err := d.repository().LockGroup(ctx, groupID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return database.ErrPreconditionFailed
+	} else if err != nil {
 		return err
 	}
 
+	if resourceVersion(updatedAt) != version {
+		return database.ErrPreconditionFailed
+	}
+
 	return nil
 }
 
-func (d *DB) CreateGroup(ctx context.Context, displayName string) (database.Group, error) {
-	group, err := d.app.Repository.CreateGroup(ctx, displayName)
+func (d *DB) DeleteGroup(ctx context.Context, groupID uuid.UUID) error {
+	return d.transaction(ctx, func(tx *DB) error {
+		err := tx.repository().DeleteGroup(ctx, groupID.String())
+		if err != nil {
+			return err
+		}
+
+		tx.afterCommit(func(ctx context.Context) error {
+			return d.app.FGAClient.RemoveGroup(ctx, groupID)
+		})
+
+		return nil
+	})
+}
+
+func (d *DB) CreateGroup(ctx context.Context, arg database.GroupParams) (database.Group, error) {
+	extensions, err := parseJSONB(arg.Extensions)
 	if err != nil {
 		return database.Group{}, err
 	}
 
-	scimGroup := toScimGroup(group)
-	return scimGroup, nil
+	var group db.Group
+	err = d.transaction(ctx, func(tx *DB) error {
+		var err error
+		group, err = tx.repository().CreateGroup(ctx, db.CreateGroupParams{
+			DisplayName: arg.DisplayName,
+			ExternalID:  nullString(arg.ExternalID),
+			Extensions:  extensions,
+		})
+		if err != nil {
+			return err
+		}
+
+		return tx.updateMembers(ctx, group.ID, arg.Members, nil)
+	})
+	if err != nil {
+		return database.Group{}, translateError(err)
+	}
+
+	return toScimGroup(group)
 }
 
 func (d *DB) GetGroupMembership(ctx context.Context, groupID uuid.UUID) ([]database.GroupMembership, error) {
-	members, err := d.app.Repository.GetGroupMembership(ctx, groupID.String())
+	members, err := d.repository().GetGroupMembership(ctx, groupID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	nestedGroups, err := d.repository().GetNestedGroupMembership(ctx, groupID)
 	if err != nil {
 		return nil, err
 	}
@@ -163,29 +334,48 @@ func (d *DB) GetGroupMembership(ctx context.Context, groupID uuid.UUID) ([]datab
 	for _, member := range members {
 		groupMembers = append(groupMembers, database.GroupMembership{
 			GroupID:  member.GroupID,
-			Username: member.Username,
-			UserID:   member.UserID,
+			MemberID: member.UserID,
+			Type:     database.UserMember,
+			Display:  member.Username,
+		})
+	}
+
+	for _, member := range nestedGroups {
+		groupMembers = append(groupMembers, database.GroupMembership{
+			GroupID:  member.GroupID,
+			MemberID: member.MemberGroupID,
+			Type:     database.GroupMember,
+			Display:  member.DisplayName,
 		})
 	}
 
 	return groupMembers, nil
 }
 
-func (d *DB) FindGroup(ctx context.Context, userID uuid.UUID) (database.Group, error) {
-	group, err := d.app.Repository.FindGroup(ctx, userID.String())
+// FindGroup locks the group within a transaction, see database.Bridge.
+func (d *DB) FindGroup(ctx context.Context, groupID uuid.UUID) (database.Group, error) {
+	if d.tx != nil {
+		_, err := d.repository().LockGroup(ctx, groupID)
+		if err != nil {
+			return database.Group{}, translateError(err)
+		}
+	}
+
+	group, err := d.repository().FindGroup(ctx, groupID.String())
 	if err != nil {
-		return database.Group{}, err
+		return database.Group{}, translateError(err)
 	}
 
-	scimGroup := toScimGroup(group)
-	return scimGroup, nil
+	return toScimGroup(group)
 }
 
-func (d *DB) GetGroups(ctx context.Context, limit int32, offset int32) (int64, []database.Group, error) {
-
-	totalCount, groups, err := d.app.Repository.GetGroups(ctx, db.GetGroupsParams{
-		Offset: offset,
-		Limit:  limit,
+func (d *DB) GetGroups(ctx context.Context, input database.GetGroupsParams) (int64, []database.Group, error) {
+	totalCount, groups, err := d.repository().GetScimGroups(ctx, db.GetScimGroupsInput{
+		Filter:    input.Filter,
+		SortBy:    input.SortBy,
+		SortOrder: input.SortOrder,
+		Offset:    input.Offset,
+		Limit:     input.Limit,
 	})
 	if err != nil {
 		return 0, nil, err
@@ -193,16 +383,29 @@ func (d *DB) GetGroups(ctx context.Context, limit int32, offset int32) (int64, [
 
 	var scimGroups []database.Group
 	for _, group := range groups {
-		scimGroups = append(scimGroups, toScimGroup(group))
+		scimGroup, err := toScimGroup(group)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		scimGroups = append(scimGroups, scimGroup)
 	}
 
 	return totalCount, scimGroups, nil
 }
 
+// FindUser locks the user within a transaction, see database.Bridge.
 func (d *DB) FindUser(ctx context.Context, userID uuid.UUID) (database.User, error) {
-	user, err := d.app.Repository.FindUser(ctx, userID.String())
+	if d.tx != nil {
+		_, err := d.repository().LockUser(ctx, userID)
+		if err != nil {
+			return database.User{}, translateError(err)
+		}
+	}
+
+	user, err := d.repository().FindUser(ctx, userID.String())
 	if err != nil {
-		return database.User{}, err
+		return database.User{}, translateError(err)
 	}
 
 	scimUser, err := toScimUser(user)
@@ -212,50 +415,39 @@ func (d *DB) FindUser(ctx context.Context, userID uuid.UUID) (database.User, err
 	return scimUser, nil
 }
 
-func (d *DB) SetUserActive(ctx context.Context, userID uuid.UUID, active bool) error {
-	err := d.app.Repository.ScimPatchUser(ctx, db.PatchUserParams{
-		ID:     userID,
-		Active: active,
-	})
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
 func (d *DB) UpdateUser(ctx context.Context, userID uuid.UUID, arg database.UserParams) (database.User, error) {
-	name, err := parseJSONB(arg.Name)
+	columns, err := parseUserJSONB(arg)
 	if err != nil {
 		return database.User{}, err
 	}
 
-	emails, err := parseJSONB(arg.Emails)
-	if err != nil {
-		return database.User{}, err
-	}
-
-	user, err := d.app.Repository.UpdateUser(ctx, userID, db.UpdateUserParams{
-		ID:       userID,
-		Username: arg.Username,
-		Name:     name,
-		Active:   arg.Active,
-		Emails:   emails,
-		Locale: sql.NullString{
-			String: arg.Locale,
-			Valid:  arg.Locale != "",
-		},
-		DisplayName: sql.NullString{
-			String: arg.DisplayName,
-			Valid:  arg.DisplayName != "",
-		},
-		ExternalID: sql.NullString{
-			String: arg.ExternalID,
-			Valid:  arg.ExternalID != "",
-		},
+	user, err := d.repository().UpdateUser(ctx, userID, db.UpdateUserParams{
+		ID:                userID,
+		Username:          arg.Username,
+		Name:              columns.name,
+		Active:            arg.Active,
+		Emails:            columns.emails,
+		Locale:            nullString(arg.Locale),
+		DisplayName:       nullString(arg.DisplayName),
+		ExternalID:        nullString(arg.ExternalID),
+		EnterpriseUser:    columns.enterpriseUser,
+		Nickname:          nullString(arg.NickName),
+		ProfileUrl:        nullString(arg.ProfileURL),
+		Title:             nullString(arg.Title),
+		UserType:          nullString(arg.UserType),
+		PreferredLanguage: nullString(arg.PreferredLanguage),
+		Timezone:          nullString(arg.Timezone),
+		PhoneNumbers:      columns.phoneNumbers,
+		Ims:               columns.ims,
+		Photos:            columns.photos,
+		Addresses:         columns.addresses,
+		Entitlements:      columns.entitlements,
+		Roles:             columns.roles,
+		X509Certificates:  columns.x509Certificates,
+		Extensions:        columns.extensions,
 	})
 	if err != nil {
-		return database.User{}, err
+		return database.User{}, translateError(err)
 	}
 
 	scimUser, err := toScimUser(user)
@@ -266,80 +458,103 @@ func (d *DB) UpdateUser(ctx context.Context, userID uuid.UUID, arg database.User
 	return scimUser, nil
 }
 
-func (d *DB) DeleteUser(ctx context.Context, userID uuid.UUID) error {
-	tx, err := d.app.Repository.Begin(ctx)
+func (d *DB) GetUserGroups(ctx context.Context, userID uuid.UUID, indirect bool) ([]database.UserGroup, error) {
+	groups, err := d.repository().GetGroupsForUser(ctx, userID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	defer func(tx db.RepositoryQueries, ctx context.Context) {
-		_ = tx.Rollback(ctx)
-	}(tx, ctx)
+	var userGroups []database.UserGroup
+	for _, group := range groups {
+		userGroups = append(userGroups, database.UserGroup{
+			GroupID:     group.ID,
+			DisplayName: group.DisplayName,
+			Type:        database.DirectMembership,
+		})
+	}
 
-	err = tx.DeleteUser(ctx, userID)
-	if err != nil {
-		return err
+	if !indirect {
+		return userGroups, nil
 	}
 
-	err = tx.Commit(ctx)
+	indirectGroups, err := d.repository().GetIndirectGroupsForUser(ctx, userID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	err = d.app.FGAClient.RemoveUser(ctx, userID)
-	if err != nil {
-		return err
+	for _, group := range indirectGroups {
+		userGroups = append(userGroups, database.UserGroup{
+			GroupID:     group.ID,
+			DisplayName: group.DisplayName,
+			Type:        database.IndirectMembership,
+		})
 	}
 
-	return nil
+	return userGroups, nil
 }
 
-func (d *DB) CreateUser(ctx context.Context, arg database.UserParams) (database.User, error) {
-	tx, err := d.app.Repository.Begin(ctx)
-	if err != nil {
-		return database.User{}, err
+func (d *DB) CheckUserVersion(ctx context.Context, userID uuid.UUID, version string) error {
+	updatedAt, err := d.repository().LockUser(ctx, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return database.ErrPreconditionFailed
+	} else if err != nil {
+		return err
 	}
 
-	defer func(tx db.RepositoryQueries, ctx context.Context) {
-		_ = tx.Rollback(ctx)
-	}(tx, ctx)
-
-	name, err := parseJSONB(arg.Name)
-	if err != nil {
-		return database.User{}, err
+	if resourceVersion(updatedAt) != version {
+		return database.ErrPreconditionFailed
 	}
 
-	emails, err := parseJSONB(arg.Emails)
-	if err != nil {
-		return database.User{}, err
-	}
+	return nil
+}
+
+func (d *DB) DeleteUser(ctx context.Context, userID uuid.UUID) error {
+	return d.transaction(ctx, func(tx *DB) error {
+		err := tx.repository().DeleteUser(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		tx.afterCommit(func(ctx context.Context) error {
+			return d.app.FGAClient.RemoveUser(ctx, userID)
+		})
 
-	user, err := tx.CreateUser(ctx, db.CreateUserParams{
-		Username: arg.Username,
-		Name:     name,
-		Active:   arg.Active,
-		Emails:   emails,
-		Locale: sql.NullString{
-			String: arg.Locale,
-			Valid:  arg.Locale != "",
-		},
-		ExternalID: sql.NullString{
-			String: arg.ExternalID,
-			Valid:  arg.ExternalID != "",
-		},
-		DisplayName: sql.NullString{
-			String: arg.DisplayName,
-			Valid:  arg.DisplayName != "",
-		},
+		return nil
 	})
+}
 
+func (d *DB) CreateUser(ctx context.Context, arg database.UserParams) (database.User, error) {
+	columns, err := parseUserJSONB(arg)
 	if err != nil {
 		return database.User{}, err
 	}
 
-	err = tx.Commit(ctx)
+	user, err := d.repository().CreateUser(ctx, db.CreateUserParams{
+		Username:          arg.Username,
+		Name:              columns.name,
+		Active:            arg.Active,
+		Emails:            columns.emails,
+		Locale:            nullString(arg.Locale),
+		ExternalID:        nullString(arg.ExternalID),
+		DisplayName:       nullString(arg.DisplayName),
+		EnterpriseUser:    columns.enterpriseUser,
+		Nickname:          nullString(arg.NickName),
+		ProfileUrl:        nullString(arg.ProfileURL),
+		Title:             nullString(arg.Title),
+		UserType:          nullString(arg.UserType),
+		PreferredLanguage: nullString(arg.PreferredLanguage),
+		Timezone:          nullString(arg.Timezone),
+		PhoneNumbers:      columns.phoneNumbers,
+		Ims:               columns.ims,
+		Photos:            columns.photos,
+		Addresses:         columns.addresses,
+		Entitlements:      columns.entitlements,
+		Roles:             columns.roles,
+		X509Certificates:  columns.x509Certificates,
+		Extensions:        columns.extensions,
+	})
 	if err != nil {
-		return database.User{}, err
+		return database.User{}, translateError(err)
 	}
 
 	scimUser, err := toScimUser(user)
@@ -351,10 +566,12 @@ func (d *DB) CreateUser(ctx context.Context, arg database.UserParams) (database.
 }
 
 func (d *DB) GetUsers(ctx context.Context, input database.GetUsersParams) (int64, []database.User, error) {
-	count, users, err := d.app.Repository.GetScimUsers(ctx, db.GetScimUsersInput{
-		Filters: input.Filters,
-		Offset:  input.Offset,
-		Limit:   input.Limit,
+	count, users, err := d.repository().GetScimUsers(ctx, db.GetScimUsersInput{
+		Filter:    input.Filter,
+		SortBy:    input.SortBy,
+		SortOrder: input.SortOrder,
+		Offset:    input.Offset,
+		Limit:     input.Limit,
 	})
 	if err != nil {
 		return 0, nil, err
@@ -373,52 +590,96 @@ func (d *DB) GetUsers(ctx context.Context, input database.GetUsersParams) (int64
 	return count, scimUsers, nil
 }
 
-func toScimGroup(group db.Group) database.Group {
+func toScimGroup(group db.Group) (database.Group, error) {
 	scimGroup := database.Group{
 		ID:          group.ID,
 		DisplayName: group.DisplayName,
+		ExternalID:  group.ExternalID,
 		CreatedAt:   group.CreatedAt,
 		UpdatedAt:   group.UpdatedAt,
+		Version:     resourceVersion(group.UpdatedAt),
+	}
+
+	if group.Extensions.Bytes != nil {
+		err := json.Unmarshal(group.Extensions.Bytes, &scimGroup.Extensions)
+		if err != nil {
+			return database.Group{}, err
+		}
 	}
 
-	return scimGroup
+	return scimGroup, nil
 }
 
 func toScimUser(user db.User) (database.User, error) {
-	var name map[string]string
-	if user.Name.Bytes != nil {
-		err := json.Unmarshal(user.Name.Bytes, &name)
-		if err != nil {
-			return database.User{}, err
+	scimUser := database.User{
+		ID:                user.ID,
+		Username:          user.Username,
+		ExternalID:        user.ExternalID,
+		DisplayName:       user.DisplayName,
+		NickName:          user.Nickname,
+		ProfileURL:        user.ProfileUrl,
+		Title:             user.Title,
+		UserType:          user.UserType,
+		PreferredLanguage: user.PreferredLanguage,
+		Locale:            user.Locale,
+		Timezone:          user.Timezone,
+		Active:            user.Active,
+		CreatedAt:         user.CreatedAt,
+		UpdatedAt:         user.UpdatedAt,
+		Version:           resourceVersion(user.UpdatedAt),
+	}
+
+	for _, column := range []struct {
+		value  pgtype.JSONB
+		target interface{}
+	}{
+		{user.Name, &scimUser.Name},
+		{user.Emails, &scimUser.Emails},
+		{user.EnterpriseUser, &scimUser.EnterpriseUser},
+		{user.PhoneNumbers, &scimUser.PhoneNumbers},
+		{user.Ims, &scimUser.Ims},
+		{user.Photos, &scimUser.Photos},
+		{user.Addresses, &scimUser.Addresses},
+		{user.Entitlements, &scimUser.Entitlements},
+		{user.Roles, &scimUser.Roles},
+		{user.X509Certificates, &scimUser.X509Certificates},
+		{user.Extensions, &scimUser.Extensions},
+	} {
+		if column.value.Bytes == nil {
+			continue
 		}
-	}
 
-	var emails []payloads.UserEmail
-	if user.Emails.Bytes != nil {
-		err := json.Unmarshal(user.Emails.Bytes, &emails)
+		err := json.Unmarshal(column.value.Bytes, column.target)
 		if err != nil {
 			return database.User{}, err
 		}
 	}
 
-	scimUser := database.User{
-		ID:          user.ID,
-		Username:    user.Username,
-		ExternalID:  user.ExternalID,
-		Name:        name,
-		DisplayName: user.DisplayName,
-		Locale:      user.Locale,
-		Active:      user.Active,
-		Emails:      emails,
-		CreatedAt:   user.CreatedAt,
-		UpdatedAt:   user.UpdatedAt,
+	return scimUser, nil
+}
+
+// translateError returns the database package equivalent of the errors of the repository, so that the
+// bridge renders them as SCIM errors.
+func translateError(err error) error {
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return database.ErrNotFound
+	default:
+		return err
 	}
+}
 
-	return scimUser, nil
+// resourceVersion derives the version of a user or group from the time it was last updated, which
+// every change sets to the start of its transaction. Membership changes update both the groups and
+// the users, whose representation lists their groups.
+func resourceVersion(updatedAt time.Time) string {
+	return strconv.FormatInt(updatedAt.UnixMicro(), 10)
 }
 
+// parseJSONB stores nil and empty values, e.g. a user without emails, as NULL rather than as a JSON
+// null or an empty list, so that filters treat them as unassigned.
 func parseJSONB(arg interface{}) (pgtype.JSONB, error) {
-	if arg == nil {
+	if isEmpty(arg) {
 		return pgtype.JSONB{Bytes: nil, Status: pgtype.Null}, nil
 	}
 
@@ -430,3 +691,77 @@ func parseJSONB(arg interface{}) (pgtype.JSONB, error) {
 
 	return name, nil
 }
+
+// isEmpty reports whether the value is nil, a nil pointer, or an empty slice or map.
+func isEmpty(value interface{}) bool {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	default:
+		return false
+	}
+}
+
+// userJSONB holds the JSONB columns of a user.
+type userJSONB struct {
+	name             pgtype.JSONB
+	emails           pgtype.JSONB
+	enterpriseUser   pgtype.JSONB
+	phoneNumbers     pgtype.JSONB
+	ims              pgtype.JSONB
+	photos           pgtype.JSONB
+	addresses        pgtype.JSONB
+	entitlements     pgtype.JSONB
+	roles            pgtype.JSONB
+	x509Certificates pgtype.JSONB
+	extensions       pgtype.JSONB
+}
+
+func parseUserJSONB(arg database.UserParams) (userJSONB, error) {
+	var columns userJSONB
+	var err error
+
+	for _, column := range []struct {
+		value  interface{}
+		target *pgtype.JSONB
+	}{
+		{arg.Name, &columns.name},
+		{arg.Emails, &columns.emails},
+		{arg.PhoneNumbers, &columns.phoneNumbers},
+		{arg.Ims, &columns.ims},
+		{arg.Photos, &columns.photos},
+		{arg.Addresses, &columns.addresses},
+		{arg.Entitlements, &columns.entitlements},
+		{arg.Roles, &columns.roles},
+		{arg.X509Certificates, &columns.x509Certificates},
+	} {
+		*column.target, err = parseJSONB(column.value)
+		if err != nil {
+			return userJSONB{}, err
+		}
+	}
+
+	columns.enterpriseUser, err = parseJSONB(arg.EnterpriseUser)
+	if err != nil {
+		return userJSONB{}, err
+	}
+
+	columns.extensions, err = parseJSONB(arg.Extensions)
+	if err != nil {
+		return userJSONB{}, err
+	}
+
+	return columns, nil
+}
+
+func nullString(value string) sql.NullString {
+	return sql.NullString{
+		String: value,
+		Valid:  value != "",
+	}
+}
@@ -0,0 +1,70 @@
+package scimbridgedb
+
+import (
+	"testing"
+
+	"github.com/jackc/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/database"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/payloads"
+)
+
+func TestParseJSONB(t *testing.T) {
+	tests := []struct {
+		name       string
+		arg        interface{}
+		wantStatus pgtype.Status
+		wantBytes  string
+	}{
+		{name: "nil", arg: nil, wantStatus: pgtype.Null},
+		{name: "nil slice", arg: []payloads.UserEmail(nil), wantStatus: pgtype.Null},
+		{name: "empty slice", arg: []payloads.MultiValuedAttribute{}, wantStatus: pgtype.Null},
+		{name: "nil map", arg: map[string]string(nil), wantStatus: pgtype.Null},
+		{name: "nil pointer", arg: (*payloads.EnterpriseUser)(nil), wantStatus: pgtype.Null},
+		{name: "empty extensions", arg: database.Extensions{}, wantStatus: pgtype.Null},
+		{
+			name:       "emails",
+			arg:        []payloads.UserEmail{{Value: "bjensen@example.com"}},
+			wantStatus: pgtype.Present,
+			wantBytes:  `[{"value":"bjensen@example.com"}]`,
+		},
+		{
+			name:       "name",
+			arg:        map[string]string{"familyName": "Jensen"},
+			wantStatus: pgtype.Present,
+			wantBytes:  `{"familyName":"Jensen"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseJSONB(tc.arg)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantStatus, got.Status)
+			if tc.wantBytes != "" {
+				assert.JSONEq(t, tc.wantBytes, string(got.Bytes))
+			}
+		})
+	}
+}
+
+func TestParseUserJSONBWithoutAttributes(t *testing.T) {
+	columns, err := parseUserJSONB(database.UserParams{Username: "bjensen"})
+	assert.NoError(t, err)
+
+	for _, column := range []pgtype.JSONB{
+		columns.name,
+		columns.emails,
+		columns.phoneNumbers,
+		columns.ims,
+		columns.photos,
+		columns.addresses,
+		columns.entitlements,
+		columns.roles,
+		columns.x509Certificates,
+		columns.enterpriseUser,
+		columns.extensions,
+	} {
+		assert.Equal(t, pgtype.Null, column.Status)
+	}
+}
@@ -19,26 +19,49 @@ select *
 from users
 where id = $1;
 
+-- name: LockUser :one
+select updated_at
+from users
+where id = $1
+    for update;
+
 -- name: FindByUsername :one
 select *
 from users
 where username = $1;
 
 -- name: CreateUser :one
-insert into users (username, name, display_name, emails, active, locale, external_id, created_at, updated_at)
-values ($1, $2, $3, $4, $5, $6, $7, now(), now())
+insert into users (username, name, display_name, emails, active, locale, external_id, enterprise_user, nickname,
+                   profile_url, title, user_type, preferred_language, timezone, phone_numbers, ims, photos, addresses,
+                   entitlements, roles, x509_certificates, extensions, created_at, updated_at)
+values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, now(), now())
 returning *;
 
 -- name: UpdateUser :exec
 update users
-set username     =$2,
-    name         = $3,
-    display_name = $4,
-    emails       = $5,
-    active       = $6,
-    external_id  = $7,
-    locale       = $8,
-    updated_at   = now()
+set username           = $2,
+    name               = $3,
+    display_name       = $4,
+    emails             = $5,
+    active             = $6,
+    external_id        = $7,
+    locale             = $8,
+    enterprise_user    = $9,
+    nickname           = $10,
+    profile_url        = $11,
+    title              = $12,
+    user_type          = $13,
+    preferred_language = $14,
+    timezone           = $15,
+    phone_numbers      = $16,
+    ims                = $17,
+    photos             = $18,
+    addresses          = $19,
+    entitlements       = $20,
+    roles              = $21,
+    x509_certificates  = $22,
+    extensions         = $23,
+    updated_at         = now()
 where id = $1;
 
 -- name: PatchUser :exec
@@ -71,9 +94,15 @@ select *
 from groups
 where id = $1;
 
+-- name: LockGroup :one
+select updated_at
+from groups
+where id = $1
+    for update;
+
 -- name: CreateGroup :one
-insert into groups (display_name, created_at, updated_at)
-values ($1, now(), now())
+insert into groups (display_name, external_id, extensions, created_at, updated_at)
+values ($1, $2, $3, now(), now())
 returning *;
 
 -- name: DeleteGroup :exec
@@ -85,9 +114,11 @@ where id = $1;
 select count(*)
 from groups;
 
--- name: PatchGroupDisplayName :exec
+-- name: UpdateGroup :exec
 update groups
 set display_name = $2,
+    external_id  = $3,
+    extensions   = $4,
     updated_at   = now()
 where id = $1;
 
@@ -124,6 +155,91 @@ insert into group_users (user_id, group_id)
 values ($1, $2)
 on conflict (user_id, group_id) do nothing;
 
+-- name: GetGroupsForUser :many
+select groups.id, groups.display_name
+from group_users
+         join groups on groups.id = group_users.group_id
+where group_users.user_id = $1;
+
+-- name: GetIndirectGroupsForUser :many
+with recursive parents (group_id) as (select group_groups.group_id
+                                      from group_groups
+                                               join group_users on group_users.group_id = group_groups.member_group_id
+                                      where group_users.user_id = $1
+                                      union
+                                      select group_groups.group_id
+                                      from group_groups
+                                               join parents on parents.group_id = group_groups.member_group_id)
+select groups.id, groups.display_name
+from parents
+         join groups on groups.id = parents.group_id
+where groups.id not in (select group_users.group_id from group_users where group_users.user_id = $1);
+
+-- name: GetNestedGroupMembership :many
+select group_groups.*, groups.display_name as display_name
+from group_groups
+         left join groups on groups.id = group_groups.member_group_id
+where group_groups.group_id = $1;
+
+-- name: GetNestedGroupIDs :many
+with recursive nested (member_group_id) as (select group_groups.member_group_id
+                                             from group_groups
+                                             where group_groups.group_id = $1
+                                             union
+                                             select group_groups.member_group_id
+                                             from group_groups
+                                                      join nested on nested.member_group_id = group_groups.group_id)
+select member_group_id
+from nested;
+
+-- name: DropMembershipForGroupAndGroup :exec
+delete
+from group_groups
+where member_group_id = $1
+  and group_id = $2;
+
+-- name: TouchUser :exec
+-- users list their groups, so membership changes update them
+update users
+set updated_at = now()
+where id = $1;
+
+-- name: TouchGroupsForUser :exec
+-- deleting a user removes it from the members of its groups
+update groups
+set updated_at = now()
+where id in (select group_users.group_id
+             from group_users
+             where group_users.user_id = $1);
+
+-- name: TouchGroupUsers :exec
+-- nesting a group changes the indirect groups of its users, directly or through nested groups
+update users
+set updated_at = now()
+where id in (with recursive nested (id) as (select groups.id
+                                            from groups
+                                            where groups.id = $1
+                                            union
+                                            select group_groups.member_group_id
+                                            from group_groups
+                                                     join nested on nested.id = group_groups.group_id)
+             select group_users.user_id
+             from group_users
+                      join nested on nested.id = group_users.group_id);
+
+-- name: TouchParentGroups :exec
+-- deleting a group removes it from the members of its parent groups
+update groups
+set updated_at = now()
+where id in (select group_groups.group_id
+             from group_groups
+             where group_groups.member_group_id = $1);
+
+-- name: CreateMembershipForGroupAndGroup :exec
+insert into group_groups (member_group_id, group_id)
+values ($1, $2)
+on conflict (group_id, member_group_id) do nothing;
+
 --------------------------------------------------------------------------------------------------------------------
 -- SCIM API Key
 --------------------------------------------------------------------------------------------------------------------
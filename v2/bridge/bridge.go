@@ -1,17 +1,197 @@
 package bridge
 
 import (
+	"strings"
+
+	"github.com/pkg/errors"
 	"github.com/suse-skyscraper/openfga-scim-bridge/v2/database"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/schema"
+)
+
+// The limits of a Bridge created by New. They also apply when the fields of a Bridge are not set.
+const (
+	DefaultPageSize           = 10
+	DefaultMaxPageSize        = 1000
+	DefaultBulkMaxOperations  = 1000
+	DefaultBulkMaxPayloadSize = 1 << 20
 )
 
 type Bridge struct {
 	BaseURL string
 	DB      database.Bridge
+	// PageSize is the number of resources returned by list requests without a count. It defaults to
+	// DefaultPageSize.
+	PageSize int
+	// MaxPageSize is the largest count a list request may ask for. It defaults to
+	// DefaultMaxPageSize.
+	MaxPageSize int
+	// SortSupported enables the sortBy and sortOrder parameters of list requests. Enable it only if
+	// the database honours the sort fields of GetUsersParams and GetGroupsParams.
+	SortSupported bool
+	// ETagSupported advertises entity tags in the ServiceProviderConfig. Enable it only if the
+	// database sets the Version of users and groups, resources without a version have no ETag.
+	ETagSupported bool
+	// BulkMaxOperations is the largest number of operations accepted in a bulk request. It defaults
+	// to DefaultBulkMaxOperations.
+	BulkMaxOperations int
+	// BulkMaxPayloadSize is the largest size of a bulk request in bytes. It defaults to
+	// DefaultBulkMaxPayloadSize.
+	BulkMaxPayloadSize int
+	// IndirectGroups adds the groups users belong to through nested groups to the groups attribute of
+	// users, besides the groups they are direct members of.
+	IndirectGroups bool
+	// AuthenticationSchemes are advertised in the ServiceProviderConfig. They should describe what the
+	// authorization middleware passed to router.Hook accepts.
+	AuthenticationSchemes []AuthenticationScheme
+
+	// extensions are the custom extension schemas added by RegisterExtension.
+	extensions []extension
+}
+
+// extension is a custom extension schema of a resource type.
+type extension struct {
+	resourceType string
+	schema       schema.Schema
+}
+
+// AuthenticationScheme is an authentication scheme supported by the service provider, as described
+// in RFC 7643 section 5.
+type AuthenticationScheme struct {
+	// Type is one of "oauth", "oauth2", "oauthbearertoken", "httpbasic" or "httpdigest".
+	Type             string
+	Name             string
+	Description      string
+	SpecURI          string
+	DocumentationURI string
+	Primary          bool
 }
 
 func New(db database.Bridge, baseURL string) Bridge {
 	return Bridge{
-		BaseURL: baseURL,
-		DB:      db,
+		BaseURL:            baseURL,
+		DB:                 db,
+		PageSize:           DefaultPageSize,
+		MaxPageSize:        DefaultMaxPageSize,
+		BulkMaxOperations:  DefaultBulkMaxOperations,
+		BulkMaxPayloadSize: DefaultBulkMaxPayloadSize,
+	}
+}
+
+// PageLimits returns the PageSize and the MaxPageSize, or their defaults if they are not positive.
+func (b *Bridge) PageLimits() (pageSize int, maxPageSize int) {
+	return orDefault(b.PageSize, DefaultPageSize), orDefault(b.MaxPageSize, DefaultMaxPageSize)
+}
+
+// BulkLimits returns the BulkMaxOperations and the BulkMaxPayloadSize, or their defaults if they are
+// not positive.
+func (b *Bridge) BulkLimits() (maxOperations int, maxPayloadSize int) {
+	return orDefault(b.BulkMaxOperations, DefaultBulkMaxOperations), orDefault(b.BulkMaxPayloadSize, DefaultBulkMaxPayloadSize)
+}
+
+func orDefault(value int, defaultValue int) int {
+	if value <= 0 {
+		return defaultValue
+	}
+
+	return value
+}
+
+// RegisterExtension adds a custom extension schema to a resource type, "User" or "Group". The
+// attributes of the extension are validated against the schema in requests, stored in the Extensions
+// of users and groups, and rendered in responses. The extension is optional for the resources.
+func (b *Bridge) RegisterExtension(resourceType string, s schema.Schema) error {
+	if _, ok := b.ResourceType(resourceType); !ok {
+		return errors.Errorf("unknown resource type %q", resourceType)
+	}
+
+	err := s.Validate()
+	if err != nil {
+		return err
+	}
+
+	for _, registered := range b.Schemas() {
+		if strings.EqualFold(registered.ID, s.ID) {
+			return errors.Errorf("schema %q is already registered", s.ID)
+		}
+	}
+
+	b.extensions = append(b.extensions, extension{resourceType: resourceType, schema: s})
+	return nil
+}
+
+// CustomExtensions returns the extension schemas registered for a resource type, in the order of
+// their registration.
+func (b *Bridge) CustomExtensions(resourceType string) []schema.Schema {
+	var extensions []schema.Schema
+	for _, e := range b.extensions {
+		if e.resourceType == resourceType {
+			extensions = append(extensions, e.schema)
+		}
 	}
+
+	return extensions
+}
+
+// Schemas returns the schemas served by the /Schemas endpoint.
+func (b *Bridge) Schemas() []schema.Schema {
+	schemas := schema.Schemas()
+	for _, e := range b.extensions {
+		schemas = append(schemas, e.schema)
+	}
+
+	return schemas
+}
+
+// ResourceTypes returns the resource types served by the /ResourceTypes endpoint.
+func (b *Bridge) ResourceTypes() []schema.ResourceType {
+	resourceTypes := schema.ResourceTypes()
+	for i, resourceType := range resourceTypes {
+		extensions := append([]schema.SchemaExtension{}, resourceType.SchemaExtensions...)
+		for _, s := range b.CustomExtensions(resourceType.Name) {
+			extensions = append(extensions, schema.SchemaExtension{Schema: s.ID})
+		}
+
+		if len(extensions) > 0 {
+			resourceTypes[i].SchemaExtensions = extensions
+		}
+	}
+
+	return resourceTypes
+}
+
+// Schema finds a schema by its URI.
+func (b *Bridge) Schema(id string) (schema.Schema, bool) {
+	for _, s := range b.Schemas() {
+		if s.ID == id {
+			return s, true
+		}
+	}
+
+	return schema.Schema{}, false
+}
+
+// ResourceType finds a resource type by its name.
+func (b *Bridge) ResourceType(name string) (schema.ResourceType, bool) {
+	for _, resourceType := range b.ResourceTypes() {
+		if resourceType.Name == name {
+			return resourceType, true
+		}
+	}
+
+	return schema.ResourceType{}, false
+}
+
+// ResourceSchemas returns the core schema and the extension schemas of a resource type.
+func (b *Bridge) ResourceSchemas(name string) (schema.Schema, []schema.Schema) {
+	resourceType, _ := b.ResourceType(name)
+	core, _ := b.Schema(resourceType.Schema)
+
+	var extensions []schema.Schema
+	for _, extension := range resourceType.SchemaExtensions {
+		if s, ok := b.Schema(extension.Schema); ok {
+			extensions = append(extensions, s)
+		}
+	}
+
+	return core, extensions
 }
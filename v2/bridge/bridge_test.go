@@ -0,0 +1,60 @@
+package bridge
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/schema"
+)
+
+var skyscraperUser = schema.Schema{
+	ID:          "urn:suse:skyscraper:2.0:User",
+	Name:        "SkyscraperUser",
+	Description: "Skyscraper User",
+	Attributes: []schema.Attribute{
+		{Name: "costCenterRouting", Type: schema.String},
+	},
+}
+
+func TestRegisterExtension(t *testing.T) {
+	b := New(nil, "https://example.com")
+
+	assert.NoError(t, b.RegisterExtension("User", skyscraperUser))
+	assert.EqualError(t, b.RegisterExtension("User", skyscraperUser), `schema "urn:suse:skyscraper:2.0:User" is already registered`)
+	assert.EqualError(t, b.RegisterExtension("Device", skyscraperUser), `unknown resource type "Device"`)
+	assert.EqualError(t, b.RegisterExtension("Group", schema.Schema{ID: "urn:suse:skyscraper:2.0:Group"}), `schema "urn:suse:skyscraper:2.0:Group" has no attributes`)
+
+	assert.Equal(t, []schema.Schema{skyscraperUser}, b.CustomExtensions("User"))
+	assert.Empty(t, b.CustomExtensions("Group"))
+
+	s, ok := b.Schema(skyscraperUser.ID)
+	assert.True(t, ok)
+	assert.Equal(t, skyscraperUser, s)
+
+	core, extensions := b.ResourceSchemas("User")
+	assert.Equal(t, schema.User, core)
+	assert.Equal(t, []schema.Schema{schema.EnterpriseUser, skyscraperUser}, extensions)
+
+	// the resource types of the schema package are left unchanged
+	assert.Len(t, schema.UserResourceType.SchemaExtensions, 1)
+}
+
+func TestLimits(t *testing.T) {
+	b := New(nil, "https://example.com")
+	b.MaxPageSize = 200
+
+	pageSize, maxPageSize := b.PageLimits()
+	assert.Equal(t, DefaultPageSize, pageSize)
+	assert.Equal(t, 200, maxPageSize)
+
+	// the limits of a Bridge that is not created by New fall back to the defaults
+	b = Bridge{BulkMaxOperations: -1}
+
+	pageSize, maxPageSize = b.PageLimits()
+	assert.Equal(t, DefaultPageSize, pageSize)
+	assert.Equal(t, DefaultMaxPageSize, maxPageSize)
+
+	maxOperations, maxPayloadSize := b.BulkLimits()
+	assert.Equal(t, DefaultBulkMaxOperations, maxOperations)
+	assert.Equal(t, DefaultBulkMaxPayloadSize, maxPayloadSize)
+}
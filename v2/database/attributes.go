@@ -0,0 +1,152 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/filters"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/payloads"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/schema"
+)
+
+var _ filters.Resource = User{}
+var _ filters.PartialResource = Group{}
+
+// Attributes returns the user in its SCIM JSON representation, so it can be evaluated by filters.Evaluate.
+func (u User) Attributes() map[string]interface{} {
+	attributes := map[string]interface{}{
+		"id":       u.ID.String(),
+		"userName": u.Username,
+		"active":   u.Active,
+		"meta":     meta("User", u.CreatedAt, u.UpdatedAt),
+	}
+
+	for name, value := range map[string]sql.NullString{
+		"externalId":        u.ExternalID,
+		"displayName":       u.DisplayName,
+		"nickName":          u.NickName,
+		"profileUrl":        u.ProfileURL,
+		"title":             u.Title,
+		"userType":          u.UserType,
+		"preferredLanguage": u.PreferredLanguage,
+		"locale":            u.Locale,
+		"timezone":          u.Timezone,
+	} {
+		if value.Valid {
+			attributes[name] = value.String
+		}
+	}
+
+	if u.Name != nil {
+		name := map[string]interface{}{}
+		for key, value := range u.Name {
+			name[key] = value
+		}
+		attributes["name"] = name
+	}
+
+	for name, values := range map[string]interface{}{
+		"emails":           u.Emails,
+		"phoneNumbers":     u.PhoneNumbers,
+		"ims":              u.Ims,
+		"photos":           u.Photos,
+		"addresses":        u.Addresses,
+		"entitlements":     u.Entitlements,
+		"roles":            u.Roles,
+		"x509Certificates": u.X509Certificates,
+	} {
+		if elements := multiValuedAttributes(values); len(elements) > 0 {
+			attributes[name] = elements
+		}
+	}
+
+	if u.EnterpriseUser != nil {
+		attributes[schema.EnterpriseUserURN] = enterpriseUserAttributes(*u.EnterpriseUser)
+	}
+
+	u.Extensions.addTo(attributes)
+
+	return attributes
+}
+
+// Attributes returns the group in its SCIM JSON representation, so it can be evaluated by filters.Evaluate.
+// Members are not included, as they are loaded separately, see OmittedAttributes.
+func (g Group) Attributes() map[string]interface{} {
+	attributes := map[string]interface{}{
+		"id":          g.ID.String(),
+		"displayName": g.DisplayName,
+		"meta":        meta("Group", g.CreatedAt, g.UpdatedAt),
+	}
+
+	if g.ExternalID.Valid {
+		attributes["externalId"] = g.ExternalID.String
+	}
+
+	g.Extensions.addTo(attributes)
+
+	return attributes
+}
+
+// OmittedAttributes lists the members, which filters.Evaluate can't filter groups by.
+func (g Group) OmittedAttributes() []string {
+	return []string{"members"}
+}
+
+// addTo adds the extensions to the attributes of a resource, nested below their schema URI.
+func (e Extensions) addTo(attributes map[string]interface{}) {
+	for id, values := range e {
+		attributes[id] = values
+	}
+}
+
+// multiValuedAttributes converts a slice of payload structs to their JSON representation.
+func multiValuedAttributes(values interface{}) []interface{} {
+	data, err := json.Marshal(values)
+	if err != nil {
+		return nil
+	}
+
+	var elements []interface{}
+	if err := json.Unmarshal(data, &elements); err != nil {
+		return nil
+	}
+
+	return elements
+}
+
+func enterpriseUserAttributes(e payloads.EnterpriseUser) map[string]interface{} {
+	attributes := map[string]interface{}{}
+	for name, value := range map[string]string{
+		"employeeNumber": e.EmployeeNumber,
+		"costCenter":     e.CostCenter,
+		"organization":   e.Organization,
+		"division":       e.Division,
+		"department":     e.Department,
+	} {
+		if value != "" {
+			attributes[name] = value
+		}
+	}
+
+	if e.Manager != nil {
+		manager := map[string]interface{}{
+			"value":       e.Manager.Value,
+			"displayName": e.Manager.DisplayName,
+		}
+		if e.Manager.Ref != "" {
+			manager["$ref"] = e.Manager.Ref
+		}
+		attributes["manager"] = manager
+	}
+
+	return attributes
+}
+
+func meta(resourceType string, createdAt, updatedAt time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"resourceType": resourceType,
+		"created":      createdAt.Format(time.RFC3339),
+		"lastModified": updatedAt.Format(time.RFC3339),
+	}
+}
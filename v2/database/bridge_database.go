@@ -2,26 +2,41 @@ package database
 
 import (
 	"context"
-	"errors"
 
 	"github.com/google/uuid"
-	"github.com/suse-skyscraper/openfga-scim-bridge/v2/payloads"
 )
 
-var ErrConflict = errors.New("duplicate key value violates unique constraint")
-
+// Bridge is the storage of the users and groups. Its methods report failures with the errors of this
+// package, e.g. ErrNotFound or *ConflictError, which are translated into the matching SCIM errors.
+// Other errors are internal server errors.
 type Bridge interface {
+	// FindUser returns the user. Within a Transaction, the user must not be modified by others until
+	// the transaction ends, so that changes can be based on the returned user.
 	FindUser(ctx context.Context, userID uuid.UUID) (User, error)
 	CreateUser(ctx context.Context, arg UserParams) (User, error)
 	GetUsers(ctx context.Context, arg GetUsersParams) (int64, []User, error)
 	DeleteUser(ctx context.Context, userID uuid.UUID) error
 	UpdateUser(ctx context.Context, userID uuid.UUID, arg UserParams) (User, error)
-	SetUserActive(ctx context.Context, userID uuid.UUID, active bool) error
+	// GetUserGroups returns the groups the user is a member of. Groups the user belongs to through
+	// nested groups are only returned if indirect is set.
+	GetUserGroups(ctx context.Context, userID uuid.UUID, indirect bool) ([]UserGroup, error)
+	// CheckUserVersion returns ErrPreconditionFailed if the user is no longer at the version. Within a
+	// Transaction, the user must not be modified by others until the transaction ends, so that its
+	// changes are based on the checked version.
+	CheckUserVersion(ctx context.Context, userID uuid.UUID, version string) error
 
+	// FindGroup is the equivalent of FindUser for groups.
 	FindGroup(ctx context.Context, groupID uuid.UUID) (Group, error)
-	CreateGroup(ctx context.Context, displayName string) (Group, error)
-	GetGroups(ctx context.Context, limit int32, offset int32) (int64, []Group, error)
+	CreateGroup(ctx context.Context, arg GroupParams) (Group, error)
+	GetGroups(ctx context.Context, arg GetGroupsParams) (int64, []Group, error)
 	GetGroupMembership(ctx context.Context, groupID uuid.UUID) ([]GroupMembership, error)
 	DeleteGroup(ctx context.Context, groupID uuid.UUID) error
-	PatchGroup(ctx context.Context, groupID uuid.UUID, operations []payloads.GroupPatchOperation) error
+	PatchGroup(ctx context.Context, groupID uuid.UUID, patch GroupPatch) error
+	ReplaceGroup(ctx context.Context, groupID uuid.UUID, arg GroupParams) (Group, error)
+	// CheckGroupVersion is the equivalent of CheckUserVersion for groups.
+	CheckGroupVersion(ctx context.Context, groupID uuid.UUID, version string) error
+
+	// Transaction runs fn with a Bridge whose changes are committed together once fn returns without
+	// an error, and discarded otherwise.
+	Transaction(ctx context.Context, fn func(tx Bridge) error) error
 }
@@ -0,0 +1,58 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNotFound is returned when a resource does not exist.
+var ErrNotFound = errors.New("resource not found")
+
+// ErrConflict is returned when a change would make a unique attribute of a resource collide with
+// another resource. Backends that know the attribute return a *ConflictError instead.
+var ErrConflict = errors.New("duplicate key value violates unique constraint")
+
+// ErrTooMany is returned when a request would return or change more resources than the backend is
+// willing to process.
+var ErrTooMany = errors.New("too many resources")
+
+// ErrPreconditionFailed is returned when a resource is no longer at the version a change is based on.
+var ErrPreconditionFailed = errors.New("the resource has been modified")
+
+// ErrMembershipCycle is returned when a group would become a member of itself, directly or through
+// its nested groups.
+var ErrMembershipCycle = &InvalidValueError{Attribute: "members", Detail: "a group cannot be a member of itself"}
+
+// ConflictError is returned when the value of the unique Attribute, e.g. "userName", is already used
+// by another resource. It matches ErrConflict.
+type ConflictError struct {
+	Attribute string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("the value of %q is already in use", e.Attribute)
+}
+
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// MutabilityError is returned when a change would modify an Attribute that cannot be modified.
+type MutabilityError struct {
+	Attribute string
+}
+
+func (e *MutabilityError) Error() string {
+	return fmt.Sprintf("attribute %q cannot be modified", e.Attribute)
+}
+
+// InvalidValueError is returned when the value of an Attribute is rejected by the backend. Detail
+// explains why.
+type InvalidValueError struct {
+	Attribute string
+	Detail    string
+}
+
+func (e *InvalidValueError) Error() string {
+	return fmt.Sprintf("invalid value of %q: %s", e.Attribute, e.Detail)
+}
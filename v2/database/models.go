@@ -10,43 +10,157 @@ import (
 )
 
 type User struct {
-	ID          uuid.UUID
-	Username    string
-	ExternalID  sql.NullString
-	Name        map[string]string
-	DisplayName sql.NullString
-	Locale      sql.NullString
-	Active      bool
-	Emails      []payloads.UserEmail
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
+	ID                uuid.UUID
+	Username          string
+	ExternalID        sql.NullString
+	Name              map[string]string
+	DisplayName       sql.NullString
+	NickName          sql.NullString
+	ProfileURL        sql.NullString
+	Title             sql.NullString
+	UserType          sql.NullString
+	PreferredLanguage sql.NullString
+	Locale            sql.NullString
+	Timezone          sql.NullString
+	Active            bool
+	Emails            []payloads.UserEmail
+	PhoneNumbers      []payloads.MultiValuedAttribute
+	Ims               []payloads.MultiValuedAttribute
+	Photos            []payloads.MultiValuedAttribute
+	Addresses         []payloads.UserAddress
+	Entitlements      []payloads.MultiValuedAttribute
+	Roles             []payloads.MultiValuedAttribute
+	X509Certificates  []payloads.MultiValuedAttribute
+	// EnterpriseUser is nil if the user has no enterprise user extension.
+	EnterpriseUser *payloads.EnterpriseUser
+	Extensions     Extensions
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	// Version identifies the revision of the user, it must change whenever the user is modified.
+	// It is the opaque value of the weak ETag of the user. An empty Version means that the user is
+	// not versioned: it has no ETag and If-Match headers are ignored.
+	Version string
 }
 
 type Group struct {
 	ID          uuid.UUID
 	DisplayName string
+	ExternalID  sql.NullString
+	Extensions  Extensions
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
+	// Version identifies the revision of the group, it must change whenever the group or its members
+	// are modified. It is the opaque value of the weak ETag of the group, or empty if the group is
+	// not versioned.
+	Version string
+}
+
+// Extensions holds the attributes of the custom extensions registered with
+// bridge.Bridge.RegisterExtension, keyed by the URI of their schema. The values are decoded by
+// encoding/json and validated against the schemas; the database stores them as they are.
+type Extensions map[string]map[string]interface{}
+
+// The types of group members.
+const (
+	UserMember  = "User"
+	GroupMember = "Group"
+)
+
+// Member references a member of a group: a user, or a group whose members are nested in the group.
+type Member struct {
+	ID   uuid.UUID
+	Type string
+}
+
+// GroupParams holds the attributes of a group. Members lists every member of the group.
+type GroupParams struct {
+	DisplayName string
+	ExternalID  string
+	Extensions  Extensions
+	Members     []Member
+}
+
+// GroupPatch holds the changes of a PATCH request to a group. DisplayName, ExternalID and Extensions
+// are the values after the patch, members are the members added to and removed from the group.
+type GroupPatch struct {
+	DisplayName    string
+	ExternalID     string
+	Extensions     Extensions
+	AddedMembers   []Member
+	RemovedMembers []Member
 }
 
 type GroupMembership struct {
 	GroupID  uuid.UUID
-	UserID   uuid.UUID
-	Username sql.NullString
+	MemberID uuid.UUID
+	// Type is UserMember or GroupMember.
+	Type string
+	// Display is the userName of a user, or the displayName of a group.
+	Display sql.NullString
 }
 
-type UserParams struct {
-	Username    string
-	Name        map[string]string
+// The types of the membership of a user in a group.
+const (
+	DirectMembership   = "direct"
+	IndirectMembership = "indirect"
+)
+
+// UserGroup is a group a user belongs to.
+type UserGroup struct {
+	GroupID     uuid.UUID
 	DisplayName string
-	Emails      []payloads.UserEmail
-	Active      bool
-	Locale      string
-	ExternalID  string
+	// Type is DirectMembership, or IndirectMembership for groups the user belongs to through nested
+	// groups.
+	Type string
+}
+
+type UserParams struct {
+	Username          string
+	Name              map[string]string
+	DisplayName       string
+	NickName          string
+	ProfileURL        string
+	Title             string
+	UserType          string
+	PreferredLanguage string
+	Timezone          string
+	Emails            []payloads.UserEmail
+	PhoneNumbers      []payloads.MultiValuedAttribute
+	Ims               []payloads.MultiValuedAttribute
+	Photos            []payloads.MultiValuedAttribute
+	Addresses         []payloads.UserAddress
+	Entitlements      []payloads.MultiValuedAttribute
+	Roles             []payloads.MultiValuedAttribute
+	X509Certificates  []payloads.MultiValuedAttribute
+	Active            bool
+	Locale            string
+	ExternalID        string
+	// EnterpriseUser is nil if the user has no enterprise user extension.
+	EnterpriseUser *payloads.EnterpriseUser
+	Extensions     Extensions
 }
 
+type SortOrder int
+
+const (
+	Ascending SortOrder = iota
+	Descending
+)
+
 type GetUsersParams struct {
-	Filters []filters.Filter
-	Offset  int32
-	Limit   int32
+	Filter filters.Expression
+	// SortBy is an attribute path such as "name.familyName", or empty if the order doesn't matter.
+	SortBy    string
+	SortOrder SortOrder
+	Offset    int32
+	Limit     int32
+}
+
+type GetGroupsParams struct {
+	Filter filters.Expression
+	// SortBy is an attribute path such as "displayName", or empty if the order doesn't matter.
+	SortBy    string
+	SortOrder SortOrder
+	Offset    int32
+	Limit     int32
 }
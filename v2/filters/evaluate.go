@@ -0,0 +1,368 @@
+package filters
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/schema"
+)
+
+// Resource is a SCIM resource that can be tested against a filter. Attributes returns the resource in
+// its JSON representation: maps for complex attributes, slices for multi-valued attributes and
+// strings, bools or float64s for simple values. Extension attributes are nested under their schema URI.
+type Resource interface {
+	Attributes() map[string]interface{}
+}
+
+// PartialResource is a Resource whose Attributes leave out some attributes, e.g. because they are
+// loaded separately. Filters referencing them fail with ErrUnknownAttribute, rather than not matching.
+type PartialResource interface {
+	Resource
+	OmittedAttributes() []string
+}
+
+type attributeMap map[string]interface{}
+
+func (m attributeMap) Attributes() map[string]interface{} {
+	return m
+}
+
+// coreSchemas are the schema URIs whose attributes live at the top level of a resource.
+var coreSchemas = []string{
+	schema.UserURN,
+	schema.GroupURN,
+}
+
+// Evaluate reports whether the resource matches the filter expression. A nil expression matches every
+// resource.
+func Evaluate(expression Expression, resource Resource) (bool, error) {
+	if expression == nil {
+		return true, nil
+	}
+
+	if partial, ok := resource.(PartialResource); ok {
+		err := checkOmitted(expression, partial.OmittedAttributes())
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return evaluate(expression, resource.Attributes(), "")
+}
+
+// checkOmitted returns ErrUnknownAttribute if the expression references one of the omitted
+// attributes of a resource.
+func checkOmitted(expression Expression, omitted []string) error {
+	var path AttributePath
+	switch e := expression.(type) {
+	case LogicalExpression:
+		err := checkOmitted(e.Left, omitted)
+		if err != nil {
+			return err
+		}
+
+		return checkOmitted(e.Right, omitted)
+	case NotExpression:
+		return checkOmitted(e.Expression, omitted)
+	case ValuePathExpression:
+		path = e.Path
+	case AttributeExpression:
+		path = e.Path
+	default:
+		return nil
+	}
+
+	if path.URI != "" && !isCoreSchema(path.URI) {
+		return nil
+	}
+
+	for _, name := range omitted {
+		if strings.EqualFold(path.Name, name) {
+			return errors.Wrapf(ErrUnknownAttribute, "%s", path.String())
+		}
+	}
+
+	return nil
+}
+
+func evaluate(expression Expression, attributes map[string]interface{}, parent string) (bool, error) {
+	switch e := expression.(type) {
+	case LogicalExpression:
+		left, err := evaluate(e.Left, attributes, parent)
+		if err != nil {
+			return false, err
+		}
+
+		if e.Operator == And && !left {
+			return false, nil
+		} else if e.Operator == Or && left {
+			return true, nil
+		}
+
+		return evaluate(e.Right, attributes, parent)
+	case NotExpression:
+		result, err := evaluate(e.Expression, attributes, parent)
+		return !result, err
+	case ValuePathExpression:
+		for _, value := range flatten(lookup(attributes, e.Path)) {
+			element, ok := value.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			match, err := evaluate(e.Filter, element, qualify(parent, e.Path.Name))
+			if err != nil {
+				return false, err
+			} else if match {
+				return true, nil
+			}
+		}
+
+		return false, nil
+	case AttributeExpression:
+		return evaluateAttribute(e, attributes, parent)
+	default:
+		return false, errors.Errorf("unsupported expression %T", expression)
+	}
+}
+
+func evaluateAttribute(e AttributeExpression, attributes map[string]interface{}, parent string) (bool, error) {
+	values := flatten(lookup(attributes, e.Path))
+
+	// a multi-valued complex attribute without a sub-attribute is compared by its "value" sub-attribute
+	path := qualify(parent, e.Path.String())
+	for i, value := range values {
+		if element, ok := value.(map[string]interface{}); ok {
+			values[i] = lookupName(element, "value")
+			if e.Path.SubAttribute == "" {
+				path = qualify(parent, e.Path.Name+".value")
+			}
+		}
+	}
+
+	if e.Operator == Pr || (e.Value == nil && (e.Operator == Eq || e.Operator == Ne)) {
+		present := false
+		for _, value := range values {
+			present = present || isPresent(value)
+		}
+
+		if e.Operator == Ne {
+			return present, nil
+		}
+
+		return present == (e.Operator == Pr), nil
+	}
+
+	if e.Operator == Ne {
+		match, err := compareAny(values, Eq, e.Value, schema.IsCaseExact(path))
+		return !match, err
+	}
+
+	return compareAny(values, e.Operator, e.Value, schema.IsCaseExact(path))
+}
+
+func compareAny(values []interface{}, operator CompareOperator, expected interface{}, caseExact bool) (bool, error) {
+	for _, value := range values {
+		match, err := compare(value, operator, expected, caseExact)
+		if err != nil {
+			return false, err
+		} else if match {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func compare(actual interface{}, operator CompareOperator, expected interface{}, caseExact bool) (bool, error) {
+	switch expectedValue := expected.(type) {
+	case string:
+		actualValue, ok := actual.(string)
+		if !ok {
+			return false, nil
+		}
+
+		return compareStrings(actualValue, operator, expectedValue, caseExact), nil
+	case bool:
+		actualValue, ok := actual.(bool)
+		if operator != Eq {
+			return false, errors.Errorf("operator %q is not supported for boolean values", operator)
+		}
+
+		return ok && actualValue == expectedValue, nil
+	case float64:
+		actualValue, ok := toFloat(actual)
+		if !ok {
+			return false, nil
+		}
+
+		switch operator {
+		case Eq:
+			return actualValue == expectedValue, nil
+		case Gt:
+			return actualValue > expectedValue, nil
+		case Ge:
+			return actualValue >= expectedValue, nil
+		case Lt:
+			return actualValue < expectedValue, nil
+		case Le:
+			return actualValue <= expectedValue, nil
+		default:
+			return false, errors.Errorf("operator %q is not supported for numeric values", operator)
+		}
+	default:
+		return false, errors.Errorf("unsupported value %v", expected)
+	}
+}
+
+func compareStrings(actual string, operator CompareOperator, expected string, caseExact bool) bool {
+	// date times are ordered chronologically rather than lexically
+	actualTime, actualErr := time.Parse(time.RFC3339, actual)
+	expectedTime, expectedErr := time.Parse(time.RFC3339, expected)
+	if actualErr == nil && expectedErr == nil {
+		switch operator {
+		case Eq:
+			return actualTime.Equal(expectedTime)
+		case Gt:
+			return actualTime.After(expectedTime)
+		case Ge:
+			return !actualTime.Before(expectedTime)
+		case Lt:
+			return actualTime.Before(expectedTime)
+		case Le:
+			return !actualTime.After(expectedTime)
+		}
+	}
+
+	if !caseExact {
+		actual = strings.ToLower(actual)
+		expected = strings.ToLower(expected)
+	}
+
+	switch operator {
+	case Eq:
+		return actual == expected
+	case Co:
+		return strings.Contains(actual, expected)
+	case Sw:
+		return strings.HasPrefix(actual, expected)
+	case Ew:
+		return strings.HasSuffix(actual, expected)
+	case Gt:
+		return actual > expected
+	case Ge:
+		return actual >= expected
+	case Lt:
+		return actual < expected
+	case Le:
+		return actual <= expected
+	default:
+		return false
+	}
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+func isPresent(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case string:
+		return v != ""
+	case []interface{}:
+		return len(v) > 0
+	case map[string]interface{}:
+		return len(v) > 0
+	default:
+		return true
+	}
+}
+
+// lookup resolves the path against the attributes. Attributes of the core schemas are found at the
+// top level, extension attributes below their schema URI.
+func lookup(attributes map[string]interface{}, path AttributePath) interface{} {
+	if path.URI != "" && !isCoreSchema(path.URI) {
+		extension, ok := lookupName(attributes, path.URI).(map[string]interface{})
+		if !ok {
+			return nil
+		}
+
+		attributes = extension
+	}
+
+	value := lookupName(attributes, path.Name)
+	if path.SubAttribute == "" {
+		return value
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return lookupName(v, path.SubAttribute)
+	case []interface{}:
+		var values []interface{}
+		for _, element := range v {
+			if m, ok := element.(map[string]interface{}); ok {
+				values = append(values, lookupName(m, path.SubAttribute))
+			}
+		}
+
+		return values
+	default:
+		return nil
+	}
+}
+
+// lookupName finds an attribute by name, ignoring case.
+func lookupName(attributes map[string]interface{}, name string) interface{} {
+	if value, ok := attributes[name]; ok {
+		return value
+	}
+
+	for key, value := range attributes {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+
+	return nil
+}
+
+func flatten(value interface{}) []interface{} {
+	if values, ok := value.([]interface{}); ok {
+		return append([]interface{}{}, values...)
+	}
+
+	return []interface{}{value}
+}
+
+func qualify(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+
+	return parent + "." + name
+}
+
+func isCoreSchema(uri string) bool {
+	for _, schema := range coreSchemas {
+		if strings.EqualFold(schema, uri) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,114 @@
+package filters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluate(t *testing.T) {
+	user := attributeMap{
+		"id":         "2819c223-7f76-453a-919d-413861904646",
+		"externalId": "Bjensen",
+		"userName":   "bjensen@example.com",
+		"active":     true,
+		"name": map[string]interface{}{
+			"givenName":  "Barbara",
+			"familyName": "Jensen",
+		},
+		"emails": []interface{}{
+			map[string]interface{}{"value": "bjensen@example.com", "type": "work", "primary": true},
+			map[string]interface{}{"value": "babs@jensen.org", "type": "home", "primary": false},
+		},
+		"meta": map[string]interface{}{
+			"created":      "2023-01-23T04:56:22Z",
+			"lastModified": "2023-05-13T04:42:34Z",
+		},
+		"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": map[string]interface{}{
+			"employeeNumber": "701984",
+		},
+	}
+
+	tests := []struct {
+		filter        string
+		want          bool
+		errorExpected bool
+	}{
+		{filter: "", want: true},
+		{filter: `userName eq "BJENSEN@example.com"`, want: true},
+		{filter: `username eq "bjensen@example.com"`, want: true},
+		{filter: `userName ne "bjensen@example.com"`, want: false},
+		{filter: `externalId eq "bjensen"`, want: false},
+		{filter: `externalId eq "Bjensen"`, want: true},
+		{filter: `name.familyName co "ENS"`, want: true},
+		{filter: `name.givenName sw "Bar" and name.familyName ew "sen"`, want: true},
+		{filter: `urn:ietf:params:scim:schemas:core:2.0:User:name.givenName eq "Barbara"`, want: true},
+		{filter: `emails co "jensen.org"`, want: true},
+		{filter: `emails.value eq "babs@jensen.org"`, want: true},
+		{filter: `emails[type eq "work" and value co "@example.com"]`, want: true},
+		{filter: `emails[type eq "home" and value co "@example.com"]`, want: false},
+		{filter: `emails[primary eq true].value`, errorExpected: true},
+		{filter: `title pr`, want: false},
+		{filter: `not (title pr)`, want: true},
+		{filter: `title pr or active eq true`, want: true},
+		{filter: `active eq false`, want: false},
+		{filter: `active gt false`, errorExpected: true},
+		{filter: `meta.lastModified gt "2023-05-13T00:00:00+02:00"`, want: true},
+		{filter: `meta.created lt "2023-01-01T00:00:00Z"`, want: false},
+		{filter: `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber eq "701984"`, want: true},
+		{filter: `nickName eq null`, want: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.filter, func(t *testing.T) {
+			expression, err := ParseFilter(tc.filter)
+			if tc.errorExpected && err != nil {
+				return
+			}
+			assert.Nil(t, err)
+
+			got, err := Evaluate(expression, user)
+			if tc.errorExpected {
+				assert.NotNil(t, err)
+			} else {
+				assert.Nil(t, err)
+			}
+
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+// group leaves out its members, like database.Group.
+type group struct {
+	attributeMap
+}
+
+func (g group) OmittedAttributes() []string {
+	return []string{"members"}
+}
+
+func TestEvaluateOmittedAttributes(t *testing.T) {
+	resource := group{attributeMap{"displayName": "Tour Guides"}}
+
+	for _, filter := range []string{
+		`members.value eq "2819c223-7f76-453a-919d-413861904646"`,
+		`displayName eq "Tour Guides" or members pr`,
+		`not (urn:ietf:params:scim:schemas:core:2.0:Group:members[type eq "User"])`,
+	} {
+		t.Run(filter, func(t *testing.T) {
+			expression, err := ParseFilter(filter)
+			assert.Nil(t, err)
+
+			_, err = Evaluate(expression, resource)
+			assert.ErrorIs(t, err, ErrUnknownAttribute)
+		})
+	}
+
+	expression, err := ParseFilter(`displayName eq "Tour Guides"`)
+	assert.Nil(t, err)
+
+	got, err := Evaluate(expression, resource)
+	assert.Nil(t, err)
+	assert.True(t, got)
+}
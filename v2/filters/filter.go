@@ -1,75 +1,153 @@
 package filters
 
 import (
-	"regexp"
 	"strings"
-
-	"github.com/pkg/errors"
 )
 
-type filterOperator int
+// CompareOperator is the operator of an attribute expression, e.g. the "eq" in `userName eq "bjensen"`.
+type CompareOperator int
 
 const (
-	Eq filterOperator = iota
-	InvalidOperator
+	Eq CompareOperator = iota
+	Ne
+	Co
+	Sw
+	Ew
+	Gt
+	Ge
+	Lt
+	Le
+	Pr
 )
 
-type filterField int
+var compareOperators = map[string]CompareOperator{
+	"eq": Eq,
+	"ne": Ne,
+	"co": Co,
+	"sw": Sw,
+	"ew": Ew,
+	"gt": Gt,
+	"ge": Ge,
+	"lt": Lt,
+	"le": Le,
+	"pr": Pr,
+}
+
+func (o CompareOperator) String() string {
+	for name, operator := range compareOperators {
+		if operator == o {
+			return name
+		}
+	}
+
+	return "invalid"
+}
+
+// LogicalOperator joins two filter expressions.
+type LogicalOperator int
 
 const (
-	Username filterField = iota
-	InvalidField
+	And LogicalOperator = iota
+	Or
 )
 
-type Filter struct {
-	FilterField    filterField
-	FilterOperator filterOperator
-	FilterValue    string
+func (o LogicalOperator) String() string {
+	if o == Or {
+		return "or"
+	}
+
+	return "and"
 }
 
-func ParseFilter(filterString string) ([]Filter, error) {
-	if filterString == "" {
-		return []Filter{}, nil
-	}
+// Expression is a node of a parsed filter. It is one of AttributeExpression, LogicalExpression,
+// NotExpression or ValuePathExpression.
+type Expression interface {
+	expression()
+}
 
-	r := regexp.MustCompile("^(?P<field>\\S+)\\s*(?P<operator>\\w+)\\s*\"(?P<value>\\S+)\"$")
-	if !r.MatchString(filterString) {
-		return []Filter{}, errors.New("invalid filter")
+// AttributePath references an attribute, optionally qualified by its schema URI and narrowed to a
+// sub-attribute, e.g. "urn:ietf:params:scim:schemas:core:2.0:User:name.givenName".
+type AttributePath struct {
+	URI          string
+	Name         string
+	SubAttribute string
+}
+
+// String returns the path without its schema URI, e.g. "name.givenName".
+func (p AttributePath) String() string {
+	if p.SubAttribute == "" {
+		return p.Name
 	}
 
-	match := r.FindStringSubmatch(filterString)
-	field, err := parseField(match[1])
-	if err != nil {
-		return []Filter{}, err
+	return p.Name + "." + p.SubAttribute
+}
+
+// Matches reports whether the path references the given attribute. The comparison ignores case, as
+// attribute names in SCIM are case-insensitive.
+func (p AttributePath) Matches(path string) bool {
+	return strings.EqualFold(p.String(), path)
+}
+
+// Path is the target of a PATCH operation. Its filter selects elements of a multi-valued attribute,
+// e.g. emails[type eq "work"].value, and is nil for plain attribute paths.
+type Path struct {
+	AttributePath
+	Filter Expression
+}
+
+// AttributeExpression compares an attribute to a value. Value is a string, bool, float64 or nil, and is
+// always nil for the Pr operator.
+type AttributeExpression struct {
+	Path     AttributePath
+	Operator CompareOperator
+	Value    interface{}
+}
+
+// LogicalExpression combines two expressions with "and" or "or".
+type LogicalExpression struct {
+	Operator LogicalOperator
+	Left     Expression
+	Right    Expression
+}
+
+// NotExpression negates the enclosed expression.
+type NotExpression struct {
+	Expression Expression
+}
+
+// ValuePathExpression applies Filter to the values of a multi-valued complex attribute, e.g.
+// `emails[type eq "work" and value co "@example.com"]`. Attribute paths within Filter are relative
+// to the values of Path.
+type ValuePathExpression struct {
+	Path   AttributePath
+	Filter Expression
+}
+
+func (AttributeExpression) expression() {}
+func (LogicalExpression) expression()   {}
+func (NotExpression) expression()       {}
+func (ValuePathExpression) expression() {}
+
+// ParseFilter parses a filter as described in RFC 7644 section 3.4.2.2. An empty filter string
+// returns a nil expression.
+func ParseFilter(filterString string) (Expression, error) {
+	if strings.TrimSpace(filterString) == "" {
+		return nil, nil
 	}
-	operator, err := parseOperator(match[2])
+
+	p, err := newParser(filterString)
 	if err != nil {
-		return []Filter{}, err
+		return nil, err
 	}
 
-	filter := Filter{
-		FilterField:    field,
-		FilterOperator: operator,
-		FilterValue:    match[3],
+	expression, err := p.parseFilter(false)
+	if err != nil {
+		return nil, err
 	}
 
-	return []Filter{filter}, nil
-}
-
-func parseField(field string) (filterField, error) {
-	switch field {
-	case "userName":
-		return Username, nil
-	default:
-		return InvalidField, errors.New("invalid field")
+	if !p.done() {
+		return nil, p.unexpected()
 	}
-}
 
-func parseOperator(operator string) (filterOperator, error) {
-	switch strings.ToLower(operator) {
-	case "eq":
-		return Eq, nil
-	default:
-		return InvalidOperator, errors.New("invalid operator")
-	}
+	return expression, nil
 }
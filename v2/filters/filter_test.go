@@ -7,71 +7,161 @@ import (
 )
 
 func TestParseFilter(t *testing.T) {
+	userName := AttributePath{Name: "userName"}
+
 	tests := []struct {
 		name          string
 		args          string
-		want          []Filter
+		want          Expression
 		errorExpected bool
 	}{
 		{
 			name:          "no filter",
 			args:          "",
-			want:          []Filter{},
+			want:          nil,
 			errorExpected: false,
 		},
 		{
-			name: "lots of spaces",
-			args: "userName              eq            \"test\"",
-			want: []Filter{
-				{
-					FilterField:    Username,
-					FilterOperator: Eq,
-					FilterValue:    "test",
-				},
-			},
+			name:          "lots of spaces",
+			args:          "userName              eq            \"test\"",
+			want:          AttributeExpression{Path: userName, Operator: Eq, Value: "test"},
 			errorExpected: false,
 		},
 		{
 			name:          "invalid operator",
 			args:          "userName foo \"test\"",
-			want:          []Filter{},
+			want:          nil,
 			errorExpected: true,
 		},
 		{
-			name:          "invalid field",
-			args:          "user eq \"test\"",
-			want:          []Filter{},
+			name:          "invalid attribute",
+			args:          "1user eq \"test\"",
+			want:          nil,
 			errorExpected: true,
 		},
 		{
 			name:          "malformed value",
 			args:          "userName eq \"test",
-			want:          []Filter{},
+			want:          nil,
+			errorExpected: true,
+		},
+		{
+			name:          "missing value",
+			args:          "userName eq",
+			want:          nil,
+			errorExpected: true,
+		},
+		{
+			name:          "trailing tokens",
+			args:          "userName eq \"test\" foo",
+			want:          nil,
 			errorExpected: true,
 		},
 		{
-			name: "valid filter",
-			args: "userName eq \"test\"",
-			want: []Filter{
-				{
-					FilterField:    Username,
-					FilterOperator: Eq,
-					FilterValue:    "test",
+			name:          "unbalanced parentheses",
+			args:          "(userName eq \"test\"",
+			want:          nil,
+			errorExpected: true,
+		},
+		{
+			name:          "nested value path",
+			args:          "emails[type[value eq \"x\"]]",
+			want:          nil,
+			errorExpected: true,
+		},
+		{
+			name:          "valid filter",
+			args:          "userName eq \"test\"",
+			want:          AttributeExpression{Path: userName, Operator: Eq, Value: "test"},
+			errorExpected: false,
+		},
+		{
+			name:          "valid filter with uppercase operator",
+			args:          "userName EQ \"test\"",
+			want:          AttributeExpression{Path: userName, Operator: Eq, Value: "test"},
+			errorExpected: false,
+		},
+		{
+			name:          "escaped string",
+			args:          `displayName eq "say \"hi\""`,
+			want:          AttributeExpression{Path: AttributePath{Name: "displayName"}, Operator: Eq, Value: `say "hi"`},
+			errorExpected: false,
+		},
+		{
+			name: "present",
+			args: "title pr",
+			want: AttributeExpression{Path: AttributePath{Name: "title"}, Operator: Pr},
+		},
+		{
+			name: "boolean, null and number values",
+			args: "active eq true or nickName eq null or x ge 4.5",
+			want: LogicalExpression{
+				Operator: Or,
+				Left: LogicalExpression{
+					Operator: Or,
+					Left:     AttributeExpression{Path: AttributePath{Name: "active"}, Operator: Eq, Value: true},
+					Right:    AttributeExpression{Path: AttributePath{Name: "nickName"}, Operator: Eq, Value: nil},
 				},
+				Right: AttributeExpression{Path: AttributePath{Name: "x"}, Operator: Ge, Value: 4.5},
 			},
-			errorExpected: false,
 		},
 		{
-			name: "valid filter with uppercase operator",
-			args: "userName EQ \"test\"",
-			want: []Filter{
-				{
-					FilterField:    Username,
-					FilterOperator: Eq,
-					FilterValue:    "test",
+			name: "and binds stronger than or",
+			args: "title pr or userType eq \"Employee\" and active eq false",
+			want: LogicalExpression{
+				Operator: Or,
+				Left:     AttributeExpression{Path: AttributePath{Name: "title"}, Operator: Pr},
+				Right: LogicalExpression{
+					Operator: And,
+					Left:     AttributeExpression{Path: AttributePath{Name: "userType"}, Operator: Eq, Value: "Employee"},
+					Right:    AttributeExpression{Path: AttributePath{Name: "active"}, Operator: Eq, Value: false},
+				},
+			},
+		},
+		{
+			name: "grouping and not",
+			args: "not (title pr OR userType eq \"Intern\") and (active eq true)",
+			want: LogicalExpression{
+				Operator: And,
+				Left: NotExpression{Expression: LogicalExpression{
+					Operator: Or,
+					Left:     AttributeExpression{Path: AttributePath{Name: "title"}, Operator: Pr},
+					Right:    AttributeExpression{Path: AttributePath{Name: "userType"}, Operator: Eq, Value: "Intern"},
+				}},
+				Right: AttributeExpression{Path: AttributePath{Name: "active"}, Operator: Eq, Value: true},
+			},
+		},
+		{
+			name: "sub-attribute and schema URI",
+			args: "urn:ietf:params:scim:schemas:core:2.0:User:name.familyName co \"O'Malley\"",
+			want: AttributeExpression{
+				Path: AttributePath{
+					URI:          "urn:ietf:params:scim:schemas:core:2.0:User",
+					Name:         "name",
+					SubAttribute: "familyName",
+				},
+				Operator: Co,
+				Value:    "O'Malley",
+			},
+		},
+		{
+			name: "value path",
+			args: "emails[type eq \"work\" and value co \"@example.com\"] or ims[type eq \"xmpp\"]",
+			want: LogicalExpression{
+				Operator: Or,
+				Left: ValuePathExpression{
+					Path: AttributePath{Name: "emails"},
+					Filter: LogicalExpression{
+						Operator: And,
+						Left:     AttributeExpression{Path: AttributePath{Name: "type"}, Operator: Eq, Value: "work"},
+						Right:    AttributeExpression{Path: AttributePath{Name: "value"}, Operator: Co, Value: "@example.com"},
+					},
+				},
+				Right: ValuePathExpression{
+					Path:   AttributePath{Name: "ims"},
+					Filter: AttributeExpression{Path: AttributePath{Name: "type"}, Operator: Eq, Value: "xmpp"},
 				},
 			},
-			errorExpected: false,
 		},
 	}
 	for _, tc := range tests {
@@ -88,3 +178,135 @@ func TestParseFilter(t *testing.T) {
 		})
 	}
 }
+
+func TestParseFilterErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args string
+		want string
+	}{
+		{
+			name: "missing operator",
+			args: "userName",
+			want: "invalid operator: unexpected end of filter",
+		},
+		{
+			name: "missing value",
+			args: "userName eq",
+			want: "invalid value: unexpected end of filter",
+		},
+		{
+			name: "trailing and",
+			args: "userName eq \"test\" and",
+			want: "unexpected end of filter",
+		},
+		{
+			name: "trailing or",
+			args: "userName eq \"test\" or",
+			want: "unexpected end of filter",
+		},
+		{
+			name: "unclosed value path",
+			args: "emails[type eq \"work\"",
+			want: "unexpected end of filter",
+		},
+		{
+			name: "invalid operator",
+			args: "userName foo \"test\"",
+			want: "invalid operator: unexpected \"foo\" at position 9",
+		},
+		{
+			name: "invalid value",
+			args: "(userName eq )",
+			want: "invalid value: unexpected \")\" at position 13",
+		},
+		{
+			name: "invalid attribute",
+			args: "userName eq \"test\" and )",
+			want: "unexpected \")\" at position 23",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseFilter(tc.args)
+			assert.EqualError(t, err, tc.want)
+		})
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		name          string
+		args          string
+		want          Path
+		errorExpected bool
+	}{
+		{
+			name: "attribute",
+			args: "active",
+			want: Path{AttributePath: AttributePath{Name: "active"}},
+		},
+		{
+			name: "sub-attribute",
+			args: "name.givenName",
+			want: Path{AttributePath: AttributePath{Name: "name", SubAttribute: "givenName"}},
+		},
+		{
+			name: "extension attribute",
+			args: "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:manager.value",
+			want: Path{AttributePath: AttributePath{
+				URI:          "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User",
+				Name:         "manager",
+				SubAttribute: "value",
+			}},
+		},
+		{
+			name: "value filter",
+			args: `emails[type eq "work"]`,
+			want: Path{
+				AttributePath: AttributePath{Name: "emails"},
+				Filter:        AttributeExpression{Path: AttributePath{Name: "type"}, Operator: Eq, Value: "work"},
+			},
+		},
+		{
+			name: "value filter with sub-attribute",
+			args: `emails[type eq "work" and primary eq true].value`,
+			want: Path{
+				AttributePath: AttributePath{Name: "emails", SubAttribute: "value"},
+				Filter: LogicalExpression{
+					Operator: And,
+					Left:     AttributeExpression{Path: AttributePath{Name: "type"}, Operator: Eq, Value: "work"},
+					Right:    AttributeExpression{Path: AttributePath{Name: "primary"}, Operator: Eq, Value: true},
+				},
+			},
+		},
+		{
+			name:          "missing closing bracket",
+			args:          `emails[type eq "work"`,
+			errorExpected: true,
+		},
+		{
+			name:          "empty value filter",
+			args:          "emails[].value",
+			errorExpected: true,
+		},
+		{
+			name:          "invalid sub-attribute",
+			args:          `emails[type eq "work"]value`,
+			errorExpected: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParsePath(tc.args)
+			if tc.errorExpected {
+				assert.NotNil(t, err)
+			} else {
+				assert.Nil(t, err)
+			}
+
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
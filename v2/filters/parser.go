@@ -0,0 +1,370 @@
+package filters
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenString
+	tokenLeftParen
+	tokenRightParen
+	tokenLeftBracket
+	tokenRightBracket
+)
+
+type token struct {
+	kind     tokenKind
+	text     string
+	position int
+}
+
+var attributeNameRegex = regexp.MustCompile(`^(\$ref|[A-Za-z][A-Za-z0-9_-]*)$`)
+
+func tokenize(input string) ([]token, error) {
+	var tokens []token
+
+	for i := 0; i < len(input); {
+		c := input[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenLeftParen, text: "(", position: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenRightParen, text: ")", position: i})
+			i++
+		case c == '[':
+			tokens = append(tokens, token{kind: tokenLeftBracket, text: "[", position: i})
+			i++
+		case c == ']':
+			tokens = append(tokens, token{kind: tokenRightBracket, text: "]", position: i})
+			i++
+		case c == '"':
+			end := i + 1
+			for ; end < len(input) && input[end] != '"'; end++ {
+				if input[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(input) {
+				return nil, errors.Errorf("unterminated string at position %d", i)
+			}
+
+			tokens = append(tokens, token{kind: tokenString, text: input[i : end+1], position: i})
+			i = end + 1
+		default:
+			end := i
+			for ; end < len(input) && !strings.ContainsRune(" \t\n\r()[]\"", rune(input[end])); end++ {
+			}
+
+			tokens = append(tokens, token{kind: tokenWord, text: input[i:end], position: i})
+			i = end
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, position: len(input)}), nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func newParser(input string) (*parser, error) {
+	tokens, err := tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+
+	return &parser{tokens: tokens}, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+
+	return t
+}
+
+func (p *parser) done() bool {
+	return p.peek().kind == tokenEOF
+}
+
+func (p *parser) unexpected() error {
+	t := p.peek()
+	if t.kind == tokenEOF {
+		return errors.New("unexpected end of filter")
+	}
+
+	return errors.Errorf("unexpected %q at position %d", t.text, t.position)
+}
+
+func (p *parser) isKeyword(keyword string) bool {
+	t := p.peek()
+	return t.kind == tokenWord && strings.EqualFold(t.text, keyword)
+}
+
+func (p *parser) expect(kind tokenKind) error {
+	if p.peek().kind != kind {
+		return p.unexpected()
+	}
+
+	p.next()
+	return nil
+}
+
+// parseFilter parses a sequence of expressions joined by "or", which binds weaker than "and".
+// Within a value path, attribute paths are relative and value paths cannot be nested.
+func (p *parser) parseFilter(inValuePath bool) (Expression, error) {
+	left, err := p.parseAnd(inValuePath)
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isKeyword("or") {
+		p.next()
+
+		right, err := p.parseAnd(inValuePath)
+		if err != nil {
+			return nil, err
+		}
+
+		left = LogicalExpression{Operator: Or, Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseAnd(inValuePath bool) (Expression, error) {
+	left, err := p.parseUnary(inValuePath)
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isKeyword("and") {
+		p.next()
+
+		right, err := p.parseUnary(inValuePath)
+		if err != nil {
+			return nil, err
+		}
+
+		left = LogicalExpression{Operator: And, Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseUnary(inValuePath bool) (Expression, error) {
+	if p.isKeyword("not") {
+		p.next()
+
+		expression, err := p.parseGroup(inValuePath)
+		if err != nil {
+			return nil, err
+		}
+
+		return NotExpression{Expression: expression}, nil
+	}
+
+	if p.peek().kind == tokenLeftParen {
+		return p.parseGroup(inValuePath)
+	}
+
+	return p.parseAttributeExpression(inValuePath)
+}
+
+func (p *parser) parseGroup(inValuePath bool) (Expression, error) {
+	err := p.expect(tokenLeftParen)
+	if err != nil {
+		return nil, err
+	}
+
+	expression, err := p.parseFilter(inValuePath)
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.expect(tokenRightParen)
+	if err != nil {
+		return nil, err
+	}
+
+	return expression, nil
+}
+
+func (p *parser) parseAttributeExpression(inValuePath bool) (Expression, error) {
+	t := p.peek()
+	if t.kind != tokenWord {
+		return nil, p.unexpected()
+	}
+
+	p.next()
+
+	path, err := ParseAttributePath(t.text)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.peek().kind == tokenLeftBracket {
+		if inValuePath {
+			return nil, errors.Errorf("nested value path at position %d", p.peek().position)
+		} else if path.SubAttribute != "" {
+			return nil, errors.Errorf("value path on sub-attribute %q", path.String())
+		}
+
+		p.next()
+
+		filter, err := p.parseFilter(true)
+		if err != nil {
+			return nil, err
+		}
+
+		err = p.expect(tokenRightBracket)
+		if err != nil {
+			return nil, err
+		}
+
+		return ValuePathExpression{Path: path, Filter: filter}, nil
+	}
+
+	operatorToken := p.peek()
+	operator, ok := compareOperators[strings.ToLower(operatorToken.text)]
+	if operatorToken.kind != tokenWord || !ok {
+		return nil, errors.Wrap(p.unexpected(), "invalid operator")
+	}
+
+	p.next()
+
+	if operator == Pr {
+		return AttributeExpression{Path: path, Operator: Pr}, nil
+	}
+
+	value, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+
+	return AttributeExpression{Path: path, Operator: operator, Value: value}, nil
+}
+
+func (p *parser) parseValue() (interface{}, error) {
+	t := p.peek()
+	switch t.kind {
+	case tokenString:
+		p.next()
+
+		var value string
+		err := json.Unmarshal([]byte(t.text), &value)
+		if err != nil {
+			return nil, errors.Errorf("invalid string %s at position %d", t.text, t.position)
+		}
+
+		return value, nil
+	case tokenWord:
+		p.next()
+
+		switch strings.ToLower(t.text) {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+
+		value, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, errors.Errorf("invalid value %q at position %d", t.text, t.position)
+		}
+
+		return value, nil
+	default:
+		return nil, errors.Wrap(p.unexpected(), "invalid value")
+	}
+}
+
+// ParseAttributePath parses an attribute path such as "userName", "name.givenName" or
+// "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:manager.value".
+func ParseAttributePath(text string) (AttributePath, error) {
+	var path AttributePath
+
+	attribute := text
+	if strings.HasPrefix(strings.ToLower(text), "urn:") {
+		separator := strings.LastIndex(text, ":")
+		path.URI = text[:separator]
+		attribute = text[separator+1:]
+	}
+
+	name, subAttribute, hasSubAttribute := strings.Cut(attribute, ".")
+	if !attributeNameRegex.MatchString(name) {
+		return AttributePath{}, errors.Errorf("invalid attribute path %q", text)
+	} else if hasSubAttribute && !attributeNameRegex.MatchString(subAttribute) {
+		return AttributePath{}, errors.Errorf("invalid attribute path %q", text)
+	}
+
+	path.Name = name
+	path.SubAttribute = subAttribute
+
+	return path, nil
+}
+
+// ParsePath parses the path of a PATCH operation, as described in RFC 7644 section 3.5.2. Besides
+// attribute paths, it accepts value filters on multi-valued attributes, optionally followed by a
+// sub-attribute, e.g. emails[type eq "work"].value.
+func ParsePath(text string) (Path, error) {
+	open := strings.Index(text, "[")
+	if open == -1 {
+		path, err := ParseAttributePath(text)
+		if err != nil {
+			return Path{}, err
+		}
+
+		return Path{AttributePath: path}, nil
+	}
+
+	closing := strings.LastIndex(text, "]")
+	if closing < open {
+		return Path{}, errors.Errorf("invalid path %q: missing ']'", text)
+	}
+
+	path, err := ParseAttributePath(text[:open])
+	if err != nil {
+		return Path{}, err
+	} else if path.SubAttribute != "" {
+		return Path{}, errors.Errorf("invalid path %q: value filters apply to attributes only", text)
+	}
+
+	filter, err := ParseFilter(text[open+1 : closing])
+	if err != nil {
+		return Path{}, err
+	} else if filter == nil {
+		return Path{}, errors.Errorf("invalid path %q: empty value filter", text)
+	}
+
+	if rest := text[closing+1:]; rest != "" {
+		if !strings.HasPrefix(rest, ".") || !attributeNameRegex.MatchString(rest[1:]) {
+			return Path{}, errors.Errorf("invalid path %q", text)
+		}
+
+		path.SubAttribute = rest[1:]
+	}
+
+	return Path{AttributePath: path, Filter: filter}, nil
+}
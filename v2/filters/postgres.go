@@ -0,0 +1,451 @@
+package filters
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrUnknownAttribute is returned when a filter references an attribute that cannot be filtered on.
+var ErrUnknownAttribute = errors.New("unknown attribute")
+
+// ErrInvalidFilter is returned when a filter cannot be translated, e.g. because it compares an
+// attribute with a value of the wrong type.
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// ColumnType describes how an attribute is stored in PostgreSQL.
+type ColumnType int
+
+const (
+	// TextColumn stores a string attribute.
+	TextColumn ColumnType = iota
+	// BooleanColumn stores a boolean attribute.
+	BooleanColumn
+	// TimestampColumn stores a dateTime attribute. Filter values must be RFC 3339 timestamps.
+	TimestampColumn
+	// JSONBObjectColumn stores a complex attribute as a JSONB object keyed by sub-attribute.
+	JSONBObjectColumn
+	// JSONBArrayColumn stores a multi-valued complex attribute as a JSONB array of objects.
+	JSONBArrayColumn
+)
+
+// Column maps an attribute to its storage.
+type Column struct {
+	// Name is the column name, or any SQL expression yielding the value.
+	Name string
+	Type ColumnType
+	// CaseExact compares text values case-sensitively.
+	CaseExact bool
+}
+
+// PostgresTranslator turns filter expressions into parameterised PostgreSQL conditions.
+//
+// Columns maps attribute paths to columns, e.g. "userName", "name" or "meta.created". Sub-attributes
+// of JSONB columns don't need their own entry, "name.familyName" resolves to the "familyName" key of
+// the "name" column. Extension attributes are mapped by their fully qualified path, e.g.
+// "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:department".
+type PostgresTranslator struct {
+	Columns map[string]Column
+}
+
+// Translate returns the WHERE condition for the expression and the arguments of its placeholders.
+// Placeholders are numbered from firstPlaceholder on, so the condition can be combined with other
+// parameters of the query. A nil expression translates to "true".
+func (t PostgresTranslator) Translate(expression Expression, firstPlaceholder int) (string, []interface{}, error) {
+	if expression == nil {
+		return "true", nil, nil
+	}
+
+	translation := &postgresTranslation{
+		columns:          t.columns(),
+		firstPlaceholder: firstPlaceholder,
+	}
+
+	condition, err := translation.translate(expression, nil)
+	if errors.Is(err, ErrUnknownAttribute) {
+		return "", nil, err
+	} else if err != nil {
+		return "", nil, errors.Wrap(ErrInvalidFilter, err.Error())
+	}
+
+	return condition, translation.args, nil
+}
+
+func (t PostgresTranslator) columns() map[string]Column {
+	columns := make(map[string]Column, len(t.Columns))
+	for path, column := range t.Columns {
+		columns[strings.ToLower(path)] = column
+	}
+
+	return columns
+}
+
+type postgresTranslation struct {
+	columns          map[string]Column
+	firstPlaceholder int
+	args             []interface{}
+	elements         int
+}
+
+// elementScope is the value of a multi-valued attribute that a value path filter is applied to.
+type elementScope struct {
+	alias  string
+	column Column
+}
+
+// operand is an SQL expression yielding the value of an attribute.
+type operand struct {
+	sql       string
+	typ       ColumnType
+	jsonText  bool
+	caseExact bool
+}
+
+func (t *postgresTranslation) placeholder(value interface{}) string {
+	t.args = append(t.args, value)
+	return fmt.Sprintf("$%d", t.firstPlaceholder+len(t.args)-1)
+}
+
+func (t *postgresTranslation) translate(expression Expression, scope *elementScope) (string, error) {
+	switch e := expression.(type) {
+	case LogicalExpression:
+		left, err := t.translate(e.Left, scope)
+		if err != nil {
+			return "", err
+		}
+
+		right, err := t.translate(e.Right, scope)
+		if err != nil {
+			return "", err
+		}
+
+		return fmt.Sprintf("(%s %s %s)", left, e.Operator, right), nil
+	case NotExpression:
+		condition, err := t.translate(e.Expression, scope)
+		if err != nil {
+			return "", err
+		}
+
+		return fmt.Sprintf("(not coalesce(%s, false))", condition), nil
+	case ValuePathExpression:
+		column, err := t.lookup(e.Path)
+		if err != nil {
+			return "", err
+		} else if column.Type != JSONBArrayColumn {
+			return "", errors.Errorf("attribute %q is not multi-valued", e.Path.String())
+		}
+
+		element := t.element(column)
+		condition, err := t.translate(e.Filter, &element)
+		if err != nil {
+			return "", err
+		}
+
+		return t.exists(element, condition), nil
+	case AttributeExpression:
+		return t.translateAttribute(e, scope)
+	default:
+		return "", errors.Errorf("unsupported expression %T", expression)
+	}
+}
+
+func (t *postgresTranslation) translateAttribute(e AttributeExpression, scope *elementScope) (string, error) {
+	if scope != nil {
+		if e.Path.SubAttribute != "" || e.Path.URI != "" {
+			return "", errors.Wrapf(ErrUnknownAttribute, "%s", e.Path.String())
+		}
+
+		return t.compare(jsonbField(scope.alias, e.Path.Name, scope.column.CaseExact), e.Operator, e.Value)
+	}
+
+	if column, ok := t.columns[t.key(e.Path, true)]; ok {
+		return t.compareColumn(column, "", e)
+	}
+
+	column, err := t.lookup(e.Path)
+	if err != nil {
+		return "", err
+	}
+
+	return t.compareColumn(column, e.Path.SubAttribute, e)
+}
+
+func (t *postgresTranslation) compareColumn(column Column, subAttribute string, e AttributeExpression) (string, error) {
+	switch column.Type {
+	case JSONBObjectColumn:
+		if subAttribute == "" {
+			return t.comparePresence(column, e)
+		}
+
+		return t.compare(jsonbField(column.Name, subAttribute, column.CaseExact), e.Operator, e.Value)
+	case JSONBArrayColumn:
+		if subAttribute == "" && (e.Operator == Pr || e.Value == nil) {
+			return t.comparePresence(column, e)
+		} else if subAttribute == "" {
+			// multi-valued attributes are compared by their "value" sub-attribute
+			subAttribute = "value"
+		}
+
+		// a multi-valued attribute is "ne" a value when none of its values equals it
+		operator := e.Operator
+		if operator == Ne {
+			operator = Eq
+		}
+
+		element := t.element(column)
+		condition, err := t.compare(jsonbField(element.alias, subAttribute, column.CaseExact), operator, e.Value)
+		if err != nil {
+			return "", err
+		}
+
+		if e.Operator == Ne {
+			return "(not " + t.exists(element, condition) + ")", nil
+		}
+
+		return t.exists(element, condition), nil
+	default:
+		if subAttribute != "" {
+			return "", errors.Wrapf(ErrUnknownAttribute, "%s", e.Path.String())
+		}
+
+		return t.compare(operand{sql: column.Name, typ: column.Type, caseExact: column.CaseExact}, e.Operator, e.Value)
+	}
+}
+
+func (t *postgresTranslation) comparePresence(column Column, e AttributeExpression) (string, error) {
+	if e.Operator != Pr && e.Value != nil {
+		return "", errors.Errorf("operator %q is not supported for complex attributes", e.Operator)
+	}
+
+	if e.Operator == Eq {
+		return t.isEmpty(column), nil
+	}
+
+	return "(not " + t.isEmpty(column) + ")", nil
+}
+
+func (t *postgresTranslation) isEmpty(column Column) string {
+	if column.Type == JSONBArrayColumn {
+		return fmt.Sprintf("(case when jsonb_typeof(%s) = 'array' then jsonb_array_length(%s) else 0 end = 0)", column.Name, column.Name)
+	}
+
+	return fmt.Sprintf("(case when jsonb_typeof(%s) = 'object' then %s else '{}'::jsonb end = '{}'::jsonb)", column.Name, column.Name)
+}
+
+func (t *postgresTranslation) element(column Column) elementScope {
+	t.elements++
+	return elementScope{alias: fmt.Sprintf("element%d", t.elements), column: column}
+}
+
+func (t *postgresTranslation) exists(element elementScope, condition string) string {
+	return fmt.Sprintf(
+		"exists (select 1 from %s as %s where %s)",
+		arrayElements(element.column.Name),
+		element.alias,
+		condition,
+	)
+}
+
+// arrayElements returns the elements of a JSONB array column. Columns holding SQL NULL or any other
+// JSON value than an array, e.g. a JSON null, have no elements.
+func arrayElements(column string) string {
+	return fmt.Sprintf("jsonb_array_elements(case when jsonb_typeof(%s) = 'array' then %s else '[]'::jsonb end)", column, column)
+}
+
+func (t *postgresTranslation) compare(o operand, operator CompareOperator, value interface{}) (string, error) {
+	if operator == Pr {
+		if o.typ == TextColumn {
+			return fmt.Sprintf("(%s is not null and %s <> '')", o.sql, o.sql), nil
+		}
+
+		return fmt.Sprintf("(%s is not null)", o.sql), nil
+	}
+
+	if value == nil {
+		switch operator {
+		case Eq:
+			return fmt.Sprintf("(%s is null)", o.sql), nil
+		case Ne:
+			return fmt.Sprintf("(%s is not null)", o.sql), nil
+		default:
+			return "", errors.Errorf("operator %q is not supported for null", operator)
+		}
+	}
+
+	switch v := value.(type) {
+	case bool:
+		if o.jsonText {
+			o.sql += "::boolean"
+		} else if o.typ != BooleanColumn {
+			return "", errors.Errorf("attribute %s is not a boolean", o.sql)
+		}
+
+		switch operator {
+		case Eq:
+			return fmt.Sprintf("(%s = %s)", o.sql, t.placeholder(v)), nil
+		case Ne:
+			return fmt.Sprintf("(%s is distinct from %s)", o.sql, t.placeholder(v)), nil
+		default:
+			return "", errors.Errorf("operator %q is not supported for boolean values", operator)
+		}
+	case float64:
+		if !o.jsonText {
+			return "", errors.Errorf("attribute %s is not numeric", o.sql)
+		}
+
+		o.sql += "::numeric"
+		return t.order(o.sql, operator, t.placeholder(v))
+	case string:
+		return t.compareString(o, operator, v)
+	default:
+		return "", errors.Errorf("unsupported value %v", value)
+	}
+}
+
+func (t *postgresTranslation) compareString(o operand, operator CompareOperator, value string) (string, error) {
+	switch o.typ {
+	case BooleanColumn:
+		return "", errors.Errorf("attribute %s is a boolean", o.sql)
+	case TimestampColumn:
+		timestamp, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return "", errors.Errorf("invalid dateTime %q", value)
+		}
+
+		return t.order(o.sql, operator, t.placeholder(timestamp.UTC()))
+	}
+
+	like := "like"
+	if !o.caseExact {
+		like = "ilike"
+	}
+
+	switch operator {
+	case Co:
+		return fmt.Sprintf("(%s %s %s)", o.sql, like, t.placeholder("%"+escapeLike(value)+"%")), nil
+	case Sw:
+		return fmt.Sprintf("(%s %s %s)", o.sql, like, t.placeholder(escapeLike(value)+"%")), nil
+	case Ew:
+		return fmt.Sprintf("(%s %s %s)", o.sql, like, t.placeholder("%"+escapeLike(value))), nil
+	}
+
+	if o.caseExact {
+		return t.order(o.sql, operator, t.placeholder(value))
+	}
+
+	return t.order(fmt.Sprintf("lower(%s)", o.sql), operator, fmt.Sprintf("lower(%s)", t.placeholder(value)))
+}
+
+func (t *postgresTranslation) order(column string, operator CompareOperator, placeholder string) (string, error) {
+	switch operator {
+	case Eq:
+		return fmt.Sprintf("(%s = %s)", column, placeholder), nil
+	case Ne:
+		return fmt.Sprintf("(%s is distinct from %s)", column, placeholder), nil
+	case Gt:
+		return fmt.Sprintf("(%s > %s)", column, placeholder), nil
+	case Ge:
+		return fmt.Sprintf("(%s >= %s)", column, placeholder), nil
+	case Lt:
+		return fmt.Sprintf("(%s < %s)", column, placeholder), nil
+	case Le:
+		return fmt.Sprintf("(%s <= %s)", column, placeholder), nil
+	default:
+		return "", errors.Errorf("operator %q is not supported for this attribute", operator)
+	}
+}
+
+// lookup finds the column storing the attribute, ignoring its sub-attribute.
+func (t *postgresTranslation) lookup(path AttributePath) (Column, error) {
+	column, ok := t.columns[t.key(path, false)]
+	if !ok {
+		return Column{}, errors.Wrapf(ErrUnknownAttribute, "%s", path.String())
+	}
+
+	return column, nil
+}
+
+func (t *postgresTranslation) key(path AttributePath, withSubAttribute bool) string {
+	key := path.Name
+	if withSubAttribute {
+		key = path.String()
+	}
+
+	if path.URI != "" && !isCoreSchema(path.URI) {
+		key = path.URI + ":" + key
+	}
+
+	return strings.ToLower(key)
+}
+
+// jsonbField extracts a sub-attribute as text. Attribute names are validated by the parser, so they
+// are safe to embed in the query.
+func jsonbField(column, name string, caseExact bool) operand {
+	return operand{
+		sql:       fmt.Sprintf("(%s->>'%s')", column, name),
+		typ:       TextColumn,
+		jsonText:  true,
+		caseExact: caseExact,
+	}
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
+
+// OrderBy returns the ORDER BY expression for the attribute path. Multi-valued attributes are ordered
+// by their primary value, as described in RFC 7644 section 3.4.2.3.
+func (t PostgresTranslator) OrderBy(sortBy string, descending bool) (string, error) {
+	path, err := ParseAttributePath(sortBy)
+	if err != nil {
+		return "", err
+	}
+
+	translation := &postgresTranslation{columns: t.columns()}
+
+	var o operand
+	column, ok := translation.columns[translation.key(path, true)]
+	if ok && column.Type != JSONBObjectColumn && column.Type != JSONBArrayColumn {
+		o = operand{sql: column.Name, typ: column.Type, caseExact: column.CaseExact}
+	} else {
+		column, err := translation.lookup(path)
+		if err != nil {
+			return "", err
+		}
+
+		subAttribute := path.SubAttribute
+		switch column.Type {
+		case JSONBObjectColumn:
+			o = jsonbField(column.Name, subAttribute, column.CaseExact)
+		case JSONBArrayColumn:
+			if subAttribute == "" {
+				subAttribute = "value"
+			}
+
+			o = jsonbField("element", subAttribute, column.CaseExact)
+			o.sql = fmt.Sprintf(
+				"(select %s from %s as element where (element->>'primary')::boolean limit 1)",
+				o.sql,
+				arrayElements(column.Name),
+			)
+		default:
+			return "", errors.Wrapf(ErrUnknownAttribute, "%s", path.String())
+		}
+
+		if subAttribute == "" {
+			return "", errors.Errorf("cannot sort by complex attribute %q", path.String())
+		}
+	}
+
+	if o.typ == TextColumn && !o.caseExact {
+		o.sql = fmt.Sprintf("lower(%s)", o.sql)
+	}
+
+	if descending {
+		return o.sql + " desc", nil
+	}
+
+	return o.sql + " asc", nil
+}
@@ -0,0 +1,155 @@
+package filters
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostgresTranslator_Translate(t *testing.T) {
+	translator := PostgresTranslator{
+		Columns: map[string]Column{
+			"id":                {Name: "id::text", CaseExact: true},
+			"userName":          {Name: "username"},
+			"active":            {Name: "active", Type: BooleanColumn},
+			"name":              {Name: "name", Type: JSONBObjectColumn},
+			"emails":            {Name: "emails", Type: JSONBArrayColumn},
+			"meta.lastModified": {Name: "updated_at", Type: TimestampColumn},
+		},
+	}
+
+	tests := []struct {
+		filter        string
+		wantCondition string
+		wantArgs      []interface{}
+		errorExpected bool
+	}{
+		{
+			filter:        "",
+			wantCondition: "true",
+		},
+		{
+			filter:        `userName eq "bjensen"`,
+			wantCondition: "(lower(username) = lower($3))",
+			wantArgs:      []interface{}{"bjensen"},
+		},
+		{
+			filter:        `id eq "2819c223" or userName co "50%_"`,
+			wantCondition: `((id::text = $3) or (username ilike $4))`,
+			wantArgs:      []interface{}{"2819c223", `%50\%\_%`},
+		},
+		{
+			filter:        `not (active eq true) and userName pr`,
+			wantCondition: "((not coalesce((active = $3), false)) and (username is not null and username <> ''))",
+			wantArgs:      []interface{}{true},
+		},
+		{
+			filter:        `name.familyName sw "Jen"`,
+			wantCondition: "((name->>'familyName') ilike $3)",
+			wantArgs:      []interface{}{"Jen%"},
+		},
+		{
+			filter:        `meta.lastModified gt "2011-05-13T04:42:34Z"`,
+			wantCondition: "(updated_at > $3)",
+			wantArgs:      []interface{}{time.Date(2011, 5, 13, 4, 42, 34, 0, time.UTC)},
+		},
+		{
+			filter: `emails[type eq "work" and primary eq true]`,
+			wantCondition: "exists (select 1 from jsonb_array_elements(case when jsonb_typeof(emails) = 'array' then emails else '[]'::jsonb end) as element1 " +
+				"where ((lower((element1->>'type')) = lower($3)) and ((element1->>'primary')::boolean = $4)))",
+			wantArgs: []interface{}{"work", true},
+		},
+		{
+			filter: `emails ew "@example.com"`,
+			wantCondition: "exists (select 1 from jsonb_array_elements(case when jsonb_typeof(emails) = 'array' then emails else '[]'::jsonb end) as element1 " +
+				"where ((element1->>'value') ilike $3))",
+			wantArgs: []interface{}{"%@example.com"},
+		},
+		{
+			filter:        `emails pr`,
+			wantCondition: "(not (case when jsonb_typeof(emails) = 'array' then jsonb_array_length(emails) else 0 end = 0))",
+		},
+		{
+			filter:        `name pr`,
+			wantCondition: "(not (case when jsonb_typeof(name) = 'object' then name else '{}'::jsonb end = '{}'::jsonb))",
+		},
+		{
+			filter:        `not (name pr)`,
+			wantCondition: "(not coalesce((not (case when jsonb_typeof(name) = 'object' then name else '{}'::jsonb end = '{}'::jsonb)), false))",
+		},
+		{
+			filter:        `title eq "Tour Guide"`,
+			errorExpected: true,
+		},
+		{
+			filter:        `active gt true`,
+			errorExpected: true,
+		},
+		{
+			filter:        `meta.lastModified gt "yesterday"`,
+			errorExpected: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.filter, func(t *testing.T) {
+			expression, err := ParseFilter(tc.filter)
+			assert.Nil(t, err)
+
+			condition, args, err := translator.Translate(expression, 3)
+			if tc.errorExpected {
+				assert.NotNil(t, err)
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.Equal(t, tc.wantCondition, condition)
+			assert.Equal(t, tc.wantArgs, args)
+		})
+	}
+}
+
+func TestPostgresTranslator_OrderBy(t *testing.T) {
+	translator := PostgresTranslator{
+		Columns: map[string]Column{
+			"id":           {Name: "id::text", CaseExact: true},
+			"userName":     {Name: "username"},
+			"name":         {Name: "name", Type: JSONBObjectColumn},
+			"emails":       {Name: "emails", Type: JSONBArrayColumn},
+			"meta.created": {Name: "created_at", Type: TimestampColumn},
+		},
+	}
+
+	tests := []struct {
+		sortBy        string
+		descending    bool
+		want          string
+		errorExpected bool
+	}{
+		{sortBy: "userName", want: "lower(username) asc"},
+		{sortBy: "id", descending: true, want: "id::text desc"},
+		{sortBy: "meta.created", want: "created_at asc"},
+		{sortBy: "urn:ietf:params:scim:schemas:core:2.0:User:name.familyName", want: "lower((name->>'familyName')) asc"},
+		{
+			sortBy: "emails",
+			want: "lower((select (element->>'value') from jsonb_array_elements(case when jsonb_typeof(emails) = 'array' then emails else '[]'::jsonb end) as element " +
+				"where (element->>'primary')::boolean limit 1)) asc",
+		},
+		{sortBy: "name", errorExpected: true},
+		{sortBy: "title", errorExpected: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.sortBy, func(t *testing.T) {
+			got, err := translator.OrderBy(tc.sortBy, tc.descending)
+			if tc.errorExpected {
+				assert.NotNil(t, err)
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
@@ -8,8 +8,8 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v4"
 	openfga_scim_bridge "github.com/suse-skyscraper/openfga-scim-bridge/v2/bridge"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/database"
 	"github.com/suse-skyscraper/openfga-scim-bridge/v2/internal/responses"
 )
 
@@ -18,18 +18,19 @@ func UserCtx(bridge *openfga_scim_bridge.Bridge) func(next http.Handler) http.Ha
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			idString := chi.URLParam(r, "id")
 
+			// resources are identified by UUIDs, other ids cannot exist
 			id, err := uuid.Parse(idString)
 			if err != nil {
-				_ = render.Render(w, r, responses.ErrInternalServerError)
+				_ = render.Render(w, r, responses.ErrNotFound(idString))
 				return
 			}
 
 			user, err := bridge.DB.FindUser(r.Context(), id)
-			if errors.Is(err, pgx.ErrNoRows) {
+			if errors.Is(err, database.ErrNotFound) {
 				_ = render.Render(w, r, responses.ErrNotFound(idString))
 				return
 			} else if err != nil {
-				_ = render.Render(w, r, responses.ErrInternalServerError)
+				_ = render.Render(w, r, responses.ErrDatabase(err))
 				return
 			}
 
@@ -4,49 +4,71 @@ import (
 	"net/http"
 	"strconv"
 
-	"github.com/go-chi/chi/v5"
+	"github.com/pkg/errors"
 )
 
 type Params struct {
-	Offset int32
-	Limit  int32
+	// StartIndex is the 1-based index of the first resource of the page.
+	StartIndex int
+	Offset     int32
+	Limit      int32
 }
 
-var defaultPaginationParams = Params{
-	Offset: 0,
-	Limit:  10,
-}
-
-func Paginate(r *http.Request) Params {
-	startIndex := chi.URLParam(r, "startIndex")
-	count := chi.URLParam(r, "count")
+// Paginate reads the startIndex and count query parameters of a list request, as described in
+// RFC 7644 section 3.4.2.4.
+func Paginate(r *http.Request, defaultCount int, maxCount int) (Params, error) {
+	query := r.URL.Query()
 
-	var err error
-	var limit int64
-	var offset int64
-
-	if startIndex == "" {
-		limit = 10
-	} else {
-		limit, err = strconv.ParseInt(count, 10, 32)
+	var startIndex *int
+	if value := query.Get("startIndex"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 32)
 		if err != nil {
-			return defaultPaginationParams
+			return Params{}, errors.Errorf("startIndex %q is not an integer", value)
 		}
+
+		startIndex = intPtr(int(parsed))
 	}
 
-	if count == "" {
-		offset = 0
-	} else {
-		offset, err = strconv.ParseInt(startIndex, 10, 32)
+	var count *int
+	if value := query.Get("count"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 32)
 		if err != nil {
-			return defaultPaginationParams
+			return Params{}, errors.Errorf("count %q is not an integer", value)
 		}
+
+		count = intPtr(int(parsed))
 	}
 
-	params := Params{
-		Offset: int32(offset),
-		Limit:  int32(limit),
+	return New(startIndex, count, defaultCount, maxCount)
+}
+
+// New validates the startIndex and count of a list or search request, either of which may be
+// omitted. A startIndex below 1 is interpreted as 1. The count defaults to defaultCount and must be
+// between 1 and maxCount.
+func New(startIndex *int, count *int, defaultCount int, maxCount int) (Params, error) {
+	start := 1
+	if startIndex != nil && *startIndex > 1 {
+		start = *startIndex
 	}
 
-	return params
+	limit := defaultCount
+	if count != nil {
+		limit = *count
+	}
+
+	if limit < 1 {
+		return Params{}, errors.Errorf("count must be at least 1, got %d", limit)
+	} else if limit > maxCount {
+		return Params{}, errors.Errorf("count must not exceed %d, got %d", maxCount, limit)
+	}
+
+	return Params{
+		StartIndex: start,
+		Offset:     int32(start - 1),
+		Limit:      int32(limit),
+	}, nil
+}
+
+func intPtr(value int) *int {
+	return &value
 }
@@ -0,0 +1,91 @@
+package pagination
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPaginate(t *testing.T) {
+	tests := []struct {
+		name          string
+		query         string
+		want          Params
+		errorExpected bool
+	}{
+		{
+			name:  "defaults",
+			query: "",
+			want:  Params{StartIndex: 1, Offset: 0, Limit: 10},
+		},
+		{
+			name:  "start index and count",
+			query: "?startIndex=21&count=20",
+			want:  Params{StartIndex: 21, Offset: 20, Limit: 20},
+		},
+		{
+			name:  "start index below 1",
+			query: "?startIndex=-5",
+			want:  Params{StartIndex: 1, Offset: 0, Limit: 10},
+		},
+		{
+			name:  "maximum count",
+			query: "?count=100",
+			want:  Params{StartIndex: 1, Offset: 0, Limit: 100},
+		},
+		{
+			name:          "count above maximum",
+			query:         "?count=101",
+			errorExpected: true,
+		},
+		{
+			name:          "zero count",
+			query:         "?count=0",
+			errorExpected: true,
+		},
+		{
+			name:          "negative count",
+			query:         "?count=-1",
+			errorExpected: true,
+		},
+		{
+			name:          "malformed start index",
+			query:         "?startIndex=first",
+			errorExpected: true,
+		},
+		{
+			name:          "malformed count",
+			query:         "?count=1.5",
+			errorExpected: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := http.NewRequest(http.MethodGet, "/scim/v2/Users"+tc.query, nil)
+			assert.Nil(t, err)
+
+			got, err := Paginate(r, 10, 100)
+			if tc.errorExpected {
+				assert.NotNil(t, err)
+			} else {
+				assert.Nil(t, err)
+			}
+
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	startIndex, count := 11, 5
+
+	got, err := New(&startIndex, &count, 10, 100)
+	assert.Nil(t, err)
+	assert.Equal(t, Params{StartIndex: 11, Offset: 10, Limit: 5}, got)
+
+	got, err = New(nil, nil, 10, 100)
+	assert.Nil(t, err)
+	assert.Equal(t, Params{StartIndex: 1, Offset: 0, Limit: 10}, got)
+}
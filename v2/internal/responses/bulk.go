@@ -0,0 +1,36 @@
+package responses
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+type BulkOperationResponse struct {
+	Location string `json:"location,omitempty"`
+	Method   string `json:"method"`
+	BulkID   string `json:"bulkId,omitempty"`
+	Version  string `json:"version,omitempty"`
+	Status   string `json:"status"`
+	// Response is the error of a failed operation.
+	Response json.RawMessage `json:"response,omitempty"`
+}
+
+type BulkResponse struct {
+	Schemas    []string                 `json:"schemas"`
+	Operations []*BulkOperationResponse `json:"Operations"`
+}
+
+func (rd *BulkResponse) Render(_ http.ResponseWriter, _ *http.Request) error {
+	return nil
+}
+
+func NewBulkResponse(operations []*BulkOperationResponse) *BulkResponse {
+	if operations == nil {
+		operations = []*BulkOperationResponse{}
+	}
+
+	return &BulkResponse{
+		Schemas:    []string{"urn:ietf:params:scim:api:messages:2.0:BulkResponse"},
+		Operations: operations,
+	}
+}
@@ -4,6 +4,11 @@ import (
 	"net/http"
 
 	"github.com/go-chi/render"
+	"github.com/pkg/errors"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/database"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/filters"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/patch"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/validation"
 )
 
 var ErrInternalServerError = &ErrResponse{Schemas: errorSchema, HTTPStatusCode: 500, Details: "Internal server error"}
@@ -48,3 +53,132 @@ func ErrBadFilter(err error) render.Renderer {
 		HTTPStatusCode: 400,
 	}
 }
+
+func ErrInvalidSyntax(err error) render.Renderer {
+	return &ErrResponse{
+		Schemas:        errorSchema,
+		ScimType:       "invalidSyntax",
+		Details:        err.Error(),
+		HTTPStatusCode: 400,
+	}
+}
+
+func ErrPayloadTooLarge(err error) render.Renderer {
+	return &ErrResponse{
+		Schemas:        errorSchema,
+		Details:        err.Error(),
+		HTTPStatusCode: 413,
+	}
+}
+
+// ErrTooManyOperations renders a bulk request with more operations than the maxOperations, see
+// RFC 7644 section 3.7.4.
+func ErrTooManyOperations(err error) render.Renderer {
+	return &ErrResponse{
+		Schemas:        errorSchema,
+		ScimType:       "tooMany",
+		Details:        err.Error(),
+		HTTPStatusCode: 413,
+	}
+}
+
+// ErrPreconditionFailed renders a conditional request whose If-Match header doesn't match the current
+// version of the resource.
+func ErrPreconditionFailed(err error) render.Renderer {
+	return &ErrResponse{
+		Schemas:        errorSchema,
+		Details:        err.Error(),
+		HTTPStatusCode: 412,
+	}
+}
+
+// ErrMutability renders an attempt to modify an attribute that cannot be modified.
+func ErrMutability(err error) render.Renderer {
+	return &ErrResponse{
+		Schemas:        errorSchema,
+		ScimType:       patch.Mutability,
+		Details:        err.Error(),
+		HTTPStatusCode: 400,
+	}
+}
+
+// ErrPatch renders an error of a PATCH operation with its scimType.
+func ErrPatch(err error) render.Renderer {
+	scimType := patch.InvalidValue
+
+	var patchErr *patch.Error
+	if errors.As(err, &patchErr) {
+		scimType = patchErr.ScimType
+	}
+
+	return &ErrResponse{
+		Schemas:        errorSchema,
+		ScimType:       scimType,
+		Details:        err.Error(),
+		HTTPStatusCode: 400,
+	}
+}
+
+// ErrValidation renders a resource that doesn't conform to its schemas with the scimType of the
+// validation error.
+func ErrValidation(err error) render.Renderer {
+	scimType := validation.InvalidValue
+
+	var validationErr *validation.Error
+	if errors.As(err, &validationErr) {
+		scimType = validationErr.ScimType
+	}
+
+	return &ErrResponse{
+		Schemas:        errorSchema,
+		ScimType:       scimType,
+		Details:        err.Error(),
+		HTTPStatusCode: 400,
+	}
+}
+
+// ErrDatabase renders an error returned by the database.Bridge with the status and scimType of its
+// type, see RFC 7644 section 3.12. Errors of other types are internal server errors.
+func ErrDatabase(err error) render.Renderer {
+	var mutabilityErr *database.MutabilityError
+	var invalidValueErr *database.InvalidValueError
+	var patchErr *patch.Error
+	var validationErr *validation.Error
+
+	switch {
+	case errors.Is(err, database.ErrNotFound):
+		return &ErrResponse{
+			Schemas:        errorSchema,
+			Details:        err.Error(),
+			HTTPStatusCode: 404,
+		}
+	case errors.Is(err, database.ErrConflict):
+		return &ErrResponse{
+			Schemas:        errorSchema,
+			ScimType:       "uniqueness",
+			Details:        err.Error(),
+			HTTPStatusCode: 409,
+		}
+	case errors.Is(err, database.ErrTooMany):
+		return &ErrResponse{
+			Schemas:        errorSchema,
+			ScimType:       "tooMany",
+			Details:        err.Error(),
+			HTTPStatusCode: 400,
+		}
+	case errors.Is(err, database.ErrPreconditionFailed):
+		return ErrPreconditionFailed(err)
+	case errors.As(err, &mutabilityErr):
+		return ErrMutability(err)
+	case errors.As(err, &invalidValueErr):
+		return ErrBadValue(err)
+	case errors.As(err, &patchErr):
+		return ErrPatch(err)
+	case errors.As(err, &validationErr):
+		return ErrValidation(err)
+	case errors.Is(err, filters.ErrUnknownAttribute) || errors.Is(err, filters.ErrInvalidFilter):
+		return ErrBadFilter(err)
+	default:
+		return ErrInternalServerError
+	}
+}
@@ -0,0 +1,40 @@
+package responses
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/database"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/filters"
+)
+
+func TestErrDatabase(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantType   string
+	}{
+		{name: "not found", err: database.ErrNotFound, wantStatus: 404},
+		{name: "conflict", err: database.ErrConflict, wantStatus: 409, wantType: "uniqueness"},
+		{name: "conflict with attribute", err: &database.ConflictError{Attribute: "userName"}, wantStatus: 409, wantType: "uniqueness"},
+		{name: "mutability", err: &database.MutabilityError{Attribute: "id"}, wantStatus: 400, wantType: "mutability"},
+		{name: "invalid value", err: &database.InvalidValueError{Attribute: "emails", Detail: "too long"}, wantStatus: 400, wantType: "invalidValue"},
+		{name: "membership cycle", err: database.ErrMembershipCycle, wantStatus: 400, wantType: "invalidValue"},
+		{name: "too many", err: database.ErrTooMany, wantStatus: 400, wantType: "tooMany"},
+		{name: "precondition failed", err: database.ErrPreconditionFailed, wantStatus: 412},
+		{name: "unknown filter attribute", err: errors.Wrap(filters.ErrUnknownAttribute, "nickName"), wantStatus: 400, wantType: "invalidFilter"},
+		{name: "wrapped", err: errors.Wrap(database.ErrNotFound, "group"), wantStatus: 404},
+		{name: "other", err: errors.New("connection refused"), wantStatus: 500},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := ErrDatabase(tc.err).(*ErrResponse)
+			assert.True(t, ok)
+			assert.Equal(t, tc.wantStatus, got.HTTPStatusCode)
+			assert.Equal(t, tc.wantType, got.ScimType)
+		})
+	}
+}
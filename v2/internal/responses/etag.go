@@ -0,0 +1,11 @@
+package responses
+
+import (
+	"fmt"
+)
+
+// ETag returns the weak entity tag of a resource version, used both as the ETag header and as
+// meta.version, as described in RFC 7644 section 3.14.
+func ETag(version string) string {
+	return fmt.Sprintf(`W/"%s"`, version)
+}
@@ -0,0 +1,59 @@
+package responses
+
+import (
+	"encoding/json"
+
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/bridge"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/database"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/schema"
+)
+
+// customExtensions returns the attributes of the custom extensions of a resource that are part of
+// responses, and the URIs of their schemas. Attributes that are never returned, like passwords, are
+// left out.
+func customExtensions(bridge *bridge.Bridge, resourceType string, extensions database.Extensions) (database.Extensions, []string) {
+	var returned database.Extensions
+	var schemas []string
+	for _, s := range bridge.CustomExtensions(resourceType) {
+		values := map[string]interface{}{}
+		for name, value := range extensions[s.ID] {
+			if attribute, ok := s.Attribute(name); ok && attribute.Returned == schema.Never {
+				continue
+			}
+			values[name] = value
+		}
+
+		if len(values) == 0 {
+			continue
+		}
+
+		if returned == nil {
+			returned = database.Extensions{}
+		}
+		returned[s.ID] = values
+		schemas = append(schemas, s.ID)
+	}
+
+	return returned, schemas
+}
+
+// marshalWithExtensions encodes a resource with the attributes of its custom extensions nested below
+// their schema URI.
+func marshalWithExtensions(resource interface{}, extensions database.Extensions) ([]byte, error) {
+	data, err := json.Marshal(resource)
+	if err != nil || len(extensions) == 0 {
+		return data, err
+	}
+
+	var attributes map[string]interface{}
+	err = json.Unmarshal(data, &attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	for id, values := range extensions {
+		attributes[id] = values
+	}
+
+	return json.Marshal(attributes)
+}
@@ -0,0 +1,272 @@
+package responses
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/filters"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/schema"
+)
+
+// alwaysReturned are the attributes that are part of every response, regardless of the projection.
+var alwaysReturned = append([]string{"schemas"}, schema.AlwaysReturned()...)
+
+// Projection selects the attributes of the resources in a response, as described in RFC 7644
+// section 3.4.2.5. The zero value returns every attribute.
+type Projection struct {
+	Attributes         []filters.AttributePath
+	ExcludedAttributes []filters.AttributePath
+}
+
+// NewProjection reads the attributes and excludedAttributes query parameters of a request.
+func NewProjection(r *http.Request) (Projection, error) {
+	query := r.URL.Query()
+
+	return ParseProjection(splitAttributes(query.Get("attributes")), splitAttributes(query.Get("excludedAttributes")))
+}
+
+// ParseProjection parses the lists of attribute paths to return and to exclude.
+func ParseProjection(attributes []string, excludedAttributes []string) (Projection, error) {
+	var projection Projection
+
+	for _, attribute := range attributes {
+		path, err := filters.ParseAttributePath(attribute)
+		if err != nil {
+			return Projection{}, err
+		}
+
+		projection.Attributes = append(projection.Attributes, path)
+	}
+
+	for _, attribute := range excludedAttributes {
+		path, err := filters.ParseAttributePath(attribute)
+		if err != nil {
+			return Projection{}, err
+		}
+
+		projection.ExcludedAttributes = append(projection.ExcludedAttributes, path)
+	}
+
+	return projection, nil
+}
+
+func splitAttributes(value string) []string {
+	var attributes []string
+	for _, attribute := range strings.Split(value, ",") {
+		attribute = strings.TrimSpace(attribute)
+		if attribute != "" {
+			attributes = append(attributes, attribute)
+		}
+	}
+
+	return attributes
+}
+
+// IsEmpty reports whether the projection returns every attribute.
+func (p Projection) IsEmpty() bool {
+	return len(p.Attributes) == 0 && len(p.ExcludedAttributes) == 0
+}
+
+// Includes reports whether the top-level attribute, e.g. "members", is part of the response. It
+// allows skipping expensive lookups of attributes that are not returned.
+func (p Projection) Includes(attribute string) bool {
+	if isAlwaysReturned(attribute) {
+		return true
+	}
+
+	for _, path := range p.ExcludedAttributes {
+		if path.SubAttribute == "" && strings.EqualFold(path.Name, attribute) {
+			return false
+		}
+	}
+
+	if len(p.Attributes) == 0 {
+		return true
+	}
+
+	for _, path := range p.Attributes {
+		if strings.EqualFold(path.Name, attribute) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Apply returns the response reduced to the projected attributes. It accepts single resources as
+// well as list responses, whose resources are projected individually.
+func (p Projection) Apply(response interface{}) (interface{}, error) {
+	if p.IsEmpty() {
+		return response, nil
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		return nil, err
+	}
+
+	var document map[string]interface{}
+	err = json.Unmarshal(data, &document)
+	if err != nil {
+		return nil, err
+	}
+
+	resources, isList := document["Resources"].([]interface{})
+	if !isList {
+		return p.project(document), nil
+	}
+
+	for i, resource := range resources {
+		if attributes, ok := resource.(map[string]interface{}); ok {
+			resources[i] = p.project(attributes)
+		}
+	}
+
+	return document, nil
+}
+
+func (p Projection) project(resource map[string]interface{}) map[string]interface{} {
+	if len(p.Attributes) > 0 {
+		included := map[string]interface{}{}
+		for _, path := range p.Attributes {
+			include(resource, included, segments(resource, path))
+		}
+
+		for _, attribute := range alwaysReturned {
+			if value, ok := resource[attribute]; ok {
+				included[attribute] = value
+			}
+		}
+
+		resource = included
+	}
+
+	for _, path := range p.ExcludedAttributes {
+		exclude(resource, segments(resource, path))
+	}
+
+	return resource
+}
+
+// segments splits the path into the keys leading to the attribute in the resource. Extension
+// attributes are nested below their schema URI.
+func segments(resource map[string]interface{}, path filters.AttributePath) []string {
+	if path.URI != "" {
+		// the path may reference an extension schema as a whole
+		uri := path.URI + ":" + path.String()
+		if key := findKey(resource, uri); key != "" {
+			return []string{key}
+		}
+	}
+
+	var keys []string
+	if path.URI != "" && findKey(resource, path.URI) != "" {
+		keys = append(keys, path.URI)
+	}
+
+	keys = append(keys, path.Name)
+	if path.SubAttribute != "" {
+		keys = append(keys, path.SubAttribute)
+	}
+
+	return keys
+}
+
+// include copies the value at the keys from source to target. Multi-valued complex attributes are
+// copied element by element.
+func include(source map[string]interface{}, target map[string]interface{}, keys []string) {
+	key := findKey(source, keys[0])
+	if key == "" {
+		return
+	}
+
+	value := source[key]
+	if len(keys) == 1 {
+		target[key] = value
+		return
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		nested, ok := target[key].(map[string]interface{})
+		if !ok {
+			nested = map[string]interface{}{}
+			target[key] = nested
+		}
+
+		include(v, nested, keys[1:])
+	case []interface{}:
+		nested, ok := target[key].([]interface{})
+		if !ok {
+			nested = make([]interface{}, len(v))
+			target[key] = nested
+		}
+
+		for i, element := range v {
+			elementSource, ok := element.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			elementTarget, ok := nested[i].(map[string]interface{})
+			if !ok {
+				elementTarget = map[string]interface{}{}
+				nested[i] = elementTarget
+			}
+
+			include(elementSource, elementTarget, keys[1:])
+		}
+	}
+}
+
+// exclude removes the value at the keys, unless it is always returned.
+func exclude(resource map[string]interface{}, keys []string) {
+	key := findKey(resource, keys[0])
+	if key == "" {
+		return
+	}
+
+	if len(keys) == 1 {
+		if !isAlwaysReturned(key) {
+			delete(resource, key)
+		}
+		return
+	}
+
+	switch v := resource[key].(type) {
+	case map[string]interface{}:
+		exclude(v, keys[1:])
+	case []interface{}:
+		for _, element := range v {
+			if attributes, ok := element.(map[string]interface{}); ok {
+				exclude(attributes, keys[1:])
+			}
+		}
+	}
+}
+
+// findKey returns the key of the attribute in the resource, ignoring case.
+func findKey(resource map[string]interface{}, name string) string {
+	if _, ok := resource[name]; ok {
+		return name
+	}
+
+	for key := range resource {
+		if strings.EqualFold(key, name) {
+			return key
+		}
+	}
+
+	return ""
+}
+
+func isAlwaysReturned(attribute string) bool {
+	for _, always := range alwaysReturned {
+		if strings.EqualFold(always, attribute) {
+			return true
+		}
+	}
+
+	return false
+}
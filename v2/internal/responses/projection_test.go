@@ -0,0 +1,107 @@
+package responses
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProjection_Apply(t *testing.T) {
+	user := func() map[string]interface{} {
+		return map[string]interface{}{
+			"schemas":  []interface{}{"urn:ietf:params:scim:schemas:core:2.0:User"},
+			"id":       "2819c223",
+			"userName": "bjensen",
+			"name": map[string]interface{}{
+				"givenName":  "Barbara",
+				"familyName": "Jensen",
+			},
+			"emails": []interface{}{
+				map[string]interface{}{"value": "bjensen@example.com", "type": "work"},
+			},
+			"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": map[string]interface{}{
+				"department":     "Tour Operations",
+				"employeeNumber": "701984",
+			},
+		}
+	}
+
+	tests := []struct {
+		name               string
+		attributes         []string
+		excludedAttributes []string
+		want               map[string]interface{}
+	}{
+		{
+			name:       "attributes",
+			attributes: []string{"USERNAME", "name.familyName", "emails.value"},
+			want: map[string]interface{}{
+				"schemas":  []interface{}{"urn:ietf:params:scim:schemas:core:2.0:User"},
+				"id":       "2819c223",
+				"userName": "bjensen",
+				"name": map[string]interface{}{
+					"familyName": "Jensen",
+				},
+				"emails": []interface{}{
+					map[string]interface{}{"value": "bjensen@example.com"},
+				},
+			},
+		},
+		{
+			name:       "extension attributes",
+			attributes: []string{"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:department"},
+			want: map[string]interface{}{
+				"schemas": []interface{}{"urn:ietf:params:scim:schemas:core:2.0:User"},
+				"id":      "2819c223",
+				"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": map[string]interface{}{
+					"department": "Tour Operations",
+				},
+			},
+		},
+		{
+			name:               "excluded attributes",
+			excludedAttributes: []string{"id", "name", "emails.type", "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"},
+			want: map[string]interface{}{
+				"schemas":  []interface{}{"urn:ietf:params:scim:schemas:core:2.0:User"},
+				"id":       "2819c223",
+				"userName": "bjensen",
+				"emails": []interface{}{
+					map[string]interface{}{"value": "bjensen@example.com"},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			projection, err := ParseProjection(tc.attributes, tc.excludedAttributes)
+			assert.Nil(t, err)
+
+			got, err := projection.Apply(user())
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+
+			list, err := projection.Apply(map[string]interface{}{
+				"Resources": []interface{}{user()},
+			})
+			assert.Nil(t, err)
+			assert.Equal(t, map[string]interface{}{"Resources": []interface{}{tc.want}}, list)
+		})
+	}
+}
+
+func TestProjection_Includes(t *testing.T) {
+	projection, err := ParseProjection(nil, []string{"members"})
+	assert.Nil(t, err)
+	assert.False(t, projection.Includes("members"))
+	assert.True(t, projection.Includes("displayName"))
+
+	projection, err = ParseProjection([]string{"displayName"}, nil)
+	assert.Nil(t, err)
+	assert.False(t, projection.Includes("members"))
+	assert.True(t, projection.Includes("id"))
+
+	projection, err = ParseProjection([]string{"members.value"}, nil)
+	assert.Nil(t, err)
+	assert.True(t, projection.Includes("members"))
+}
@@ -0,0 +1,141 @@
+package responses
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/bridge"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/schema"
+)
+
+type SchemaResponse struct {
+	Schemas     []string           `json:"schemas,omitempty"`
+	ID          string             `json:"id"`
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Attributes  []schema.Attribute `json:"attributes"`
+	Meta        map[string]string  `json:"meta"`
+}
+
+func (rd *SchemaResponse) Render(_ http.ResponseWriter, _ *http.Request) error {
+	return nil
+}
+
+type ListSchemasResponse struct {
+	Schemas      []string          `json:"schemas"`
+	ItemsPerPage int               `json:"itemsPerPage"`
+	StartIndex   int               `json:"startIndex"`
+	TotalResults int               `json:"totalResults"`
+	Resources    []*SchemaResponse `json:"Resources"`
+}
+
+func (rd *ListSchemasResponse) Render(_ http.ResponseWriter, _ *http.Request) error {
+	return nil
+}
+
+func NewSchemaResponse(bridge *bridge.Bridge, s schema.Schema) *SchemaResponse {
+	return newSchemaResponse(bridge, s, true)
+}
+
+func NewListSchemasResponse(bridge *bridge.Bridge, schemas []schema.Schema) *ListSchemasResponse {
+	resources := make([]*SchemaResponse, 0, len(schemas))
+	for _, s := range schemas {
+		resources = append(resources, newSchemaResponse(bridge, s, false))
+	}
+
+	return &ListSchemasResponse{
+		Schemas:      []string{"urn:ietf:params:scim:api:messages:2.0:ListResponse"},
+		ItemsPerPage: len(resources),
+		StartIndex:   1,
+		TotalResults: len(resources),
+		Resources:    resources,
+	}
+}
+
+func newSchemaResponse(bridge *bridge.Bridge, s schema.Schema, singleResponse bool) *SchemaResponse {
+	// the schemas should be added if the response is a single schema, not a list
+	var schemas []string
+	if singleResponse {
+		schemas = []string{schema.SchemaURN}
+	}
+
+	return &SchemaResponse{
+		Schemas:     schemas,
+		ID:          s.ID,
+		Name:        s.Name,
+		Description: s.Description,
+		Attributes:  s.Attributes,
+		Meta: map[string]string{
+			"resourceType": "Schema",
+			"location":     fmt.Sprintf("%s/scim/v2/Schemas/%s", bridge.BaseURL, s.ID),
+		},
+	}
+}
+
+type ResourceTypeResponse struct {
+	Schemas          []string                 `json:"schemas,omitempty"`
+	ID               string                   `json:"id"`
+	Name             string                   `json:"name"`
+	Endpoint         string                   `json:"endpoint"`
+	Description      string                   `json:"description"`
+	Schema           string                   `json:"schema"`
+	SchemaExtensions []schema.SchemaExtension `json:"schemaExtensions,omitempty"`
+	Meta             map[string]string        `json:"meta"`
+}
+
+func (rd *ResourceTypeResponse) Render(_ http.ResponseWriter, _ *http.Request) error {
+	return nil
+}
+
+type ListResourceTypesResponse struct {
+	Schemas      []string                `json:"schemas"`
+	ItemsPerPage int                     `json:"itemsPerPage"`
+	StartIndex   int                     `json:"startIndex"`
+	TotalResults int                     `json:"totalResults"`
+	Resources    []*ResourceTypeResponse `json:"Resources"`
+}
+
+func (rd *ListResourceTypesResponse) Render(_ http.ResponseWriter, _ *http.Request) error {
+	return nil
+}
+
+func NewResourceTypeResponse(bridge *bridge.Bridge, resourceType schema.ResourceType) *ResourceTypeResponse {
+	return newResourceTypeResponse(bridge, resourceType, true)
+}
+
+func NewListResourceTypesResponse(bridge *bridge.Bridge, resourceTypes []schema.ResourceType) *ListResourceTypesResponse {
+	resources := make([]*ResourceTypeResponse, 0, len(resourceTypes))
+	for _, resourceType := range resourceTypes {
+		resources = append(resources, newResourceTypeResponse(bridge, resourceType, false))
+	}
+
+	return &ListResourceTypesResponse{
+		Schemas:      []string{"urn:ietf:params:scim:api:messages:2.0:ListResponse"},
+		ItemsPerPage: len(resources),
+		StartIndex:   1,
+		TotalResults: len(resources),
+		Resources:    resources,
+	}
+}
+
+func newResourceTypeResponse(bridge *bridge.Bridge, resourceType schema.ResourceType, singleResponse bool) *ResourceTypeResponse {
+	// the schemas should be added if the response is a single resource type, not a list
+	var schemas []string
+	if singleResponse {
+		schemas = []string{schema.ResourceTypeURN}
+	}
+
+	return &ResourceTypeResponse{
+		Schemas:          schemas,
+		ID:               resourceType.ID,
+		Name:             resourceType.Name,
+		Endpoint:         resourceType.Endpoint,
+		Description:      resourceType.Description,
+		Schema:           resourceType.Schema,
+		SchemaExtensions: resourceType.SchemaExtensions,
+		Meta: map[string]string{
+			"resourceType": "ResourceType",
+			"location":     fmt.Sprintf("%s/scim/v2/ResourceTypes/%s", bridge.BaseURL, resourceType.ID),
+		},
+	}
+}
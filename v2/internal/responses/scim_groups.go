@@ -12,15 +12,24 @@ import (
 type ScimGroupResponse struct {
 	Schemas     []string            `json:"schemas,omitempty"`
 	ID          string              `json:"id"`
+	ExternalID  string              `json:"externalId,omitempty"`
 	DisplayName string              `json:"displayName"`
 	Members     []map[string]string `json:"members"`
 	Meta        map[string]string   `json:"meta"`
+	// Extensions are the custom extensions of the group, they are encoded below their schema URI.
+	Extensions database.Extensions `json:"-"`
 }
 
 func (rd *ScimGroupResponse) Render(_ http.ResponseWriter, _ *http.Request) error {
 	return nil
 }
 
+func (rd *ScimGroupResponse) MarshalJSON() ([]byte, error) {
+	// the alias has no MarshalJSON method, so that the fields are encoded as usual
+	type response ScimGroupResponse
+	return marshalWithExtensions((*response)(rd), rd.Extensions)
+}
+
 type ScimListGroupsResponse struct {
 	Schemas      []string             `json:"schemas"`
 	ItemsPerPage int                  `json:"itemsPerPage"`
@@ -40,9 +49,16 @@ func NewScimGroupResponse(
 ) *ScimGroupResponse {
 	var memberships []map[string]string
 	for _, member := range members {
+		endpoint := "Users"
+		if member.Type == database.GroupMember {
+			endpoint = "Groups"
+		}
+
 		memberships = append(memberships, map[string]string{
-			"value":   member.UserID.String(),
-			"display": member.Username.String,
+			"value":   member.MemberID.String(),
+			"$ref":    fmt.Sprintf("%s/scim/v2/%s/%s", bridge.BaseURL, endpoint, member.MemberID.String()),
+			"type":    member.Type,
+			"display": member.Display.String,
 		})
 	}
 	return newScimGroupResponse(bridge, group, memberships, true)
@@ -54,23 +70,32 @@ func newScimGroupResponse(
 	members []map[string]string,
 	singleResponse bool,
 ) *ScimGroupResponse {
+	extensions, extensionSchemas := customExtensions(bridge, "Group", group.Extensions)
+
 	// the schemas should be added if the response is a single user, not a list
 	var schemas []string
 	if singleResponse {
-		schemas = []string{"urn:ietf:params:scim:schemas:core:2.0:Group"}
+		schemas = append([]string{"urn:ietf:params:scim:schemas:core:2.0:Group"}, extensionSchemas...)
+	}
+
+	meta := map[string]string{
+		"resourceType": "Group",
+		"created":      group.CreatedAt.Format(time.RFC3339),
+		"lastModified": group.UpdatedAt.Format(time.RFC3339),
+		"location":     fmt.Sprintf("%s/scim/v2/Groups/%s", bridge.BaseURL, group.ID.String()),
+	}
+	if group.Version != "" {
+		meta["version"] = ETag(group.Version)
 	}
 
 	return &ScimGroupResponse{
 		Schemas:     schemas,
 		ID:          group.ID.String(),
+		ExternalID:  group.ExternalID.String,
 		DisplayName: group.DisplayName,
 		Members:     members,
-		Meta: map[string]string{
-			"resourceType": "Group",
-			"created":      group.CreatedAt.Format(time.RFC3339),
-			"lastModified": group.UpdatedAt.Format(time.RFC3339),
-			"location":     fmt.Sprintf("%s/scim/v2/Groups/%s", bridge.BaseURL, group.ID.String()),
-		},
+		Meta:        meta,
+		Extensions:  extensions,
 	}
 }
 
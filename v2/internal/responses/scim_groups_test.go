@@ -0,0 +1,96 @@
+package responses
+
+import (
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/bridge"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/database"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/schema"
+)
+
+func TestNewScimGroupResponse(t *testing.T) {
+	b := bridge.New(nil, "https://example.com")
+	createdAt := time.Date(2022, 11, 1, 10, 0, 0, 0, time.UTC)
+
+	group := database.Group{
+		ID:          uuid.MustParse("e9e30dba-f08f-4109-8486-d5c6a331660a"),
+		DisplayName: "Tour Guides",
+		CreatedAt:   createdAt,
+		UpdatedAt:   createdAt,
+	}
+	members := []database.GroupMembership{
+		{
+			GroupID:  group.ID,
+			MemberID: uuid.MustParse("2819c223-7f76-453a-919d-413861904646"),
+			Type:     database.UserMember,
+			Display:  sql.NullString{String: "bjensen@example.com", Valid: true},
+		},
+		{
+			GroupID:  group.ID,
+			MemberID: uuid.MustParse("fc348aa8-3835-40eb-a20b-c726e15c55b5"),
+			Type:     database.GroupMember,
+			Display:  sql.NullString{String: "Night Tours", Valid: true},
+		},
+	}
+
+	got := NewScimGroupResponse(&b, group, members)
+
+	assert.Equal(t, []map[string]string{
+		{
+			"value":   "2819c223-7f76-453a-919d-413861904646",
+			"$ref":    "https://example.com/scim/v2/Users/2819c223-7f76-453a-919d-413861904646",
+			"type":    "User",
+			"display": "bjensen@example.com",
+		},
+		{
+			"value":   "fc348aa8-3835-40eb-a20b-c726e15c55b5",
+			"$ref":    "https://example.com/scim/v2/Groups/fc348aa8-3835-40eb-a20b-c726e15c55b5",
+			"type":    "Group",
+			"display": "Night Tours",
+		},
+	}, got.Members)
+}
+
+func TestNewScimGroupResponseExtensions(t *testing.T) {
+	b := bridge.New(nil, "https://example.com")
+	err := b.RegisterExtension("Group", schema.Schema{
+		ID: "urn:suse:skyscraper:2.0:Group",
+		Attributes: []schema.Attribute{
+			{Name: "costCenter", Type: schema.String},
+			{Name: "routingKey", Type: schema.String, Mutability: schema.WriteOnly, Returned: schema.Never},
+		},
+	})
+	assert.NoError(t, err)
+
+	createdAt := time.Date(2022, 11, 1, 10, 0, 0, 0, time.UTC)
+	group := database.Group{
+		ID:          uuid.MustParse("e9e30dba-f08f-4109-8486-d5c6a331660a"),
+		DisplayName: "Tour Guides",
+		Extensions: database.Extensions{
+			"urn:suse:skyscraper:2.0:Group": {"costCenter": "4130", "routingKey": "secret"},
+		},
+		CreatedAt: createdAt,
+		UpdatedAt: createdAt,
+	}
+
+	data, err := json.Marshal(NewScimGroupResponse(&b, group, nil))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"schemas": ["urn:ietf:params:scim:schemas:core:2.0:Group", "urn:suse:skyscraper:2.0:Group"],
+		"id": "e9e30dba-f08f-4109-8486-d5c6a331660a",
+		"displayName": "Tour Guides",
+		"members": null,
+		"meta": {
+			"resourceType": "Group",
+			"created": "2022-11-01T10:00:00Z",
+			"lastModified": "2022-11-01T10:00:00Z",
+			"location": "https://example.com/scim/v2/Groups/e9e30dba-f08f-4109-8486-d5c6a331660a"
+		},
+		"urn:suse:skyscraper:2.0:Group": {"costCenter": "4130"}
+	}`, string(data))
+}
@@ -8,22 +8,57 @@ import (
 	openfga_scim_bridge "github.com/suse-skyscraper/openfga-scim-bridge/v2/bridge"
 	"github.com/suse-skyscraper/openfga-scim-bridge/v2/database"
 	"github.com/suse-skyscraper/openfga-scim-bridge/v2/payloads"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/schema"
 )
 
 type ScimUserResponse struct {
-	Schemas  []string             `json:"schemas,omitempty"`
-	UserName string               `json:"userName"`
-	ID       string               `json:"id"`
-	Name     map[string]string    `json:"name,omitempty"`
-	Emails   []payloads.UserEmail `json:"emails,omitempty"`
-	Active   bool                 `json:"active"`
-	Meta     map[string]string    `json:"meta"`
+	Schemas           []string                        `json:"schemas,omitempty"`
+	UserName          string                          `json:"userName"`
+	ID                string                          `json:"id"`
+	ExternalID        string                          `json:"externalId,omitempty"`
+	Name              map[string]string               `json:"name,omitempty"`
+	DisplayName       string                          `json:"displayName,omitempty"`
+	NickName          string                          `json:"nickName,omitempty"`
+	ProfileURL        string                          `json:"profileUrl,omitempty"`
+	Title             string                          `json:"title,omitempty"`
+	UserType          string                          `json:"userType,omitempty"`
+	PreferredLanguage string                          `json:"preferredLanguage,omitempty"`
+	Locale            string                          `json:"locale,omitempty"`
+	Timezone          string                          `json:"timezone,omitempty"`
+	Emails            []payloads.UserEmail            `json:"emails,omitempty"`
+	PhoneNumbers      []payloads.MultiValuedAttribute `json:"phoneNumbers,omitempty"`
+	Ims               []payloads.MultiValuedAttribute `json:"ims,omitempty"`
+	Photos            []payloads.MultiValuedAttribute `json:"photos,omitempty"`
+	Addresses         []payloads.UserAddress          `json:"addresses,omitempty"`
+	Groups            []UserGroupResponse             `json:"groups,omitempty"`
+	Entitlements      []payloads.MultiValuedAttribute `json:"entitlements,omitempty"`
+	Roles             []payloads.MultiValuedAttribute `json:"roles,omitempty"`
+	X509Certificates  []payloads.MultiValuedAttribute `json:"x509Certificates,omitempty"`
+	Active            bool                            `json:"active"`
+	Meta              map[string]string               `json:"meta"`
+
+	EnterpriseUser *payloads.EnterpriseUser `json:"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User,omitempty"`
+	// Extensions are the custom extensions of the user, they are encoded below their schema URI.
+	Extensions database.Extensions `json:"-"`
+}
+
+type UserGroupResponse struct {
+	Value   string `json:"value"`
+	Ref     string `json:"$ref"`
+	Display string `json:"display,omitempty"`
+	Type    string `json:"type"`
 }
 
 func (rd *ScimUserResponse) Render(_ http.ResponseWriter, _ *http.Request) error {
 	return nil
 }
 
+func (rd *ScimUserResponse) MarshalJSON() ([]byte, error) {
+	// the alias has no MarshalJSON method, so that the fields are encoded as usual
+	type response ScimUserResponse
+	return marshalWithExtensions((*response)(rd), rd.Extensions)
+}
+
 type ScimListUsersResponse struct {
 	Schemas      []string            `json:"schemas"`
 	ItemsPerPage int                 `json:"itemsPerPage"`
@@ -36,30 +71,82 @@ func (rd *ScimListUsersResponse) Render(_ http.ResponseWriter, _ *http.Request)
 	return nil
 }
 
-func NewScimUserResponse(bridge *openfga_scim_bridge.Bridge, user database.User) *ScimUserResponse {
-	return newScimUserResponse(bridge, user, true)
+// NewScimUserResponse renders a single user. groups are the groups of the user, as returned by
+// GetUserGroups.
+func NewScimUserResponse(bridge *openfga_scim_bridge.Bridge, user database.User, groups []database.UserGroup) *ScimUserResponse {
+	response := newScimUserResponse(bridge, user, true)
+	for _, group := range groups {
+		response.Groups = append(response.Groups, UserGroupResponse{
+			Value:   group.GroupID.String(),
+			Ref:     fmt.Sprintf("%s/scim/v2/Groups/%s", bridge.BaseURL, group.GroupID),
+			Display: group.DisplayName,
+			Type:    group.Type,
+		})
+	}
+
+	return response
 }
 
 func newScimUserResponse(bridge *openfga_scim_bridge.Bridge, user database.User, singleResponse bool) *ScimUserResponse {
+	extensions, extensionSchemas := customExtensions(bridge, "User", user.Extensions)
+
 	// the schemas should be added if the response is a single user, not a list
 	var schemas []string
 	if singleResponse {
-		schemas = []string{"urn:ietf:params:scim:schemas:core:2.0:User"}
+		schemas = []string{schema.UserURN}
+		if user.EnterpriseUser != nil {
+			schemas = append(schemas, schema.EnterpriseUserURN)
+		}
+		schemas = append(schemas, extensionSchemas...)
+	}
+
+	enterpriseUser := user.EnterpriseUser
+	if enterpriseUser != nil && enterpriseUser.Manager != nil && enterpriseUser.Manager.Value != "" {
+		// the reference to the manager is derived from its id rather than stored
+		manager := *enterpriseUser.Manager
+		manager.Ref = fmt.Sprintf("%s/scim/v2/Users/%s", bridge.BaseURL, manager.Value)
+
+		copied := *enterpriseUser
+		copied.Manager = &manager
+		enterpriseUser = &copied
+	}
+
+	meta := map[string]string{
+		"resourceType": "User",
+		"created":      user.CreatedAt.Format(time.RFC3339),
+		"lastModified": user.UpdatedAt.Format(time.RFC3339),
+		"location":     fmt.Sprintf("%s/scim/v2/Users/%s", bridge.BaseURL, user.ID),
+	}
+	if user.Version != "" {
+		meta["version"] = ETag(user.Version)
 	}
 
 	return &ScimUserResponse{
-		Schemas:  schemas,
-		ID:       user.ID.String(),
-		UserName: user.Username,
-		Name:     user.Name,
-		Emails:   user.Emails,
-		Active:   user.Active,
-		Meta: map[string]string{
-			"resourceType": "User",
-			"created":      user.CreatedAt.Format(time.RFC3339),
-			"lastModified": user.UpdatedAt.Format(time.RFC3339),
-			"location":     fmt.Sprintf("%s/scim/v2/Users/%s", bridge.BaseURL, user.ID),
-		},
+		Schemas:           schemas,
+		ID:                user.ID.String(),
+		UserName:          user.Username,
+		ExternalID:        user.ExternalID.String,
+		Name:              user.Name,
+		DisplayName:       user.DisplayName.String,
+		NickName:          user.NickName.String,
+		ProfileURL:        user.ProfileURL.String,
+		Title:             user.Title.String,
+		UserType:          user.UserType.String,
+		PreferredLanguage: user.PreferredLanguage.String,
+		Locale:            user.Locale.String,
+		Timezone:          user.Timezone.String,
+		Emails:            user.Emails,
+		PhoneNumbers:      user.PhoneNumbers,
+		Ims:               user.Ims,
+		Photos:            user.Photos,
+		Addresses:         user.Addresses,
+		Entitlements:      user.Entitlements,
+		Roles:             user.Roles,
+		X509Certificates:  user.X509Certificates,
+		Active:            user.Active,
+		EnterpriseUser:    enterpriseUser,
+		Extensions:        extensions,
+		Meta:              meta,
 	}
 }
 
@@ -0,0 +1,104 @@
+package responses
+
+import (
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/bridge"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/database"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/payloads"
+)
+
+func TestNewScimUserResponse(t *testing.T) {
+	b := bridge.New(nil, "https://example.com")
+	createdAt := time.Date(2022, 11, 1, 10, 0, 0, 0, time.UTC)
+
+	user := database.User{
+		ID:                uuid.MustParse("2819c223-7f76-453a-919d-413861904646"),
+		Username:          "bjensen@example.com",
+		ExternalID:        sql.NullString{String: "701984", Valid: true},
+		Name:              map[string]string{"givenName": "Barbara", "familyName": "Jensen"},
+		DisplayName:       sql.NullString{String: "Babs Jensen", Valid: true},
+		NickName:          sql.NullString{String: "Babs", Valid: true},
+		ProfileURL:        sql.NullString{String: "https://login.example.com/bjensen", Valid: true},
+		Title:             sql.NullString{String: "Tour Guide", Valid: true},
+		UserType:          sql.NullString{String: "Employee", Valid: true},
+		PreferredLanguage: sql.NullString{String: "en-US", Valid: true},
+		Locale:            sql.NullString{String: "en-US", Valid: true},
+		Timezone:          sql.NullString{String: "America/Los_Angeles", Valid: true},
+		Active:            true,
+		Emails:            []payloads.UserEmail{{Value: "bjensen@example.com", Display: "Babs Jensen", Type: "work", Primary: true}, {Value: "babs@example.org"}},
+		PhoneNumbers:      []payloads.MultiValuedAttribute{{Value: "555-555-5555", Type: "work"}},
+		Ims:               []payloads.MultiValuedAttribute{{Value: "someaimhandle", Type: "aim"}},
+		Photos:            []payloads.MultiValuedAttribute{{Value: "https://photos.example.com/profilephoto.jpg", Type: "photo"}},
+		Addresses:         []payloads.UserAddress{{StreetAddress: "100 Universal City Plaza", Locality: "Hollywood", Type: "work", Primary: true}},
+		Entitlements:      []payloads.MultiValuedAttribute{{Value: "admin"}},
+		Roles:             []payloads.MultiValuedAttribute{{Value: "guide"}},
+		X509Certificates:  []payloads.MultiValuedAttribute{{Value: "MIIDQzCCAqygAwIBAgICEAAwDQYJKoZIhvcNAQEFBQAwTjELMAkGA1UEBhMCVVMx"}},
+		EnterpriseUser: &payloads.EnterpriseUser{
+			EmployeeNumber: "701984",
+			Department:     "Tour Operations",
+			Manager:        &payloads.EnterpriseUserManager{Value: "26118915-6090-4610-87e4-49d8ca9f808d"},
+		},
+		CreatedAt: createdAt,
+		UpdatedAt: createdAt,
+		Version:   "1667296800000000",
+	}
+
+	data, err := json.Marshal(NewScimUserResponse(&b, user, []database.UserGroup{
+		{GroupID: uuid.MustParse("e9e30dba-f08f-4109-8486-d5c6a331660a"), DisplayName: "Tour Guides", Type: database.DirectMembership},
+	}))
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{
+		"schemas": [
+			"urn:ietf:params:scim:schemas:core:2.0:User",
+			"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"
+		],
+		"id": "2819c223-7f76-453a-919d-413861904646",
+		"externalId": "701984",
+		"userName": "bjensen@example.com",
+		"name": {"givenName": "Barbara", "familyName": "Jensen"},
+		"displayName": "Babs Jensen",
+		"nickName": "Babs",
+		"profileUrl": "https://login.example.com/bjensen",
+		"title": "Tour Guide",
+		"userType": "Employee",
+		"preferredLanguage": "en-US",
+		"locale": "en-US",
+		"timezone": "America/Los_Angeles",
+		"active": true,
+		"emails": [{"value": "bjensen@example.com", "display": "Babs Jensen", "type": "work", "primary": true}, {"value": "babs@example.org"}],
+		"phoneNumbers": [{"value": "555-555-5555", "type": "work"}],
+		"ims": [{"value": "someaimhandle", "type": "aim"}],
+		"photos": [{"value": "https://photos.example.com/profilephoto.jpg", "type": "photo"}],
+		"addresses": [{"streetAddress": "100 Universal City Plaza", "locality": "Hollywood", "type": "work", "primary": true}],
+		"entitlements": [{"value": "admin"}],
+		"groups": [{
+			"value": "e9e30dba-f08f-4109-8486-d5c6a331660a",
+			"$ref": "https://example.com/scim/v2/Groups/e9e30dba-f08f-4109-8486-d5c6a331660a",
+			"display": "Tour Guides",
+			"type": "direct"
+		}],
+		"roles": [{"value": "guide"}],
+		"x509Certificates": [{"value": "MIIDQzCCAqygAwIBAgICEAAwDQYJKoZIhvcNAQEFBQAwTjELMAkGA1UEBhMCVVMx"}],
+		"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": {
+			"employeeNumber": "701984",
+			"department": "Tour Operations",
+			"manager": {
+				"value": "26118915-6090-4610-87e4-49d8ca9f808d",
+				"$ref": "https://example.com/scim/v2/Users/26118915-6090-4610-87e4-49d8ca9f808d"
+			}
+		},
+		"meta": {
+			"resourceType": "User",
+			"created": "2022-11-01T10:00:00Z",
+			"lastModified": "2022-11-01T10:00:00Z",
+			"location": "https://example.com/scim/v2/Users/2819c223-7f76-453a-919d-413861904646",
+			"version": "W/\"1667296800000000\""
+		}
+	}`, string(data))
+}
@@ -0,0 +1,52 @@
+package responses
+
+import (
+	"net/http"
+
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/bridge"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/database"
+)
+
+// ScimSearchResponse is the list response of a search across resource types. Its resources are users
+// and groups.
+type ScimSearchResponse struct {
+	Schemas      []string      `json:"schemas"`
+	ItemsPerPage int           `json:"itemsPerPage"`
+	StartIndex   int           `json:"startIndex"`
+	TotalResults int           `json:"totalResults"`
+	Resources    []interface{} `json:"Resources"`
+}
+
+func (rd *ScimSearchResponse) Render(_ http.ResponseWriter, _ *http.Request) error {
+	return nil
+}
+
+type ScimSearchResponseInput struct {
+	TotalResults int
+	StartIndex   int
+}
+
+// NewScimSearchResponse lists the users followed by the groups. Unlike the list responses of a single
+// resource type, each resource carries its schemas so clients can tell them apart.
+func NewScimSearchResponse(
+	bridge *bridge.Bridge,
+	users []database.User,
+	groups []database.Group,
+	input ScimSearchResponseInput,
+) *ScimSearchResponse {
+	list := make([]interface{}, 0, len(users)+len(groups))
+	for _, user := range users {
+		list = append(list, newScimUserResponse(bridge, user, true))
+	}
+	for _, group := range groups {
+		list = append(list, newScimGroupResponse(bridge, group, []map[string]string{}, true))
+	}
+
+	return &ScimSearchResponse{
+		Schemas:      []string{"urn:ietf:params:scim:api:messages:2.0:ListResponse"},
+		Resources:    list,
+		TotalResults: input.TotalResults,
+		StartIndex:   input.StartIndex,
+		ItemsPerPage: len(list),
+	}
+}
@@ -0,0 +1,91 @@
+package responses
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/bridge"
+)
+
+type Supported struct {
+	Supported bool `json:"supported"`
+}
+
+type BulkSupported struct {
+	Supported      bool `json:"supported"`
+	MaxOperations  int  `json:"maxOperations"`
+	MaxPayloadSize int  `json:"maxPayloadSize"`
+}
+
+type FilterSupported struct {
+	Supported  bool `json:"supported"`
+	MaxResults int  `json:"maxResults"`
+}
+
+type AuthenticationSchemeResponse struct {
+	Type             string `json:"type"`
+	Name             string `json:"name"`
+	Description      string `json:"description"`
+	SpecURI          string `json:"specUri,omitempty"`
+	DocumentationURI string `json:"documentationUri,omitempty"`
+	Primary          bool   `json:"primary,omitempty"`
+}
+
+type ServiceProviderConfigResponse struct {
+	Schemas               []string                       `json:"schemas"`
+	Patch                 Supported                      `json:"patch"`
+	Bulk                  BulkSupported                  `json:"bulk"`
+	Filter                FilterSupported                `json:"filter"`
+	ChangePassword        Supported                      `json:"changePassword"`
+	Sort                  Supported                      `json:"sort"`
+	Etag                  Supported                      `json:"etag"`
+	AuthenticationSchemes []AuthenticationSchemeResponse `json:"authenticationSchemes"`
+	Meta                  map[string]string              `json:"meta"`
+}
+
+func (rd *ServiceProviderConfigResponse) Render(_ http.ResponseWriter, _ *http.Request) error {
+	return nil
+}
+
+// NewServiceProviderConfigResponse describes the capabilities of the bridge, derived from its
+// configuration so that the advertised features always match the behaviour of the server.
+func NewServiceProviderConfigResponse(bridge *bridge.Bridge) *ServiceProviderConfigResponse {
+	schemes := make([]AuthenticationSchemeResponse, 0, len(bridge.AuthenticationSchemes))
+	for _, scheme := range bridge.AuthenticationSchemes {
+		schemes = append(schemes, AuthenticationSchemeResponse{
+			Type:             scheme.Type,
+			Name:             scheme.Name,
+			Description:      scheme.Description,
+			SpecURI:          scheme.SpecURI,
+			DocumentationURI: scheme.DocumentationURI,
+			Primary:          scheme.Primary,
+		})
+	}
+
+	maxOperations, maxPayloadSize := bridge.BulkLimits()
+	_, maxPageSize := bridge.PageLimits()
+
+	return &ServiceProviderConfigResponse{
+		Schemas: []string{"urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"},
+		// users and groups can always be patched
+		Patch: Supported{Supported: true},
+		Bulk: BulkSupported{
+			Supported:      true,
+			MaxOperations:  maxOperations,
+			MaxPayloadSize: maxPayloadSize,
+		},
+		Filter: FilterSupported{
+			Supported:  true,
+			MaxResults: maxPageSize,
+		},
+		// passwords are not managed by the bridge
+		ChangePassword:        Supported{Supported: false},
+		Sort:                  Supported{Supported: bridge.SortSupported},
+		Etag:                  Supported{Supported: bridge.ETagSupported},
+		AuthenticationSchemes: schemes,
+		Meta: map[string]string{
+			"resourceType": "ServiceProviderConfig",
+			"location":     fmt.Sprintf("%s/scim/v2/ServiceProviderConfig", bridge.BaseURL),
+		},
+	}
+}
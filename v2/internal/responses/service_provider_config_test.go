@@ -0,0 +1,40 @@
+package responses
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/bridge"
+)
+
+func TestNewServiceProviderConfigResponse(t *testing.T) {
+	b := bridge.New(nil, "https://example.com")
+	b.MaxPageSize = 200
+	b.SortSupported = true
+	b.ETagSupported = true
+	b.AuthenticationSchemes = []bridge.AuthenticationScheme{
+		{Type: "oauthbearertoken", Name: "OAuth Bearer Token", Primary: true},
+	}
+
+	got := NewServiceProviderConfigResponse(&b)
+
+	assert.True(t, got.Patch.Supported)
+	assert.False(t, got.ChangePassword.Supported)
+	assert.True(t, got.Etag.Supported)
+	assert.Equal(t, BulkSupported{Supported: true, MaxOperations: 1000, MaxPayloadSize: 1048576}, got.Bulk)
+	assert.Equal(t, FilterSupported{Supported: true, MaxResults: 200}, got.Filter)
+	assert.True(t, got.Sort.Supported)
+	assert.Equal(t, []AuthenticationSchemeResponse{
+		{Type: "oauthbearertoken", Name: "OAuth Bearer Token", Primary: true},
+	}, got.AuthenticationSchemes)
+	assert.Equal(t, "https://example.com/scim/v2/ServiceProviderConfig", got.Meta["location"])
+}
+
+func TestNewServiceProviderConfigResponseDefaults(t *testing.T) {
+	b := bridge.New(nil, "https://example.com")
+
+	got := NewServiceProviderConfigResponse(&b)
+
+	assert.False(t, got.Etag.Supported)
+	assert.False(t, got.Sort.Supported)
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/database"
+	responses2 "github.com/suse-skyscraper/openfga-scim-bridge/v2/internal/responses"
+)
+
+// setETag sets the ETag header of a response returning the resource at the version.
+func setETag(w http.ResponseWriter, version string) {
+	if version != "" {
+		w.Header().Set("ETag", responses2.ETag(version))
+	}
+}
+
+// notModified reports whether the If-None-Match header of a request lists the version, in which case
+// the resource is not returned again.
+func notModified(r *http.Request, version string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" || version == "" {
+		return false
+	}
+
+	return matchesETag(header, version)
+}
+
+// ifMatch returns the version a request is conditioned on by its If-Match header, or "" if the
+// request is unconditional. It returns database.ErrPreconditionFailed if none of the entity tags
+// matches the current version of the resource. The header is ignored for resources without a
+// version, which are not versioned by the database.
+func ifMatch(r *http.Request, version string) (string, error) {
+	header := r.Header.Get("If-Match")
+	if header == "" || version == "" || strings.TrimSpace(header) == "*" {
+		return "", nil
+	}
+
+	if !matchesETag(header, version) {
+		return "", database.ErrPreconditionFailed
+	}
+
+	return version, nil
+}
+
+// matchesETag reports whether the list of entity tags of an If-Match or If-None-Match header contains
+// the tag of the version. Tags are compared weakly, ignoring the W/ prefix.
+func matchesETag(header string, version string) bool {
+	tag := strings.TrimPrefix(responses2.ETag(version), "W/")
+
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == tag {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/database"
+)
+
+func TestMatchesETag(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   bool
+	}{
+		{name: "weak tag", header: `W/"3"`, want: true},
+		{name: "strong tag", header: `"3"`, want: true},
+		{name: "list of tags", header: `W/"1", W/"3"`, want: true},
+		{name: "any tag", header: "*", want: true},
+		{name: "other version", header: `W/"2"`, want: false},
+		{name: "unquoted tag", header: "3", want: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, matchesETag(tc.header, "3"))
+		})
+	}
+}
+
+func TestIfMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		version string
+		want    string
+		wantErr error
+	}{
+		{name: "unconditional", header: "", version: "3", want: ""},
+		{name: "any version", header: "*", version: "3", want: ""},
+		{name: "current version", header: `W/"3"`, version: "3", want: "3"},
+		{name: "stale version", header: `W/"2"`, version: "3", wantErr: database.ErrPreconditionFailed},
+		{name: "unversioned resource", header: `W/"2"`, version: "", want: ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPut, "/scim/v2/Users/2819c223-7f76-453a-919d-413861904646", nil)
+			if tc.header != "" {
+				r.Header.Set("If-Match", tc.header)
+			}
+
+			got, err := ifMatch(r, tc.version)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestNotModified(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/scim/v2/Users/2819c223-7f76-453a-919d-413861904646", nil)
+	assert.False(t, notModified(r, "3"))
+
+	r.Header.Set("If-None-Match", `W/"3"`)
+	assert.True(t, notModified(r, "3"))
+	assert.False(t, notModified(r, "4"))
+}
@@ -0,0 +1,335 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/database"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/filters"
+)
+
+// fakeBridge is an in-memory database.Bridge. Like the example database, it rejects members that
+// don't exist and groups that would become members of themselves. The methods the tests don't use
+// panic through the embedded nil interface.
+type fakeBridge struct {
+	database.Bridge
+
+	users   map[uuid.UUID]database.User
+	groups  map[uuid.UUID]database.Group
+	members map[uuid.UUID][]database.Member
+	version int
+
+	// usersQueries and groupsQueries record the parameters of GetUsers and GetGroups.
+	usersQueries  []database.GetUsersParams
+	groupsQueries []database.GetGroupsParams
+}
+
+func newFakeBridge() *fakeBridge {
+	return &fakeBridge{
+		users:   map[uuid.UUID]database.User{},
+		groups:  map[uuid.UUID]database.Group{},
+		members: map[uuid.UUID][]database.Member{},
+	}
+}
+
+func (f *fakeBridge) addUser(username string) database.User {
+	user := database.User{ID: uuid.New(), Username: username, Active: true, Version: f.nextVersion()}
+	f.users[user.ID] = user
+
+	return user
+}
+
+func (f *fakeBridge) addGroup(displayName string, members ...database.Member) database.Group {
+	group := database.Group{ID: uuid.New(), DisplayName: displayName, Version: f.nextVersion()}
+	f.groups[group.ID] = group
+	f.members[group.ID] = members
+
+	return group
+}
+
+func (f *fakeBridge) nextVersion() string {
+	f.version++
+	return strconv.Itoa(f.version)
+}
+
+// Transaction runs fn directly, the changes of failed transactions are not rolled back.
+func (f *fakeBridge) Transaction(_ context.Context, fn func(tx database.Bridge) error) error {
+	return fn(f)
+}
+
+func (f *fakeBridge) FindUser(_ context.Context, userID uuid.UUID) (database.User, error) {
+	user, ok := f.users[userID]
+	if !ok {
+		return database.User{}, database.ErrNotFound
+	}
+
+	return user, nil
+}
+
+func (f *fakeBridge) UpdateUser(_ context.Context, userID uuid.UUID, arg database.UserParams) (database.User, error) {
+	user, ok := f.users[userID]
+	if !ok {
+		return database.User{}, database.ErrNotFound
+	}
+
+	user = database.User{
+		ID:                user.ID,
+		Username:          arg.Username,
+		ExternalID:        nullString(arg.ExternalID),
+		Name:              arg.Name,
+		DisplayName:       nullString(arg.DisplayName),
+		NickName:          nullString(arg.NickName),
+		ProfileURL:        nullString(arg.ProfileURL),
+		Title:             nullString(arg.Title),
+		UserType:          nullString(arg.UserType),
+		PreferredLanguage: nullString(arg.PreferredLanguage),
+		Locale:            nullString(arg.Locale),
+		Timezone:          nullString(arg.Timezone),
+		Active:            arg.Active,
+		Emails:            arg.Emails,
+		PhoneNumbers:      arg.PhoneNumbers,
+		Ims:               arg.Ims,
+		Photos:            arg.Photos,
+		Addresses:         arg.Addresses,
+		Entitlements:      arg.Entitlements,
+		Roles:             arg.Roles,
+		X509Certificates:  arg.X509Certificates,
+		EnterpriseUser:    arg.EnterpriseUser,
+		Extensions:        arg.Extensions,
+		CreatedAt:         user.CreatedAt,
+		Version:           f.nextVersion(),
+	}
+	f.users[userID] = user
+
+	return user, nil
+}
+
+func (f *fakeBridge) CheckUserVersion(_ context.Context, userID uuid.UUID, version string) error {
+	if f.users[userID].Version != version {
+		return database.ErrPreconditionFailed
+	}
+
+	return nil
+}
+
+func (f *fakeBridge) GetUserGroups(_ context.Context, userID uuid.UUID, _ bool) ([]database.UserGroup, error) {
+	var groups []database.UserGroup
+	for groupID, members := range f.members {
+		for _, member := range members {
+			if member.ID == userID {
+				groups = append(groups, database.UserGroup{
+					GroupID:     groupID,
+					DisplayName: f.groups[groupID].DisplayName,
+					Type:        database.DirectMembership,
+				})
+			}
+		}
+	}
+
+	return groups, nil
+}
+
+// GetUsers returns the users matching the filter, ordered by userName.
+func (f *fakeBridge) GetUsers(_ context.Context, arg database.GetUsersParams) (int64, []database.User, error) {
+	f.usersQueries = append(f.usersQueries, arg)
+
+	var users []database.User
+	for _, user := range f.users {
+		ok, err := filters.Evaluate(arg.Filter, user)
+		if err != nil {
+			return 0, nil, err
+		} else if ok {
+			users = append(users, user)
+		}
+	}
+
+	sort.Slice(users, func(i, j int) bool { return users[i].Username < users[j].Username })
+	start, end := page(len(users), arg.Offset, arg.Limit)
+
+	return int64(len(users)), users[start:end], nil
+}
+
+// GetGroups returns the groups matching the filter, ordered by displayName.
+func (f *fakeBridge) GetGroups(_ context.Context, arg database.GetGroupsParams) (int64, []database.Group, error) {
+	f.groupsQueries = append(f.groupsQueries, arg)
+
+	var groups []database.Group
+	for _, group := range f.groups {
+		ok, err := filters.Evaluate(arg.Filter, group)
+		if err != nil {
+			return 0, nil, err
+		} else if ok {
+			groups = append(groups, group)
+		}
+	}
+
+	sort.Slice(groups, func(i, j int) bool { return groups[i].DisplayName < groups[j].DisplayName })
+	start, end := page(len(groups), arg.Offset, arg.Limit)
+
+	return int64(len(groups)), groups[start:end], nil
+}
+
+// page returns the bounds of the page of a list with the offset and limit.
+func page(length int, offset, limit int32) (int, int) {
+	start := int(offset)
+	if start > length {
+		start = length
+	}
+
+	end := start + int(limit)
+	if end > length {
+		end = length
+	}
+
+	return start, end
+}
+
+func (f *fakeBridge) FindGroup(_ context.Context, groupID uuid.UUID) (database.Group, error) {
+	group, ok := f.groups[groupID]
+	if !ok {
+		return database.Group{}, database.ErrNotFound
+	}
+
+	return group, nil
+}
+
+func (f *fakeBridge) CreateGroup(_ context.Context, arg database.GroupParams) (database.Group, error) {
+	group := database.Group{ID: uuid.New()}
+
+	err := f.checkMembers(group.ID, arg.Members)
+	if err != nil {
+		return database.Group{}, err
+	}
+
+	return f.saveGroup(group, arg), nil
+}
+
+func (f *fakeBridge) ReplaceGroup(_ context.Context, groupID uuid.UUID, arg database.GroupParams) (database.Group, error) {
+	group, ok := f.groups[groupID]
+	if !ok {
+		return database.Group{}, database.ErrNotFound
+	}
+
+	err := f.checkMembers(groupID, arg.Members)
+	if err != nil {
+		return database.Group{}, err
+	}
+
+	return f.saveGroup(group, arg), nil
+}
+
+func (f *fakeBridge) PatchGroup(_ context.Context, groupID uuid.UUID, arg database.GroupPatch) error {
+	group, ok := f.groups[groupID]
+	if !ok {
+		return database.ErrNotFound
+	}
+
+	err := f.checkMembers(groupID, arg.AddedMembers)
+	if err != nil {
+		return err
+	}
+
+	var members []database.Member
+	for _, member := range f.members[groupID] {
+		removed := false
+		for _, other := range arg.RemovedMembers {
+			removed = removed || member == other
+		}
+
+		if !removed {
+			members = append(members, member)
+		}
+	}
+
+	f.saveGroup(group, database.GroupParams{
+		DisplayName: arg.DisplayName,
+		ExternalID:  arg.ExternalID,
+		Extensions:  arg.Extensions,
+		Members:     append(members, arg.AddedMembers...),
+	})
+
+	return nil
+}
+
+func (f *fakeBridge) saveGroup(group database.Group, arg database.GroupParams) database.Group {
+	group.DisplayName = arg.DisplayName
+	group.ExternalID = nullString(arg.ExternalID)
+	group.Extensions = arg.Extensions
+	group.Version = f.nextVersion()
+
+	f.groups[group.ID] = group
+	f.members[group.ID] = arg.Members
+
+	return group
+}
+
+func (f *fakeBridge) CheckGroupVersion(_ context.Context, groupID uuid.UUID, version string) error {
+	if f.groups[groupID].Version != version {
+		return database.ErrPreconditionFailed
+	}
+
+	return nil
+}
+
+func (f *fakeBridge) GetGroupMembership(_ context.Context, groupID uuid.UUID) ([]database.GroupMembership, error) {
+	var membership []database.GroupMembership
+	for _, member := range f.members[groupID] {
+		display := f.users[member.ID].Username
+		if member.Type == database.GroupMember {
+			display = f.groups[member.ID].DisplayName
+		}
+
+		membership = append(membership, database.GroupMembership{
+			GroupID:  groupID,
+			MemberID: member.ID,
+			Type:     member.Type,
+			Display:  sql.NullString{String: display, Valid: true},
+		})
+	}
+
+	return membership, nil
+}
+
+// checkMembers returns an error if a member doesn't exist, or if a nested group contains the group.
+func (f *fakeBridge) checkMembers(groupID uuid.UUID, members []database.Member) error {
+	for _, member := range members {
+		if member.Type == database.UserMember {
+			if _, ok := f.users[member.ID]; !ok {
+				return &database.InvalidValueError{Attribute: "members", Detail: "a member does not exist"}
+			}
+			continue
+		}
+
+		if _, ok := f.groups[member.ID]; !ok {
+			return &database.InvalidValueError{Attribute: "members", Detail: "a member does not exist"}
+		}
+
+		if f.contains(member.ID, groupID) {
+			return database.ErrMembershipCycle
+		}
+	}
+
+	return nil
+}
+
+// contains reports whether the group is the other group, or contains it through nested groups.
+func (f *fakeBridge) contains(groupID, otherID uuid.UUID) bool {
+	if groupID == otherID {
+		return true
+	}
+
+	for _, member := range f.members[groupID] {
+		if member.Type == database.GroupMember && f.contains(member.ID, otherID) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func nullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
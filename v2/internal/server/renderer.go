@@ -4,6 +4,9 @@ import (
 	"bytes"
 	"encoding/json"
 	"net/http"
+
+	"github.com/go-chi/render"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/internal/responses"
 )
 
 func RenderScimJSON(w http.ResponseWriter, _ *http.Request, status int, v interface{}) {
@@ -22,3 +25,15 @@ func RenderScimJSON(w http.ResponseWriter, _ *http.Request, status int, v interf
 	w.WriteHeader(status)
 	_, _ = w.Write(buf.Bytes())
 }
+
+// RenderScimResource renders a resource, or a list of resources, reduced to the attributes selected
+// by the projection.
+func RenderScimResource(w http.ResponseWriter, r *http.Request, status int, projection responses.Projection, v interface{}) {
+	projected, err := projection.Apply(v)
+	if err != nil {
+		_ = render.Render(w, r, responses.ErrInternalServerError)
+		return
+	}
+
+	RenderScimJSON(w, r, status, projected)
+}
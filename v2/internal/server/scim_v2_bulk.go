@@ -0,0 +1,385 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/pkg/errors"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/bridge"
+	responses2 "github.com/suse-skyscraper/openfga-scim-bridge/v2/internal/responses"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/payloads"
+)
+
+// bulkIDReference matches references to the resources created by other operations of a bulk request,
+// e.g. "bulkId:qwerty".
+var bulkIDReference = regexp.MustCompile(`bulkId:([^"/?\s]+)`)
+
+// V2Bulk processes a bulk request by dispatching each of its operations to resources, the handler of
+// the resource endpoints.
+func V2Bulk(bridge *bridge.Bridge, resources http.Handler) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		maxOperations, maxPayloadSize := bridge.BulkLimits()
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, int64(maxPayloadSize)+1))
+		if err != nil {
+			_ = render.Render(w, r, responses2.ErrInvalidSyntax(err))
+			return
+		} else if len(body) > maxPayloadSize {
+			_ = render.Render(w, r, responses2.ErrPayloadTooLarge(
+				errors.Errorf("The size of the bulk operation exceeds the maxPayloadSize (%d)", maxPayloadSize)))
+			return
+		}
+
+		payload, err := payloads.BulkPayloadFromJSON(bytes.NewReader(body))
+		if err != nil {
+			_ = render.Render(w, r, responses2.ErrInvalidSyntax(err))
+			return
+		}
+
+		if len(payload.Operations) > maxOperations {
+			_ = render.Render(w, r, responses2.ErrTooManyOperations(
+				errors.Errorf("The number of operations exceeds the maxOperations (%d)", maxOperations)))
+			return
+		}
+
+		results := newBulkProcessor(bridge, resources, payload).process(r)
+
+		RenderScimJSON(w, r, http.StatusOK, responses2.NewBulkResponse(results))
+	}
+}
+
+type bulkProcessor struct {
+	bridge     *bridge.Bridge
+	resources  http.Handler
+	operations []payloads.BulkOperation
+	// failOnErrors is the number of errors after which the remaining operations are skipped, or 0
+	// to process every operation.
+	failOnErrors int
+	errors       int
+
+	// ids maps the bulkId of each successful POST operation to the id of the created resource.
+	ids map[string]string
+	// declared lists the bulkId of every POST operation, failed maps those that failed.
+	declared map[string]bool
+	failed   map[string]bool
+}
+
+func newBulkProcessor(bridge *bridge.Bridge, resources http.Handler, payload *payloads.BulkPayload) *bulkProcessor {
+	p := &bulkProcessor{
+		bridge:       bridge,
+		resources:    resources,
+		operations:   payload.Operations,
+		failOnErrors: payload.FailOnErrors,
+		ids:          map[string]string{},
+		declared:     map[string]bool{},
+		failed:       map[string]bool{},
+	}
+
+	for _, operation := range payload.Operations {
+		if operation.BulkID != "" && strings.EqualFold(operation.Method, http.MethodPost) {
+			p.declared[operation.BulkID] = true
+		}
+	}
+
+	return p
+}
+
+// process runs the operations and returns their results in the order of the request. Operations
+// referencing a resource created by a later operation are postponed until it has been created.
+func (p *bulkProcessor) process(r *http.Request) []*responses2.BulkOperationResponse {
+	results := make([]*responses2.BulkOperationResponse, len(p.operations))
+
+	pending := make([]int, 0, len(p.operations))
+	for i := range p.operations {
+		pending = append(pending, i)
+	}
+
+	for len(pending) > 0 && !p.stopped() {
+		var postponed []int
+		for _, i := range pending {
+			if p.stopped() {
+				break
+			}
+
+			operation := p.operations[i]
+			path, data, unresolved := resolveBulkIDs(operation.Path, operation.Data, p.ids)
+			if unresolved != "" && p.declared[unresolved] && !p.failed[unresolved] {
+				postponed = append(postponed, i)
+				continue
+			}
+
+			if unresolved != "" {
+				results[i] = p.fail(operation, responses2.ErrBadValue(
+					errors.Errorf("The bulkId %q does not reference a resource created by this request", unresolved)))
+				continue
+			}
+
+			results[i] = p.run(r, operation, path, data)
+		}
+
+		// the remaining operations reference each other, they can't be resolved
+		if len(postponed) == len(pending) {
+			for _, i := range postponed {
+				results[i] = p.fail(p.operations[i], responses2.ErrBadValue(
+					errors.New("The operation is part of a circular bulkId reference")))
+			}
+			break
+		}
+
+		pending = postponed
+	}
+
+	// operations skipped because of failOnErrors are not reported
+	processed := make([]*responses2.BulkOperationResponse, 0, len(results))
+	for _, result := range results {
+		if result != nil {
+			processed = append(processed, result)
+		}
+	}
+
+	return processed
+}
+
+func (p *bulkProcessor) stopped() bool {
+	return p.failOnErrors > 0 && p.errors >= p.failOnErrors
+}
+
+// run dispatches the operation to the resource endpoints.
+func (p *bulkProcessor) run(r *http.Request, operation payloads.BulkOperation, path string, data []byte) *responses2.BulkOperationResponse {
+	method := strings.ToUpper(operation.Method)
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+	default:
+		return p.fail(operation, responses2.ErrBadValue(
+			errors.Errorf("Unsupported method %q", operation.Method)))
+	}
+
+	if method == http.MethodPost && operation.BulkID == "" {
+		return p.fail(operation, responses2.ErrBadValue(
+			errors.New("POST operations require a bulkId")))
+	}
+
+	// the chi routing context of the bulk request must not leak into the dispatched request
+	ctx := context.WithValue(r.Context(), chi.RouteCtxKey, nil)
+	request, err := http.NewRequestWithContext(ctx, method, path, bytes.NewReader(data))
+	if err != nil {
+		return p.fail(operation, responses2.ErrBadValue(err))
+	}
+	request.Header.Set("Content-Type", "application/scim+json")
+	if operation.Version != "" {
+		request.Header.Set("If-Match", operation.Version)
+	}
+
+	recorder := newResponseRecorder()
+	p.resources.ServeHTTP(recorder, request)
+
+	result := &responses2.BulkOperationResponse{
+		Method:  method,
+		BulkID:  operation.BulkID,
+		Version: recorder.header.Get("ETag"),
+		Status:  strconv.Itoa(recorder.status),
+	}
+
+	if recorder.status >= http.StatusBadRequest {
+		p.errors++
+		if operation.BulkID != "" {
+			p.failed[operation.BulkID] = true
+		}
+
+		result.Response = recorder.body.Bytes()
+		return result
+	}
+
+	result.Location = fmt.Sprintf("%s/scim/v2%s", p.bridge.BaseURL, path)
+	if method == http.MethodPost {
+		var created struct {
+			ID string `json:"id"`
+		}
+		_ = json.Unmarshal(recorder.body.Bytes(), &created)
+
+		p.ids[operation.BulkID] = created.ID
+		result.Location = fmt.Sprintf("%s/%s", result.Location, created.ID)
+	}
+
+	return result
+}
+
+// fail reports an operation that could not be dispatched.
+func (p *bulkProcessor) fail(operation payloads.BulkOperation, err render.Renderer) *responses2.BulkOperationResponse {
+	p.errors++
+	if operation.BulkID != "" {
+		p.failed[operation.BulkID] = true
+	}
+
+	status := http.StatusBadRequest
+	if errResponse, ok := err.(*responses2.ErrResponse); ok {
+		status = errResponse.HTTPStatusCode
+	}
+
+	response, _ := json.Marshal(err)
+
+	return &responses2.BulkOperationResponse{
+		Method:   strings.ToUpper(operation.Method),
+		BulkID:   operation.BulkID,
+		Status:   strconv.Itoa(status),
+		Response: response,
+	}
+}
+
+// resolveBulkIDs replaces the bulkId references of an operation with the ids of the created
+// resources. It returns the first bulkId that can't be resolved yet, if any. References are resolved
+// in the path of the operation, in the value and $ref of group members, and in the paths of PATCH
+// operations, e.g. members[value eq "bulkId:qwerty"]. Other attributes are left as they are, even if
+// their value looks like a reference. Data that is not a JSON object is passed on unchanged, for the
+// resource endpoint to reject it.
+func resolveBulkIDs(path string, data []byte, ids map[string]string) (string, []byte, string) {
+	resolver := bulkIDResolver{ids: ids}
+	path = resolver.resolve(path)
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var resource map[string]interface{}
+	if err := decoder.Decode(&resource); err != nil {
+		return path, data, resolver.unresolved
+	}
+
+	resolver.resolveResource(resource)
+	operations, _ := lookupValue(resource, "Operations").([]interface{})
+	for _, element := range operations {
+		operation, ok := element.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		key, operationPath := lookupString(operation, "path")
+		if key == "" {
+			if values, ok := lookupValue(operation, "value").(map[string]interface{}); ok {
+				resolver.resolveResource(values)
+			}
+			continue
+		}
+
+		operation[key] = resolver.resolve(operationPath)
+		if strings.HasPrefix(strings.ToLower(operationPath), "members") {
+			resolver.resolveMembers(lookupValue(operation, "value"))
+		}
+	}
+
+	if resolver.replaced {
+		if resolved, err := json.Marshal(resource); err == nil {
+			data = resolved
+		}
+	}
+
+	return path, data, resolver.unresolved
+}
+
+// bulkIDResolver replaces bulkId references with the ids of the created resources.
+type bulkIDResolver struct {
+	ids map[string]string
+	// unresolved is the first bulkId without an id, replaced reports whether a reference was resolved.
+	unresolved string
+	replaced   bool
+}
+
+func (b *bulkIDResolver) resolve(text string) string {
+	return bulkIDReference.ReplaceAllStringFunc(text, func(reference string) string {
+		bulkID := bulkIDReference.FindStringSubmatch(reference)[1]
+		id, ok := b.ids[bulkID]
+		if !ok {
+			if b.unresolved == "" {
+				b.unresolved = bulkID
+			}
+			return reference
+		}
+
+		b.replaced = true
+		return id
+	})
+}
+
+// resolveResource resolves the references of the members of a resource.
+func (b *bulkIDResolver) resolveResource(resource map[string]interface{}) {
+	b.resolveMembers(lookupValue(resource, "members"))
+}
+
+// resolveMembers resolves the value and $ref of members, given as a list or as a single member.
+func (b *bulkIDResolver) resolveMembers(members interface{}) {
+	elements, ok := members.([]interface{})
+	if !ok {
+		elements = []interface{}{members}
+	}
+
+	for _, element := range elements {
+		member, ok := element.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		for _, name := range []string{"value", "$ref"} {
+			if key, value := lookupString(member, name); key != "" {
+				member[key] = b.resolve(value)
+			}
+		}
+	}
+}
+
+// lookupValue returns the value of a JSON attribute, ignoring the case of its name.
+func lookupValue(values map[string]interface{}, name string) interface{} {
+	for key, value := range values {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+
+	return nil
+}
+
+// lookupString returns the key and the value of a JSON attribute with a string value, ignoring the
+// case of its name. The key is empty if there is no such attribute.
+func lookupString(values map[string]interface{}, name string) (string, string) {
+	for key, value := range values {
+		if text, ok := value.(string); ok && strings.EqualFold(key, name) {
+			return key, text
+		}
+	}
+
+	return "", ""
+}
+
+// responseRecorder captures the response of an operation dispatched to the resource endpoints.
+type responseRecorder struct {
+	header http.Header
+	status int
+	body   *bytes.Buffer
+}
+
+func newResponseRecorder() *responseRecorder {
+	return &responseRecorder{
+		header: http.Header{},
+		status: http.StatusOK,
+		body:   &bytes.Buffer{},
+	}
+}
+
+func (rr *responseRecorder) Header() http.Header {
+	return rr.header
+}
+
+func (rr *responseRecorder) Write(data []byte) (int, error) {
+	return rr.body.Write(data)
+}
+
+func (rr *responseRecorder) WriteHeader(status int) {
+	rr.status = status
+}
@@ -0,0 +1,251 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/bridge"
+	responses2 "github.com/suse-skyscraper/openfga-scim-bridge/v2/internal/responses"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/payloads"
+)
+
+func TestResolveBulkIDs(t *testing.T) {
+	ids := map[string]string{"qwerty": "92b725cd-9465-4e7d-8c16-01f8e146b87a"}
+
+	tests := []struct {
+		name           string
+		path           string
+		data           string
+		wantPath       string
+		wantData       string
+		wantUnresolved string
+	}{
+		{
+			name:           "group members",
+			path:           "/Groups",
+			data:           `{"members": [{"value": "bulkId:qwerty"}, {"value": "bulkId:ytrewq"}]}`,
+			wantPath:       "/Groups",
+			wantData:       `{"members": [{"value": "92b725cd-9465-4e7d-8c16-01f8e146b87a"}, {"value": "bulkId:ytrewq"}]}`,
+			wantUnresolved: "ytrewq",
+		},
+		{
+			name:     "member reference",
+			path:     "/Groups",
+			data:     `{"displayName": "Tour Guides", "members": [{"$ref": "https://example.com/scim/v2/Users/bulkId:qwerty"}]}`,
+			wantPath: "/Groups",
+			wantData: `{"displayName": "Tour Guides", "members": [{"$ref": "https://example.com/scim/v2/Users/92b725cd-9465-4e7d-8c16-01f8e146b87a"}]}`,
+		},
+		{
+			name:     "resource path",
+			path:     "/Groups/bulkId:qwerty",
+			data:     `{}`,
+			wantPath: "/Groups/92b725cd-9465-4e7d-8c16-01f8e146b87a",
+			wantData: `{}`,
+		},
+		{
+			name: "PATCH operations",
+			path: "/Groups/bulkId:qwerty",
+			data: `{"Operations": [
+				{"op": "add", "path": "members", "value": [{"value": "bulkId:qwerty"}]},
+				{"op": "remove", "path": "members[value eq \"bulkId:qwerty\"]"},
+				{"op": "replace", "value": {"displayName": "bulkId:qwerty", "members": [{"value": "bulkId:qwerty"}]}}
+			]}`,
+			wantPath: "/Groups/92b725cd-9465-4e7d-8c16-01f8e146b87a",
+			wantData: `{"Operations": [
+				{"op": "add", "path": "members", "value": [{"value": "92b725cd-9465-4e7d-8c16-01f8e146b87a"}]},
+				{"op": "remove", "path": "members[value eq \"92b725cd-9465-4e7d-8c16-01f8e146b87a\"]"},
+				{"op": "replace", "value": {"displayName": "bulkId:qwerty", "members": [{"value": "92b725cd-9465-4e7d-8c16-01f8e146b87a"}]}}
+			]}`,
+		},
+		{
+			name:     "other attributes are not references",
+			path:     "/Users",
+			data:     `{"userName": "bulkId:ytrewq", "nickName": "bulkId:qwerty", "count": 12345678901234567890}`,
+			wantPath: "/Users",
+			wantData: `{"userName": "bulkId:ytrewq", "nickName": "bulkId:qwerty", "count": 12345678901234567890}`,
+		},
+		{
+			name:     "malformed data",
+			path:     "/Groups/bulkId:qwerty",
+			data:     `{"members": [`,
+			wantPath: "/Groups/92b725cd-9465-4e7d-8c16-01f8e146b87a",
+			wantData: `{"members": [`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path, data, unresolved := resolveBulkIDs(tc.path, []byte(tc.data), ids)
+
+			assert.Equal(t, tc.wantPath, path)
+			if json.Valid([]byte(tc.wantData)) {
+				assert.JSONEq(t, tc.wantData, string(data))
+			} else {
+				assert.Equal(t, tc.wantData, string(data))
+			}
+			assert.Equal(t, tc.wantUnresolved, unresolved)
+		})
+	}
+}
+
+// resourceStub creates resources with sequential ids, and fails requests whose body contains "fail".
+func resourceStub(requests *[]string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		*requests = append(*requests, fmt.Sprintf("%s %s %s", r.Method, r.URL.Path, body))
+
+		switch {
+		case strings.Contains(string(body), "fail"):
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"status": 400}`))
+		case r.Method == http.MethodPost:
+			w.WriteHeader(http.StatusCreated)
+			_, _ = fmt.Fprintf(w, `{"id": "id-%d"}`, len(*requests))
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	})
+}
+
+func TestBulkProcessor(t *testing.T) {
+	b := bridge.New(nil, "https://example.com")
+
+	tests := []struct {
+		name         string
+		failOnErrors int
+		operations   []payloads.BulkOperation
+		requests     []string
+		statuses     []string
+	}{
+		{
+			name: "resolve forward references",
+			operations: []payloads.BulkOperation{
+				{Method: "POST", Path: "/Groups", BulkID: "group", Data: []byte(`{"members": [{"value": "bulkId:user"}]}`)},
+				{Method: "POST", Path: "/Users", BulkID: "user", Data: []byte(`{"userName": "bjensen"}`)},
+				{Method: "PATCH", Path: "/Groups/bulkId:group", Data: []byte(`{}`)},
+			},
+			requests: []string{
+				`POST /Users {"userName": "bjensen"}`,
+				`POST /Groups {"members":[{"value":"id-1"}]}`,
+				`PATCH /Groups/id-2 {}`,
+			},
+			statuses: []string{"201", "201", "200"},
+		},
+		{
+			name: "reference to a failed operation",
+			operations: []payloads.BulkOperation{
+				{Method: "POST", Path: "/Users", BulkID: "user", Data: []byte(`{"userName": "fail"}`)},
+				{Method: "POST", Path: "/Groups", BulkID: "group", Data: []byte(`{"members": [{"value": "bulkId:user"}]}`)},
+			},
+			requests: []string{`POST /Users {"userName": "fail"}`},
+			statuses: []string{"400", "400"},
+		},
+		{
+			name: "circular references",
+			operations: []payloads.BulkOperation{
+				{Method: "POST", Path: "/Groups", BulkID: "a", Data: []byte(`{"members": [{"value": "bulkId:b"}]}`)},
+				{Method: "POST", Path: "/Groups", BulkID: "b", Data: []byte(`{"members": [{"value": "bulkId:a"}]}`)},
+			},
+			statuses: []string{"400", "400"},
+		},
+		{
+			name:         "stop after failOnErrors errors",
+			failOnErrors: 1,
+			operations: []payloads.BulkOperation{
+				{Method: "POST", Path: "/Users", BulkID: "a", Data: []byte(`{"userName": "fail"}`)},
+				{Method: "POST", Path: "/Users", BulkID: "b", Data: []byte(`{"userName": "bjensen"}`)},
+			},
+			requests: []string{`POST /Users {"userName": "fail"}`},
+			statuses: []string{"400"},
+		},
+		{
+			name: "POST without bulkId",
+			operations: []payloads.BulkOperation{
+				{Method: "POST", Path: "/Users", Data: []byte(`{"userName": "bjensen"}`)},
+			},
+			statuses: []string{"400"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var requests []string
+			payload := &payloads.BulkPayload{FailOnErrors: tc.failOnErrors, Operations: tc.operations}
+
+			results := newBulkProcessor(&b, resourceStub(&requests), payload).process(httptest.NewRequest(http.MethodPost, "/scim/v2/Bulk", nil))
+
+			assert.Equal(t, tc.requests, requests)
+
+			var statuses []string
+			for _, result := range results {
+				statuses = append(statuses, result.Status)
+			}
+			assert.Equal(t, tc.statuses, statuses)
+		})
+	}
+}
+
+func TestBulkProcessorLocation(t *testing.T) {
+	b := bridge.New(nil, "https://example.com")
+	var requests []string
+	payload := &payloads.BulkPayload{Operations: []payloads.BulkOperation{
+		{Method: "post", Path: "/Users", BulkID: "user", Data: []byte(`{"userName": "bjensen"}`)},
+	}}
+
+	results := newBulkProcessor(&b, resourceStub(&requests), payload).process(httptest.NewRequest(http.MethodPost, "/scim/v2/Bulk", nil))
+
+	assert.Equal(t, []*responses2.BulkOperationResponse{{
+		Location: "https://example.com/scim/v2/Users/id-1",
+		Method:   "POST",
+		BulkID:   "user",
+		Status:   "201",
+	}}, results)
+}
+
+func TestV2BulkLimits(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		wantScimType string
+	}{
+		{
+			name:         "too many operations",
+			body:         `{"Operations": [{"method": "DELETE", "path": "/Users/a"}, {"method": "DELETE", "path": "/Users/b"}]}`,
+			wantScimType: "tooMany",
+		},
+		{
+			name: "payload too large",
+			body: `{"Operations": [{"method": "DELETE", "path": "/Users/` + strings.Repeat("a", 100) + `"}]}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			b := bridge.New(nil, "https://example.com")
+			b.BulkMaxOperations = 1
+			b.BulkMaxPayloadSize = 100
+
+			var requests []string
+			r := httptest.NewRequest(http.MethodPost, "/scim/v2/Bulk", strings.NewReader(tc.body))
+			w := httptest.NewRecorder()
+			V2Bulk(&b, resourceStub(&requests))(w, r)
+
+			assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+			assert.Empty(t, requests)
+
+			var body map[string]interface{}
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			if tc.wantScimType != "" {
+				assert.Equal(t, tc.wantScimType, body["scimType"])
+			} else {
+				assert.NotContains(t, body, "scimType")
+			}
+		})
+	}
+}
@@ -1,116 +1,294 @@
 package server
 
 import (
+	"bytes"
 	"net/http"
+	"strings"
 
 	"github.com/go-chi/render"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
 	"github.com/suse-skyscraper/openfga-scim-bridge/v2/bridge"
 	"github.com/suse-skyscraper/openfga-scim-bridge/v2/database"
 	"github.com/suse-skyscraper/openfga-scim-bridge/v2/internal/middleware"
-	pagination2 "github.com/suse-skyscraper/openfga-scim-bridge/v2/internal/pagination"
 	responses2 "github.com/suse-skyscraper/openfga-scim-bridge/v2/internal/responses"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/internal/sorting"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/patch"
 	"github.com/suse-skyscraper/openfga-scim-bridge/v2/payloads"
 )
 
 func V2ListGroups(bridge *bridge.Bridge) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		pagination := pagination2.Paginate(r)
+		query, errResponse := queryFromURL(r, bridge, sorting.GroupAttributes)
+		if errResponse != nil {
+			_ = render.Render(w, r, errResponse)
+			return
+		}
 
-		totalCount, groups, err := bridge.DB.GetGroups(r.Context(), pagination.Limit, pagination.Offset)
-		if err != nil {
-			_ = render.Render(w, r, responses2.ErrInternalServerError)
+		listGroups(w, r, bridge, query)
+	}
+}
+
+func V2SearchGroups(bridge *bridge.Bridge) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query, errResponse := queryFromSearch(r, bridge, sorting.GroupAttributes)
+		if errResponse != nil {
+			_ = render.Render(w, r, errResponse)
 			return
 		}
 
-		RenderScimJSON(w, r, http.StatusOK, responses2.NewScimGroupListResponse(
-			bridge,
-			groups,
-			responses2.ScimGroupListResponseInput{
-				StartIndex:   int(pagination.Offset)/int(pagination.Limit) + 1,
-				TotalResults: int(totalCount),
-				ItemsPerPage: int(pagination.Limit),
-			}))
+		listGroups(w, r, bridge, query)
+	}
+}
+
+func listGroups(w http.ResponseWriter, r *http.Request, bridge *bridge.Bridge, query listQuery) {
+	totalCount, groups, err := bridge.DB.GetGroups(r.Context(), database.GetGroupsParams{
+		Filter:    query.filter,
+		SortBy:    query.sort.SortBy,
+		SortOrder: query.sort.SortOrder,
+		Offset:    query.page.Offset,
+		Limit:     query.page.Limit,
+	})
+	if err != nil {
+		_ = render.Render(w, r, responses2.ErrDatabase(err))
+		return
 	}
+
+	RenderScimResource(w, r, http.StatusOK, query.projection, responses2.NewScimGroupListResponse(
+		bridge,
+		groups,
+		responses2.ScimGroupListResponseInput{
+			StartIndex:   query.page.StartIndex,
+			TotalResults: int(totalCount),
+			ItemsPerPage: len(groups),
+		}))
 }
 
 func V2GetGroup(bridge *bridge.Bridge) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
+		projection, err := responses2.NewProjection(r)
+		if err != nil {
+			_ = render.Render(w, r, responses2.ErrBadValue(err))
+			return
+		}
+
 		group, ok := r.Context().Value(middleware.Group).(database.Group)
 		if !ok {
 			_ = render.Render(w, r, responses2.ErrInternalServerError)
 			return
 		}
 
-		members, err := bridge.DB.GetGroupMembership(r.Context(), group.ID)
-		if err != nil {
-			_ = render.Render(w, r, responses2.ErrInternalServerError)
+		setETag(w, group.Version)
+		if notModified(r, group.Version) {
+			w.WriteHeader(http.StatusNotModified)
 			return
 		}
 
-		RenderScimJSON(w, r, http.StatusOK, responses2.NewScimGroupResponse(bridge, group, members))
+		// the membership of large groups is expensive to load, skip it if it's not returned
+		var members []database.GroupMembership
+		if projection.Includes("members") {
+			members, err = bridge.DB.GetGroupMembership(r.Context(), group.ID)
+			if err != nil {
+				_ = render.Render(w, r, responses2.ErrDatabase(err))
+				return
+			}
+		}
+
+		RenderScimResource(w, r, http.StatusOK, projection, responses2.NewScimGroupResponse(bridge, group, members))
 	}
 }
 
 func V2CreateGroup(bridge *bridge.Bridge) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		payload, err := payloads.GroupPayloadFromJSON(r.Body)
+		projection, err := responses2.NewProjection(r)
 		if err != nil {
 			_ = render.Render(w, r, responses2.ErrBadValue(err))
 			return
 		}
 
-		group, err := bridge.DB.CreateGroup(r.Context(), payload.DisplayName)
+		body, extensions, errResponse := validatedBody(r, bridge, "Group", nil)
+		if errResponse != nil {
+			_ = render.Render(w, r, errResponse)
+			return
+		}
+
+		payload, err := payloads.GroupPayloadFromJSON(bytes.NewReader(body))
 		if err != nil {
-			_ = render.Render(w, r, responses2.ErrInternalServerError)
+			_ = render.Render(w, r, responses2.ErrBadValue(err))
+			return
+		}
+
+		params, err := groupParams(payload, extensions)
+		if err != nil {
+			_ = render.Render(w, r, responses2.ErrBadValue(err))
+			return
+		}
+
+		group, err := bridge.DB.CreateGroup(r.Context(), params)
+		if err != nil {
+			_ = render.Render(w, r, responses2.ErrDatabase(err))
 			return
 		}
 
-		// A new group has no members, just make an empty list
 		var members []database.GroupMembership
+		if len(params.Members) > 0 && projection.Includes("members") {
+			members, err = bridge.DB.GetGroupMembership(r.Context(), group.ID)
+			if err != nil {
+				_ = render.Render(w, r, responses2.ErrDatabase(err))
+				return
+			}
+		}
 
-		RenderScimJSON(w, r, http.StatusCreated, responses2.NewScimGroupResponse(bridge, group, members))
+		setETag(w, group.Version)
+		RenderScimResource(w, r, http.StatusCreated, projection, responses2.NewScimGroupResponse(bridge, group, members))
 	}
 }
 
-func V2PatchGroup(bridge *bridge.Bridge) func(w http.ResponseWriter, r *http.Request) {
+func V2UpdateGroup(bridge *bridge.Bridge) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
+		projection, err := responses2.NewProjection(r)
+		if err != nil {
+			_ = render.Render(w, r, responses2.ErrBadValue(err))
+			return
+		}
+
 		group, ok := r.Context().Value(middleware.Group).(database.Group)
 		if !ok {
 			_ = render.Render(w, r, responses2.ErrInternalServerError)
 			return
 		}
 
-		payload, err := payloads.GroupPatchPayloadFromJSON(r.Body)
+		version, err := ifMatch(r, group.Version)
+		if err != nil {
+			_ = render.Render(w, r, responses2.ErrPreconditionFailed(err))
+			return
+		}
+
+		body, extensions, errResponse := validatedBody(r, bridge, "Group", group.Attributes())
+		if errResponse != nil {
+			_ = render.Render(w, r, errResponse)
+			return
+		}
+
+		payload, err := payloads.GroupPayloadFromJSON(bytes.NewReader(body))
 		if err != nil {
 			_ = render.Render(w, r, responses2.ErrBadValue(err))
 			return
 		}
 
-		var operations []payloads.GroupPatchOperation
-		for _, op := range payload.Operations {
-			operations = append(operations, payloads.GroupPatchOperation{
-				Op:    op.Op,
-				Path:  op.Path,
-				Value: op.Value,
-			})
+		params, err := groupParams(payload, extensions)
+		if err != nil {
+			_ = render.Render(w, r, responses2.ErrBadValue(err))
+			return
 		}
 
-		err = bridge.DB.PatchGroup(r.Context(), group.ID, operations)
+		err = bridge.DB.Transaction(r.Context(), func(tx database.Bridge) error {
+			err := checkGroupVersion(r, tx, group.ID, version)
+			if err != nil {
+				return err
+			}
+
+			group, err = tx.ReplaceGroup(r.Context(), group.ID, params)
+			return err
+		})
 		if err != nil {
-			_ = render.Render(w, r, responses2.ErrInternalServerError)
+			_ = render.Render(w, r, responses2.ErrDatabase(err))
 			return
 		}
 
-		group, err = bridge.DB.FindGroup(r.Context(), group.ID)
+		var members []database.GroupMembership
+		if projection.Includes("members") {
+			members, err = bridge.DB.GetGroupMembership(r.Context(), group.ID)
+			if err != nil {
+				_ = render.Render(w, r, responses2.ErrDatabase(err))
+				return
+			}
+		}
+
+		setETag(w, group.Version)
+		RenderScimResource(w, r, http.StatusOK, projection, responses2.NewScimGroupResponse(bridge, group, members))
+	}
+}
+
+func V2PatchGroup(bridge *bridge.Bridge) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		projection, err := responses2.NewProjection(r)
 		if err != nil {
+			_ = render.Render(w, r, responses2.ErrBadValue(err))
+			return
+		}
+
+		group, ok := r.Context().Value(middleware.Group).(database.Group)
+		if !ok {
 			_ = render.Render(w, r, responses2.ErrInternalServerError)
 			return
 		}
 
-		// displaying the membership is optional after a patch
+		version, err := ifMatch(r, group.Version)
+		if err != nil {
+			_ = render.Render(w, r, responses2.ErrPreconditionFailed(err))
+			return
+		}
+
+		payload, err := payloads.GroupPatchPayloadFromJSON(r.Body, bridge.CustomExtensions("Group")...)
+		if err != nil {
+			_ = render.Render(w, r, responses2.ErrInvalidSyntax(err))
+			return
+		}
+
+		var operations []patch.Operation
+		for _, op := range payload.Operations {
+			operations = append(operations, patch.Operation{Op: op.Op, Path: op.Path, Value: op.Value})
+		}
+
 		var members []database.GroupMembership
+		err = bridge.DB.Transaction(r.Context(), func(tx database.Bridge) error {
+			err := checkGroupVersion(r, tx, group.ID, version)
+			if err != nil {
+				return err
+			}
+
+			// the operations apply to the group as it is locked by the transaction, rather than as it
+			// was read before, so that concurrent changes are not lost
+			group, err = tx.FindGroup(r.Context(), group.ID)
+			if err != nil {
+				return err
+			}
+
+			current, err := tx.GetGroupMembership(r.Context(), group.ID)
+			if err != nil {
+				return err
+			}
+
+			changes, err := patchGroup(bridge, group, current, operations)
+			if err != nil {
+				return err
+			}
 
-		RenderScimJSON(w, r, http.StatusOK, responses2.NewScimGroupResponse(bridge, group, members))
+			err = tx.PatchGroup(r.Context(), group.ID, changes)
+			if err != nil {
+				return err
+			}
+
+			group, err = tx.FindGroup(r.Context(), group.ID)
+			if err != nil {
+				return err
+			}
+
+			// the membership of large groups is expensive to load, skip it if it's not returned
+			if projection.Includes("members") {
+				members, err = tx.GetGroupMembership(r.Context(), group.ID)
+			}
+
+			return err
+		})
+		if err != nil {
+			_ = render.Render(w, r, responses2.ErrDatabase(err))
+			return
+		}
+
+		setETag(w, group.Version)
+		RenderScimResource(w, r, http.StatusOK, projection, responses2.NewScimGroupResponse(bridge, group, members))
 	}
 }
 
@@ -118,12 +296,146 @@ func V2DeleteGroup(bridge *bridge.Bridge) func(w http.ResponseWriter, r *http.Re
 	return func(w http.ResponseWriter, r *http.Request) {
 		group := r.Context().Value(middleware.Group).(database.Group)
 
-		err := bridge.DB.DeleteGroup(r.Context(), group.ID)
+		version, err := ifMatch(r, group.Version)
 		if err != nil {
-			_ = render.Render(w, r, responses2.ErrInternalServerError)
+			_ = render.Render(w, r, responses2.ErrPreconditionFailed(err))
+			return
+		}
+
+		err = bridge.DB.Transaction(r.Context(), func(tx database.Bridge) error {
+			err := checkGroupVersion(r, tx, group.ID, version)
+			if err != nil {
+				return err
+			}
+
+			return tx.DeleteGroup(r.Context(), group.ID)
+		})
+		if err != nil {
+			_ = render.Render(w, r, responses2.ErrDatabase(err))
 			return
 		}
 
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
+
+// patchGroup applies the operations to the group and its members, and returns the resulting changes.
+// Nothing is changed if any of the operations fails.
+func patchGroup(bridge *bridge.Bridge, group database.Group, members []database.GroupMembership, operations []patch.Operation) (database.GroupPatch, error) {
+	current := make([]interface{}, 0, len(members))
+	for _, member := range members {
+		current = append(current, map[string]interface{}{
+			"value": member.MemberID.String(),
+			"type":  member.Type,
+		})
+	}
+
+	core, extensions := bridge.ResourceSchemas("Group")
+	resource := patch.Resource{
+		Attributes: group.Attributes(),
+		Schema:     core,
+		Extensions: extensions,
+	}
+	resource.Attributes["members"] = current
+
+	err := patch.Apply(resource, operations)
+	if err != nil {
+		return database.GroupPatch{}, err
+	}
+
+	err = validatePatched(bridge, "Group", resource.Attributes, group.Attributes())
+	if err != nil {
+		return database.GroupPatch{}, err
+	}
+
+	changes := database.GroupPatch{}
+	changes.DisplayName, _ = resource.Attributes["displayName"].(string)
+	changes.ExternalID, _ = resource.Attributes["externalId"].(string)
+	changes.Extensions = customExtensions(bridge, "Group", resource.Attributes)
+
+	existing := map[database.Member]bool{}
+	for _, member := range members {
+		existing[database.Member{ID: member.MemberID, Type: member.Type}] = true
+	}
+
+	patched := map[database.Member]bool{}
+	elements, _ := resource.Attributes["members"].([]interface{})
+	for _, element := range elements {
+		values, _ := element.(map[string]interface{})
+		value, _ := values["value"].(string)
+		memberType, _ := values["type"].(string)
+		ref, _ := values["$ref"].(string)
+
+		member, err := groupMember(value, memberType, ref)
+		if err != nil {
+			return database.GroupPatch{}, &patch.Error{ScimType: patch.InvalidValue, Detail: err.Error()}
+		}
+
+		if !patched[member] && !existing[member] {
+			changes.AddedMembers = append(changes.AddedMembers, member)
+		}
+		patched[member] = true
+	}
+
+	for _, member := range members {
+		removed := database.Member{ID: member.MemberID, Type: member.Type}
+		if !patched[removed] {
+			changes.RemovedMembers = append(changes.RemovedMembers, removed)
+		}
+	}
+
+	return changes, nil
+}
+
+// checkGroupVersion checks that the group is still at the version the request is conditioned on, if
+// any, within the transaction of the change.
+func checkGroupVersion(r *http.Request, tx database.Bridge, groupID uuid.UUID, version string) error {
+	if version == "" {
+		return nil
+	}
+
+	return tx.CheckGroupVersion(r.Context(), groupID, version)
+}
+
+func groupParams(payload *payloads.CreateScimGroupPayload, extensions database.Extensions) (database.GroupParams, error) {
+	params := database.GroupParams{
+		DisplayName: payload.DisplayName,
+		ExternalID:  payload.ExternalID,
+		Extensions:  extensions,
+	}
+
+	for _, member := range payload.Members {
+		member, err := groupMember(member.Value, member.Type, member.Ref)
+		if err != nil {
+			return database.GroupParams{}, err
+		}
+
+		params.Members = append(params.Members, member)
+	}
+
+	return params, nil
+}
+
+// groupMember parses a member of a group. Members without a type are users, unless their $ref points
+// to a group.
+func groupMember(value string, memberType string, ref string) (database.Member, error) {
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return database.Member{}, errors.Errorf("invalid member value %q", value)
+	}
+
+	switch {
+	case strings.EqualFold(memberType, database.UserMember):
+		memberType = database.UserMember
+	case strings.EqualFold(memberType, database.GroupMember):
+		memberType = database.GroupMember
+	case memberType == "" && strings.Contains(ref, "/Groups/"):
+		memberType = database.GroupMember
+	case memberType == "":
+		memberType = database.UserMember
+	default:
+		return database.Member{}, errors.Errorf("invalid member type %q", memberType)
+	}
+
+	return database.Member{ID: id, Type: memberType}, nil
+}
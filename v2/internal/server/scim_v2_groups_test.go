@@ -0,0 +1,336 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/bridge"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/database"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/internal/middleware"
+)
+
+// groupFixture holds a user, a group containing it, and a parent group containing that group.
+type groupFixture struct {
+	db     *fakeBridge
+	user   database.User
+	group  database.Group
+	parent database.Group
+	other  database.Group
+}
+
+func newGroupFixture() groupFixture {
+	db := newFakeBridge()
+	user := db.addUser("bjensen")
+	group := db.addGroup("Tour Guides", database.Member{ID: user.ID, Type: database.UserMember})
+	parent := db.addGroup("Employees", database.Member{ID: group.ID, Type: database.GroupMember})
+	other := db.addGroup("Admins")
+
+	return groupFixture{db: db, user: user, group: group, parent: parent, other: other}
+}
+
+// groupBody returns the JSON representation of a group with the members, given as JSON objects
+// formatted with the fixture.
+func groupBody(displayName string, members ...string) string {
+	return fmt.Sprintf(
+		`{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:Group"], "displayName": %q, "members": [%s]}`,
+		displayName,
+		strings.Join(members, ", "),
+	)
+}
+
+// responseMembers lists the members of a group response as "type value" strings.
+func responseMembers(t *testing.T, body map[string]interface{}) []string {
+	elements, _ := body["members"].([]interface{})
+
+	var members []string
+	for _, element := range elements {
+		member, ok := element.(map[string]interface{})
+		if !assert.True(t, ok) {
+			return nil
+		}
+
+		members = append(members, fmt.Sprintf("%s %s", member["type"], member["value"]))
+	}
+
+	return members
+}
+
+func TestV2CreateGroup(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		members      func(f groupFixture) []string
+		wantStatus   int
+		wantScimType string
+		wantMembers  func(f groupFixture) []string
+	}{
+		{
+			name: "user and nested group members",
+			members: func(f groupFixture) []string {
+				return []string{
+					fmt.Sprintf(`{"value": %q}`, f.user.ID),
+					fmt.Sprintf(`{"value": %q, "type": "Group"}`, f.group.ID),
+				}
+			},
+			wantStatus: http.StatusCreated,
+			wantMembers: func(f groupFixture) []string {
+				return []string{"User " + f.user.ID.String(), "Group " + f.group.ID.String()}
+			},
+		},
+		{
+			name: "nested group referenced by $ref",
+			members: func(f groupFixture) []string {
+				return []string{fmt.Sprintf(`{"value": %q, "$ref": "https://example.com/scim/v2/Groups/%s"}`, f.parent.ID, f.parent.ID)}
+			},
+			wantStatus: http.StatusCreated,
+			wantMembers: func(f groupFixture) []string {
+				return []string{"Group " + f.parent.ID.String()}
+			},
+		},
+		{
+			name:  "members excluded from the response",
+			query: "?excludedAttributes=members",
+			members: func(f groupFixture) []string {
+				return []string{fmt.Sprintf(`{"value": %q, "type": "Group"}`, f.group.ID)}
+			},
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name: "unknown nested group",
+			members: func(f groupFixture) []string {
+				return []string{fmt.Sprintf(`{"value": %q, "type": "Group"}`, f.user.ID)}
+			},
+			wantStatus:   http.StatusBadRequest,
+			wantScimType: "invalidValue",
+		},
+		{
+			name: "invalid member type",
+			members: func(f groupFixture) []string {
+				return []string{fmt.Sprintf(`{"value": %q, "type": "Robot"}`, f.user.ID)}
+			},
+			wantStatus:   http.StatusBadRequest,
+			wantScimType: "invalidValue",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newGroupFixture()
+			b := bridge.New(f.db, "https://example.com")
+
+			r := httptest.NewRequest(http.MethodPost, "/scim/v2/Groups"+tc.query, strings.NewReader(groupBody("Sales", tc.members(f)...)))
+			w := httptest.NewRecorder()
+			V2CreateGroup(&b)(w, r)
+
+			assert.Equal(t, tc.wantStatus, w.Code)
+
+			var body map[string]interface{}
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+
+			if tc.wantScimType != "" {
+				assert.Equal(t, tc.wantScimType, body["scimType"])
+				return
+			}
+
+			var wantMembers []string
+			if tc.wantMembers != nil {
+				wantMembers = tc.wantMembers(f)
+			}
+			assert.Equal(t, "Sales", body["displayName"])
+			assert.Equal(t, wantMembers, responseMembers(t, body))
+		})
+	}
+}
+
+func TestV2UpdateGroup(t *testing.T) {
+	tests := []struct {
+		name         string
+		ifMatch      string
+		body         func(f groupFixture) string
+		wantStatus   int
+		wantScimType string
+		wantMembers  func(f groupFixture) []string
+	}{
+		{
+			name: "replace members",
+			body: func(f groupFixture) string {
+				return groupBody("Tour Guides", fmt.Sprintf(`{"value": %q, "type": "Group"}`, f.other.ID))
+			},
+			wantStatus: http.StatusOK,
+			wantMembers: func(f groupFixture) []string {
+				return []string{"Group " + f.other.ID.String()}
+			},
+		},
+		{
+			name:    "current version",
+			ifMatch: `W/"2"`,
+			body: func(f groupFixture) string {
+				return groupBody("Guides", fmt.Sprintf(`{"value": %q}`, f.user.ID))
+			},
+			wantStatus: http.StatusOK,
+			wantMembers: func(f groupFixture) []string {
+				return []string{"User " + f.user.ID.String()}
+			},
+		},
+		{
+			name:    "stale version",
+			ifMatch: `W/"1"`,
+			body: func(f groupFixture) string {
+				return groupBody("Guides")
+			},
+			wantStatus: http.StatusPreconditionFailed,
+		},
+		{
+			name: "group nested in itself",
+			body: func(f groupFixture) string {
+				return groupBody("Tour Guides", fmt.Sprintf(`{"value": %q, "type": "Group"}`, f.group.ID))
+			},
+			wantStatus:   http.StatusBadRequest,
+			wantScimType: "invalidValue",
+		},
+		{
+			name: "parent group nested in its member",
+			body: func(f groupFixture) string {
+				return groupBody("Tour Guides", fmt.Sprintf(`{"value": %q, "type": "Group"}`, f.parent.ID))
+			},
+			wantStatus:   http.StatusBadRequest,
+			wantScimType: "invalidValue",
+		},
+		{
+			name: "missing displayName",
+			body: func(f groupFixture) string {
+				return `{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:Group"]}`
+			},
+			wantStatus:   http.StatusBadRequest,
+			wantScimType: "invalidValue",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newGroupFixture()
+			b := bridge.New(f.db, "https://example.com")
+
+			r := httptest.NewRequest(http.MethodPut, "/scim/v2/Groups/"+f.group.ID.String(), strings.NewReader(tc.body(f)))
+			r = r.WithContext(context.WithValue(r.Context(), middleware.Group, f.group))
+			if tc.ifMatch != "" {
+				r.Header.Set("If-Match", tc.ifMatch)
+			}
+			w := httptest.NewRecorder()
+			V2UpdateGroup(&b)(w, r)
+
+			assert.Equal(t, tc.wantStatus, w.Code)
+
+			var body map[string]interface{}
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+
+			if tc.wantStatus != http.StatusOK {
+				assert.Equal(t, []database.Member{{ID: f.user.ID, Type: database.UserMember}}, f.db.members[f.group.ID])
+				if tc.wantScimType != "" {
+					assert.Equal(t, tc.wantScimType, body["scimType"])
+				}
+				return
+			}
+
+			assert.Equal(t, tc.wantMembers(f), responseMembers(t, body))
+			assert.Equal(t, fmt.Sprintf(`W/"%d"`, f.db.version), w.Header().Get("ETag"))
+		})
+	}
+}
+
+func TestV2PatchGroup(t *testing.T) {
+	tests := []struct {
+		name         string
+		operations   func(f groupFixture) string
+		wantStatus   int
+		wantScimType string
+		wantMembers  func(f groupFixture) []database.Member
+	}{
+		{
+			name: "add member",
+			operations: func(f groupFixture) string {
+				return fmt.Sprintf(`{"op": "add", "path": "members", "value": [{"value": %q, "type": "Group"}]}`, f.other.ID)
+			},
+			wantStatus: http.StatusOK,
+			wantMembers: func(f groupFixture) []database.Member {
+				return []database.Member{
+					{ID: f.user.ID, Type: database.UserMember},
+					{ID: f.other.ID, Type: database.GroupMember},
+				}
+			},
+		},
+		{
+			name: "displayName that is not a string",
+			operations: func(f groupFixture) string {
+				return `{"op": "replace", "path": "displayName", "value": 42}`
+			},
+			wantStatus:   http.StatusBadRequest,
+			wantScimType: "invalidValue",
+		},
+		{
+			name: "multiple externalIds",
+			operations: func(f groupFixture) string {
+				return `{"op": "replace", "value": {"externalId": ["guides", "tours"]}}`
+			},
+			wantStatus:   http.StatusBadRequest,
+			wantScimType: "invalidValue",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newGroupFixture()
+			b := bridge.New(f.db, "https://example.com")
+
+			r := httptest.NewRequest(http.MethodPatch, "/scim/v2/Groups/"+f.group.ID.String(), strings.NewReader(patchBody(tc.operations(f))))
+			r = r.WithContext(context.WithValue(r.Context(), middleware.Group, f.group))
+			w := httptest.NewRecorder()
+			V2PatchGroup(&b)(w, r)
+
+			assert.Equal(t, tc.wantStatus, w.Code)
+
+			if tc.wantScimType != "" {
+				var body map[string]interface{}
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+				assert.Equal(t, tc.wantScimType, body["scimType"])
+				assert.Equal(t, f.group, f.db.groups[f.group.ID])
+				return
+			}
+
+			assert.Equal(t, tc.wantMembers(f), f.db.members[f.group.ID])
+		})
+	}
+}
+
+func TestV2PatchGroupKeepsConcurrentChanges(t *testing.T) {
+	f := newGroupFixture()
+	b := bridge.New(f.db, "https://example.com")
+
+	// another request renames the group after the middleware read it
+	concurrent := f.db.groups[f.group.ID]
+	concurrent.DisplayName = "Guides"
+	concurrent.ExternalID = sql.NullString{String: "guides", Valid: true}
+	concurrent.Version = f.db.nextVersion()
+	f.db.groups[f.group.ID] = concurrent
+
+	body := patchBody(fmt.Sprintf(`{"op": "add", "path": "members", "value": [{"value": %q, "type": "Group"}]}`, f.other.ID))
+	r := httptest.NewRequest(http.MethodPatch, "/scim/v2/Groups/"+f.group.ID.String(), strings.NewReader(body))
+	r = r.WithContext(context.WithValue(r.Context(), middleware.Group, f.group))
+	w := httptest.NewRecorder()
+	V2PatchGroup(&b)(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Guides", f.db.groups[f.group.ID].DisplayName)
+	assert.Equal(t, "guides", f.db.groups[f.group.ID].ExternalID.String)
+	assert.Equal(t, []database.Member{
+		{ID: f.user.ID, Type: database.UserMember},
+		{ID: f.other.ID, Type: database.GroupMember},
+	}, f.db.members[f.group.ID])
+}
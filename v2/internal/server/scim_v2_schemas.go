@@ -0,0 +1,48 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/bridge"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/internal/responses"
+)
+
+func V2ListSchemas(bridge *bridge.Bridge) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		RenderScimJSON(w, r, http.StatusOK, responses.NewListSchemasResponse(bridge, bridge.Schemas()))
+	}
+}
+
+func V2GetSchema(bridge *bridge.Bridge) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+		s, ok := bridge.Schema(id)
+		if !ok {
+			_ = render.Render(w, r, responses.ErrNotFound(id))
+			return
+		}
+
+		RenderScimJSON(w, r, http.StatusOK, responses.NewSchemaResponse(bridge, s))
+	}
+}
+
+func V2ListResourceTypes(bridge *bridge.Bridge) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		RenderScimJSON(w, r, http.StatusOK, responses.NewListResourceTypesResponse(bridge, bridge.ResourceTypes()))
+	}
+}
+
+func V2GetResourceType(bridge *bridge.Bridge) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := chi.URLParam(r, "name")
+		resourceType, ok := bridge.ResourceType(name)
+		if !ok {
+			_ = render.Render(w, r, responses.ErrNotFound(name))
+			return
+		}
+
+		RenderScimJSON(w, r, http.StatusOK, responses.NewResourceTypeResponse(bridge, resourceType))
+	}
+}
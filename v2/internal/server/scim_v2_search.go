@@ -0,0 +1,154 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/go-chi/render"
+	"github.com/pkg/errors"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/bridge"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/database"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/filters"
+	pagination2 "github.com/suse-skyscraper/openfga-scim-bridge/v2/internal/pagination"
+	responses2 "github.com/suse-skyscraper/openfga-scim-bridge/v2/internal/responses"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/internal/sorting"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/payloads"
+)
+
+// listQuery holds the parameters of a list request, read either from the query string of a GET
+// request or from the body of a POST to a ".search" endpoint.
+type listQuery struct {
+	projection responses2.Projection
+	filter     filters.Expression
+	page       pagination2.Params
+	sort       sorting.Params
+}
+
+// queryFromURL reads the parameters of a list request from its query string. The resources can be
+// sorted by the given attribute paths.
+func queryFromURL(r *http.Request, bridge *bridge.Bridge, sortAttributes sorting.Attributes) (listQuery, render.Renderer) {
+	var query listQuery
+	var err error
+
+	query.projection, err = responses2.NewProjection(r)
+	if err != nil {
+		return listQuery{}, responses2.ErrBadValue(err)
+	}
+
+	query.filter, err = filters.ParseFilter(r.URL.Query().Get("filter"))
+	if err != nil {
+		return listQuery{}, responses2.ErrBadFilter(err)
+	}
+
+	pageSize, maxPageSize := bridge.PageLimits()
+	query.page, err = pagination2.Paginate(r, pageSize, maxPageSize)
+	if err != nil {
+		return listQuery{}, responses2.ErrBadValue(err)
+	}
+
+	if bridge.SortSupported {
+		query.sort, err = sorting.Sort(r, sortAttributes)
+		if err != nil {
+			return listQuery{}, responses2.ErrBadValue(err)
+		}
+	}
+
+	return query, nil
+}
+
+// queryFromSearch reads the parameters of a list request from the SearchRequest body of a POST to a
+// ".search" endpoint, as described in RFC 7644 section 3.4.3.
+func queryFromSearch(r *http.Request, bridge *bridge.Bridge, sortAttributes sorting.Attributes) (listQuery, render.Renderer) {
+	payload, err := payloads.SearchRequestFromJSON(r.Body)
+	if err != nil {
+		return listQuery{}, responses2.ErrInvalidSyntax(err)
+	}
+
+	var query listQuery
+
+	query.projection, err = responses2.ParseProjection(payload.Attributes, payload.ExcludedAttributes)
+	if err != nil {
+		return listQuery{}, responses2.ErrBadValue(err)
+	}
+
+	query.filter, err = filters.ParseFilter(payload.Filter)
+	if err != nil {
+		return listQuery{}, responses2.ErrBadFilter(err)
+	}
+
+	pageSize, maxPageSize := bridge.PageLimits()
+	query.page, err = pagination2.New(payload.StartIndex, payload.Count, pageSize, maxPageSize)
+	if err != nil {
+		return listQuery{}, responses2.ErrBadValue(err)
+	}
+
+	if bridge.SortSupported {
+		query.sort, err = sorting.Parse(payload.SortBy, payload.SortOrder, sortAttributes)
+		if err != nil {
+			return listQuery{}, responses2.ErrBadValue(err)
+		}
+	}
+
+	return query, nil
+}
+
+// V2Search searches users and groups at once. The matching users are listed before the matching
+// groups, each sorted as requested, and the page spans both. A resource type is skipped when the
+// filter references an attribute it doesn't have, e.g. userName for groups.
+func V2Search(bridge *bridge.Bridge) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query, errResponse := queryFromSearch(r, bridge, sorting.ResourceAttributes)
+		if errResponse != nil {
+			_ = render.Render(w, r, errResponse)
+			return
+		}
+
+		var unknownAttribute error
+
+		userCount, users, err := bridge.DB.GetUsers(r.Context(), database.GetUsersParams{
+			Filter:    query.filter,
+			SortBy:    query.sort.SortBy,
+			SortOrder: query.sort.SortOrder,
+			Offset:    query.page.Offset,
+			Limit:     query.page.Limit,
+		})
+		if errors.Is(err, filters.ErrUnknownAttribute) {
+			unknownAttribute = err
+		} else if err != nil {
+			_ = render.Render(w, r, responses2.ErrDatabase(err))
+			return
+		}
+
+		// the groups continue the page where the users end
+		offset := query.page.Offset - int32(userCount)
+		if offset < 0 {
+			offset = 0
+		}
+
+		groupCount, groups, err := bridge.DB.GetGroups(r.Context(), database.GetGroupsParams{
+			Filter:    query.filter,
+			SortBy:    query.sort.SortBy,
+			SortOrder: query.sort.SortOrder,
+			Offset:    offset,
+			Limit:     query.page.Limit - int32(len(users)),
+		})
+		if errors.Is(err, filters.ErrUnknownAttribute) {
+			if unknownAttribute != nil {
+				// neither users nor groups have the attribute
+				_ = render.Render(w, r, responses2.ErrBadFilter(err))
+				return
+			}
+		} else if err != nil {
+			_ = render.Render(w, r, responses2.ErrDatabase(err))
+			return
+		}
+
+		RenderScimResource(w, r, http.StatusOK, query.projection, responses2.NewScimSearchResponse(
+			bridge,
+			users,
+			groups,
+			responses2.ScimSearchResponseInput{
+				StartIndex:   query.page.StartIndex,
+				TotalResults: int(userCount + groupCount),
+			}))
+	}
+}
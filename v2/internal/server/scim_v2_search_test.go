@@ -0,0 +1,254 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/bridge"
+)
+
+// searchFixture returns a database with three users and two groups.
+func searchFixture() *fakeBridge {
+	db := newFakeBridge()
+	db.addUser("bjensen")
+	db.addUser("bjones")
+	db.addUser("jsmith")
+	db.addGroup("Admins")
+	db.addGroup("Tour Guides")
+
+	return db
+}
+
+// searchResponse is the part of a list response the search tests check.
+type searchResponse struct {
+	TotalResults int                      `json:"totalResults"`
+	StartIndex   int                      `json:"startIndex"`
+	ItemsPerPage int                      `json:"itemsPerPage"`
+	Resources    []map[string]interface{} `json:"Resources"`
+	ScimType     string                   `json:"scimType"`
+}
+
+// resourceAttributes lists the attributes of the resources of a list response.
+func resourceAttributes(resources []map[string]interface{}) [][]string {
+	var attributes [][]string
+	for _, resource := range resources {
+		var keys []string
+		for key := range resource {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		attributes = append(attributes, keys)
+	}
+
+	return attributes
+}
+
+func TestV2SearchUsers(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		wantStatus     int
+		wantScimType   string
+		wantResponse   searchResponse
+		wantUsernames  []interface{}
+		wantAttributes [][]string
+		wantOffset     int32
+		wantLimit      int32
+	}{
+		{
+			name: "filter, attributes and page",
+			body: `{
+				"schemas": ["urn:ietf:params:scim:api:messages:2.0:SearchRequest"],
+				"filter": "userName sw \"b\"",
+				"attributes": ["userName"],
+				"startIndex": 2,
+				"count": 1
+			}`,
+			wantStatus:     http.StatusOK,
+			wantResponse:   searchResponse{TotalResults: 2, StartIndex: 2, ItemsPerPage: 1},
+			wantUsernames:  []interface{}{"bjones"},
+			wantAttributes: [][]string{{"id", "userName"}},
+			wantOffset:     1,
+			wantLimit:      1,
+		},
+		{
+			name: "excluded attributes and default page",
+			body: `{
+				"schemas": ["urn:ietf:params:scim:api:messages:2.0:SearchRequest"],
+				"excludedAttributes": ["active", "meta", "groups"]
+			}`,
+			wantStatus:     http.StatusOK,
+			wantResponse:   searchResponse{TotalResults: 3, StartIndex: 1, ItemsPerPage: 3},
+			wantUsernames:  []interface{}{"bjensen", "bjones", "jsmith"},
+			wantAttributes: [][]string{{"id", "userName"}, {"id", "userName"}, {"id", "userName"}},
+			wantOffset:     0,
+			wantLimit:      10,
+		},
+		{
+			name:         "invalid filter",
+			body:         `{"filter": "userName eq"}`,
+			wantStatus:   http.StatusBadRequest,
+			wantScimType: "invalidFilter",
+		},
+		{
+			name:         "invalid count",
+			body:         `{"count": 0}`,
+			wantStatus:   http.StatusBadRequest,
+			wantScimType: "invalidValue",
+		},
+		{
+			name:         "malformed body",
+			body:         `{"filter": `,
+			wantStatus:   http.StatusBadRequest,
+			wantScimType: "invalidSyntax",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			db := searchFixture()
+			b := bridge.New(db, "https://example.com")
+
+			r := httptest.NewRequest(http.MethodPost, "/scim/v2/Users/.search", strings.NewReader(tc.body))
+			w := httptest.NewRecorder()
+			V2SearchUsers(&b)(w, r)
+
+			assert.Equal(t, tc.wantStatus, w.Code)
+
+			var response searchResponse
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+
+			if tc.wantScimType != "" {
+				assert.Equal(t, tc.wantScimType, response.ScimType)
+				assert.Empty(t, db.usersQueries)
+				return
+			}
+
+			var usernames []interface{}
+			for _, resource := range response.Resources {
+				usernames = append(usernames, resource["userName"])
+			}
+
+			assert.Equal(t, tc.wantResponse.TotalResults, response.TotalResults)
+			assert.Equal(t, tc.wantResponse.StartIndex, response.StartIndex)
+			assert.Equal(t, tc.wantResponse.ItemsPerPage, response.ItemsPerPage)
+			assert.Equal(t, tc.wantUsernames, usernames)
+			assert.Equal(t, tc.wantAttributes, resourceAttributes(response.Resources))
+
+			if assert.Len(t, db.usersQueries, 1) {
+				assert.Equal(t, tc.wantOffset, db.usersQueries[0].Offset)
+				assert.Equal(t, tc.wantLimit, db.usersQueries[0].Limit)
+			}
+		})
+	}
+}
+
+func TestV2Search(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		wantStatus   int
+		wantScimType string
+		wantTotal    int
+		wantNames    []interface{}
+	}{
+		{
+			name: "users before groups",
+			body: `{
+				"schemas": ["urn:ietf:params:scim:api:messages:2.0:SearchRequest"],
+				"filter": "userName eq \"jsmith\" or displayName eq \"Admins\"",
+				"attributes": ["userName", "displayName"]
+			}`,
+			wantStatus: http.StatusOK,
+			wantTotal:  2,
+			wantNames:  []interface{}{"jsmith", "Admins"},
+		},
+		{
+			name: "page spanning users and groups",
+			body: `{
+				"schemas": ["urn:ietf:params:scim:api:messages:2.0:SearchRequest"],
+				"filter": "userName sw \"b\" or displayName pr",
+				"attributes": ["userName", "displayName"],
+				"startIndex": 2,
+				"count": 2
+			}`,
+			wantStatus: http.StatusOK,
+			wantTotal:  4,
+			wantNames:  []interface{}{"bjones", "Admins"},
+		},
+		{
+			name: "page of groups only",
+			body: `{
+				"schemas": ["urn:ietf:params:scim:api:messages:2.0:SearchRequest"],
+				"filter": "userName sw \"b\" or displayName pr",
+				"attributes": ["userName", "displayName"],
+				"startIndex": 4,
+				"count": 2
+			}`,
+			wantStatus: http.StatusOK,
+			wantTotal:  4,
+			wantNames:  []interface{}{"Tour Guides"},
+		},
+		{
+			name:         "invalid filter",
+			body:         `{"filter": "userName eq \"jsmith\" and"}`,
+			wantStatus:   http.StatusBadRequest,
+			wantScimType: "invalidFilter",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			db := searchFixture()
+			b := bridge.New(db, "https://example.com")
+
+			r := httptest.NewRequest(http.MethodPost, "/scim/v2/.search", strings.NewReader(tc.body))
+			w := httptest.NewRecorder()
+			V2Search(&b)(w, r)
+
+			assert.Equal(t, tc.wantStatus, w.Code)
+
+			var response searchResponse
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+
+			if tc.wantScimType != "" {
+				assert.Equal(t, tc.wantScimType, response.ScimType)
+				assert.Empty(t, db.usersQueries)
+				assert.Empty(t, db.groupsQueries)
+				return
+			}
+
+			var names []interface{}
+			for _, resource := range response.Resources {
+				if name, ok := resource["userName"]; ok {
+					names = append(names, name)
+				} else {
+					names = append(names, resource["displayName"])
+				}
+			}
+
+			assert.Equal(t, tc.wantTotal, response.TotalResults)
+			assert.Equal(t, tc.wantNames, names)
+		})
+	}
+}
+
+func TestV2SearchUsersWithoutLimits(t *testing.T) {
+	db := searchFixture()
+	b := bridge.Bridge{BaseURL: "https://example.com", DB: db}
+
+	r := httptest.NewRequest(http.MethodPost, "/scim/v2/Users/.search", strings.NewReader(`{"count": 2}`))
+	w := httptest.NewRecorder()
+	V2SearchUsers(&b)(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	if assert.Len(t, db.usersQueries, 1) {
+		assert.Equal(t, int32(2), db.usersQueries[0].Limit)
+	}
+}
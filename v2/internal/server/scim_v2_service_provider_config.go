@@ -0,0 +1,14 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/bridge"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/internal/responses"
+)
+
+func V2GetServiceProviderConfig(bridge *bridge.Bridge) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		RenderScimJSON(w, r, http.StatusOK, responses.NewServiceProviderConfigResponse(bridge))
+	}
+}
@@ -1,66 +1,114 @@
 package server
 
 import (
+	"bytes"
+	"encoding/json"
 	"net/http"
 
 	"github.com/go-chi/render"
+	"github.com/google/uuid"
 	"github.com/pkg/errors"
 	"github.com/suse-skyscraper/openfga-scim-bridge/v2/bridge"
 	"github.com/suse-skyscraper/openfga-scim-bridge/v2/database"
-	"github.com/suse-skyscraper/openfga-scim-bridge/v2/filters"
 	"github.com/suse-skyscraper/openfga-scim-bridge/v2/internal/middleware"
-	"github.com/suse-skyscraper/openfga-scim-bridge/v2/internal/pagination"
 	responses2 "github.com/suse-skyscraper/openfga-scim-bridge/v2/internal/responses"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/internal/sorting"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/patch"
 	"github.com/suse-skyscraper/openfga-scim-bridge/v2/payloads"
 )
 
 func V2ListUsers(bridge *bridge.Bridge) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		filterString := r.URL.Query().Get("filter")
-		filterList, err := filters.ParseFilter(filterString)
-		if err != nil {
-			_ = render.Render(w, r, responses2.ErrBadFilter(err))
+		query, errResponse := queryFromURL(r, bridge, sorting.UserAttributes)
+		if errResponse != nil {
+			_ = render.Render(w, r, errResponse)
 			return
 		}
 
-		page := pagination.Paginate(r)
+		listUsers(w, r, bridge, query)
+	}
+}
 
-		totalCount, users, err := bridge.DB.GetUsers(r.Context(), database.GetUsersParams{
-			Filters: filterList,
-			Offset:  page.Offset,
-			Limit:   page.Limit,
-		})
-		if err != nil {
-			_ = render.Render(w, r, responses2.ErrInternalServerError)
+func V2SearchUsers(bridge *bridge.Bridge) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query, errResponse := queryFromSearch(r, bridge, sorting.UserAttributes)
+		if errResponse != nil {
+			_ = render.Render(w, r, errResponse)
 			return
 		}
 
-		RenderScimJSON(w, r, http.StatusOK, responses2.NewScimUserListResponse(
-			bridge,
-			users,
-			responses2.ScimUserListResponseInput{
-				StartIndex:   int(page.Offset)/int(page.Limit) + 1,
-				TotalResults: int(totalCount),
-				ItemsPerPage: int(page.Limit),
-			}))
+		listUsers(w, r, bridge, query)
 	}
 }
 
+func listUsers(w http.ResponseWriter, r *http.Request, bridge *bridge.Bridge, query listQuery) {
+	totalCount, users, err := bridge.DB.GetUsers(r.Context(), database.GetUsersParams{
+		Filter:    query.filter,
+		SortBy:    query.sort.SortBy,
+		SortOrder: query.sort.SortOrder,
+		Offset:    query.page.Offset,
+		Limit:     query.page.Limit,
+	})
+	if err != nil {
+		_ = render.Render(w, r, responses2.ErrDatabase(err))
+		return
+	}
+
+	RenderScimResource(w, r, http.StatusOK, query.projection, responses2.NewScimUserListResponse(
+		bridge,
+		users,
+		responses2.ScimUserListResponseInput{
+			StartIndex:   query.page.StartIndex,
+			TotalResults: int(totalCount),
+			ItemsPerPage: len(users),
+		}))
+}
+
 func V2GetUser(bridge *bridge.Bridge) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
+		projection, err := responses2.NewProjection(r)
+		if err != nil {
+			_ = render.Render(w, r, responses2.ErrBadValue(err))
+			return
+		}
+
 		user, ok := r.Context().Value(middleware.User).(database.User)
 		if !ok {
 			_ = render.Render(w, r, responses2.ErrInternalServerError)
 			return
 		}
 
-		RenderScimJSON(w, r, http.StatusOK, responses2.NewScimUserResponse(bridge, user))
+		setETag(w, user.Version)
+		if notModified(r, user.Version) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		groups, err := userGroups(r, bridge, projection, user.ID)
+		if err != nil {
+			_ = render.Render(w, r, responses2.ErrDatabase(err))
+			return
+		}
+
+		RenderScimResource(w, r, http.StatusOK, projection, responses2.NewScimUserResponse(bridge, user, groups))
 	}
 }
 
 func V2CreateUser(bridge *bridge.Bridge) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		payload, err := payloads.Parse(r.Body)
+		projection, err := responses2.NewProjection(r)
+		if err != nil {
+			_ = render.Render(w, r, responses2.ErrBadValue(err))
+			return
+		}
+
+		body, extensions, errResponse := validatedBody(r, bridge, "User", nil)
+		if errResponse != nil {
+			_ = render.Render(w, r, errResponse)
+			return
+		}
+
+		payload, err := payloads.Parse(bytes.NewReader(body))
 		if err != nil {
 			_ = render.Render(w, r, responses2.ErrBadValue(err))
 			return
@@ -71,25 +119,17 @@ func V2CreateUser(bridge *bridge.Bridge) func(w http.ResponseWriter, r *http.Req
 			return
 		}
 
-		user, err := bridge.DB.CreateUser(r.Context(), database.UserParams{
-			Username:    payload.Username,
-			Name:        payload.Name,
-			Active:      payload.Active,
-			Emails:      payload.Emails,
-			Locale:      payload.Locale,
-			ExternalID:  payload.ExternalID,
-			DisplayName: payload.DisplayName,
-		})
+		user, err := bridge.DB.CreateUser(r.Context(), userParams(payload, extensions))
 
-		if err != nil && errors.Is(err, database.ErrConflict) {
-			w.WriteHeader(http.StatusConflict)
-			return
-		} else if err != nil {
-			_ = render.Render(w, r, responses2.ErrInternalServerError)
+		if err != nil {
+			_ = render.Render(w, r, responses2.ErrDatabase(err))
 			return
 		}
 
-		RenderScimJSON(w, r, http.StatusCreated, responses2.NewScimUserResponse(bridge, user))
+		setETag(w, user.Version)
+
+		// a new user is not a member of any group yet
+		RenderScimResource(w, r, http.StatusCreated, projection, responses2.NewScimUserResponse(bridge, user, nil))
 	}
 }
 
@@ -101,9 +141,22 @@ func V2DeleteUser(bridge *bridge.Bridge) func(w http.ResponseWriter, r *http.Req
 			return
 		}
 
-		err := bridge.DB.DeleteUser(r.Context(), user.ID)
+		version, err := ifMatch(r, user.Version)
 		if err != nil {
-			_ = render.Render(w, r, responses2.ErrInternalServerError)
+			_ = render.Render(w, r, responses2.ErrPreconditionFailed(err))
+			return
+		}
+
+		err = bridge.DB.Transaction(r.Context(), func(tx database.Bridge) error {
+			err := checkUserVersion(r, tx, user.ID, version)
+			if err != nil {
+				return err
+			}
+
+			return tx.DeleteUser(r.Context(), user.ID)
+		})
+		if err != nil {
+			_ = render.Render(w, r, responses2.ErrDatabase(err))
 			return
 		}
 
@@ -113,69 +166,208 @@ func V2DeleteUser(bridge *bridge.Bridge) func(w http.ResponseWriter, r *http.Req
 
 func V2UpdateUser(bridge *bridge.Bridge) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
+		projection, err := responses2.NewProjection(r)
+		if err != nil {
+			_ = render.Render(w, r, responses2.ErrBadValue(err))
+			return
+		}
+
 		user, ok := r.Context().Value(middleware.User).(database.User)
 		if !ok {
 			_ = render.Render(w, r, responses2.ErrInternalServerError)
 			return
 		}
 
-		payload, err := payloads.Parse(r.Body)
+		version, err := ifMatch(r, user.Version)
+		if err != nil {
+			_ = render.Render(w, r, responses2.ErrPreconditionFailed(err))
+			return
+		}
+
+		body, extensions, errResponse := validatedBody(r, bridge, "User", user.Attributes())
+		if errResponse != nil {
+			_ = render.Render(w, r, errResponse)
+			return
+		}
+
+		payload, err := payloads.Parse(bytes.NewReader(body))
 		if err != nil {
 			_ = render.Render(w, r, responses2.ErrBadValue(err))
 			return
 		}
 
-		user, err = bridge.DB.UpdateUser(r.Context(), user.ID, database.UserParams{
-			Username:    payload.Username,
-			Name:        payload.Name,
-			Active:      payload.Active,
-			Emails:      payload.Emails,
-			Locale:      payload.Locale,
-			DisplayName: payload.DisplayName,
-			ExternalID:  payload.ExternalID,
+		err = bridge.DB.Transaction(r.Context(), func(tx database.Bridge) error {
+			err := checkUserVersion(r, tx, user.ID, version)
+			if err != nil {
+				return err
+			}
+
+			user, err = tx.UpdateUser(r.Context(), user.ID, userParams(payload, extensions))
+			return err
 		})
 		if err != nil {
-			_ = render.Render(w, r, responses2.ErrInternalServerError)
+			_ = render.Render(w, r, responses2.ErrDatabase(err))
+			return
+		}
+
+		groups, err := userGroups(r, bridge, projection, user.ID)
+		if err != nil {
+			_ = render.Render(w, r, responses2.ErrDatabase(err))
 			return
 		}
 
-		RenderScimJSON(w, r, http.StatusOK, responses2.NewScimUserResponse(bridge, user))
+		setETag(w, user.Version)
+		RenderScimResource(w, r, http.StatusOK, projection, responses2.NewScimUserResponse(bridge, user, groups))
 	}
 }
 
 func V2PatchUser(bridge *bridge.Bridge) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
+		projection, err := responses2.NewProjection(r)
+		if err != nil {
+			_ = render.Render(w, r, responses2.ErrBadValue(err))
+			return
+		}
+
 		user, ok := r.Context().Value(middleware.User).(database.User)
 		if !ok {
 			_ = render.Render(w, r, responses2.ErrInternalServerError)
 			return
 		}
 
-		payload, err := payloads.UserPatchPayloadFromJSON(r.Body)
+		version, err := ifMatch(r, user.Version)
 		if err != nil {
-			_ = render.Render(w, r, responses2.ErrBadValue(err))
+			_ = render.Render(w, r, responses2.ErrPreconditionFailed(err))
 			return
 		}
 
+		payload, err := payloads.UserPatchPayloadFromJSON(r.Body, bridge.CustomExtensions("User")...)
+		if err != nil {
+			_ = render.Render(w, r, responses2.ErrInvalidSyntax(err))
+			return
+		}
+
+		var operations []patch.Operation
 		for _, op := range payload.Operations {
-			switch op.Op {
-			case "replace":
-				err = bridge.DB.SetUserActive(r.Context(), user.ID, op.Value.Active)
-				if err != nil {
-					_ = render.Render(w, r, responses2.ErrInternalServerError)
-					return
-				}
-			default:
-				_ = render.Render(w, r, responses2.ErrBadValue(errors.New("Unsupported operation")))
+			operations = append(operations, patch.Operation{Op: op.Op, Path: op.Path, Value: op.Value})
+		}
+
+		err = bridge.DB.Transaction(r.Context(), func(tx database.Bridge) error {
+			err := checkUserVersion(r, tx, user.ID, version)
+			if err != nil {
+				return err
 			}
+
+			// the operations apply to the user as it is locked by the transaction, rather than as it
+			// was read before, so that concurrent changes are not lost
+			user, err = tx.FindUser(r.Context(), user.ID)
+			if err != nil {
+				return err
+			}
+
+			params, err := patchUser(bridge, user, operations)
+			if err != nil {
+				return err
+			}
+
+			user, err = tx.UpdateUser(r.Context(), user.ID, params)
+			return err
+		})
+		if err != nil {
+			_ = render.Render(w, r, responses2.ErrDatabase(err))
+			return
 		}
 
-		user, err = bridge.DB.FindUser(r.Context(), user.ID)
+		groups, err := userGroups(r, bridge, projection, user.ID)
 		if err != nil {
-			_ = render.Render(w, r, responses2.ErrInternalServerError)
+			_ = render.Render(w, r, responses2.ErrDatabase(err))
 			return
 		}
 
-		RenderScimJSON(w, r, http.StatusOK, responses2.NewScimUserResponse(bridge, user))
+		setETag(w, user.Version)
+		RenderScimResource(w, r, http.StatusOK, projection, responses2.NewScimUserResponse(bridge, user, groups))
+	}
+}
+
+// userGroups loads the groups of the user, unless they are excluded from the response.
+func userGroups(r *http.Request, bridge *bridge.Bridge, projection responses2.Projection, userID uuid.UUID) ([]database.UserGroup, error) {
+	if !projection.Includes("groups") {
+		return nil, nil
+	}
+
+	return bridge.DB.GetUserGroups(r.Context(), userID, bridge.IndirectGroups)
+}
+
+// patchUser applies the operations to the user and returns its attributes after the patch.
+func patchUser(bridge *bridge.Bridge, user database.User, operations []patch.Operation) (database.UserParams, error) {
+	core, extensions := bridge.ResourceSchemas("User")
+	resource := patch.Resource{
+		Attributes: user.Attributes(),
+		Schema:     core,
+		Extensions: extensions,
+	}
+
+	err := patch.Apply(resource, operations)
+	if err != nil {
+		return database.UserParams{}, err
+	}
+
+	err = validatePatched(bridge, "User", resource.Attributes, user.Attributes())
+	if err != nil {
+		return database.UserParams{}, err
+	}
+
+	patched, err := userPayloadFromAttributes(resource.Attributes)
+	if err != nil {
+		return database.UserParams{}, &patch.Error{ScimType: patch.InvalidValue, Detail: err.Error()}
+	}
+
+	return userParams(patched, customExtensions(bridge, "User", resource.Attributes)), nil
+}
+
+// checkUserVersion checks that the user is still at the version the request is conditioned on, if
+// any, within the transaction of the change.
+func checkUserVersion(r *http.Request, tx database.Bridge, userID uuid.UUID, version string) error {
+	if version == "" {
+		return nil
+	}
+
+	return tx.CheckUserVersion(r.Context(), userID, version)
+}
+
+// userPayloadFromAttributes decodes the JSON representation of a user, e.g. after patching it.
+func userPayloadFromAttributes(attributes map[string]interface{}) (*payloads.CreateScimUserPayload, error) {
+	data, err := json.Marshal(attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	return payloads.Parse(bytes.NewReader(data))
+}
+
+func userParams(payload *payloads.CreateScimUserPayload, extensions database.Extensions) database.UserParams {
+	return database.UserParams{
+		Username:          payload.Username,
+		Name:              payload.Name,
+		DisplayName:       payload.DisplayName,
+		NickName:          payload.Nickname,
+		ProfileURL:        payload.ProfileURL,
+		Title:             payload.Title,
+		UserType:          payload.UserType,
+		PreferredLanguage: payload.PreferredLanguage,
+		Timezone:          payload.Timezone,
+		Emails:            payload.Emails,
+		PhoneNumbers:      payload.PhoneNumbers,
+		Ims:               payload.Ims,
+		Photos:            payload.Photos,
+		Addresses:         payload.Addresses,
+		Entitlements:      payload.Entitlements,
+		Roles:             payload.Roles,
+		X509Certificates:  payload.X509Certificates,
+		Active:            payload.Active,
+		Locale:            payload.Locale,
+		ExternalID:        payload.ExternalID,
+		EnterpriseUser:    payload.EnterpriseUser,
+		Extensions:        extensions,
 	}
 }
@@ -0,0 +1,162 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/bridge"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/database"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/internal/middleware"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/payloads"
+)
+
+// patchFixture returns a database with a user who has emails and an enterprise user extension.
+func patchFixture() (*fakeBridge, database.User) {
+	db := newFakeBridge()
+	user := db.addUser("bjensen")
+	user.Emails = []payloads.UserEmail{{Value: "bjensen@example.com", Type: "work", Primary: true}}
+	user.EnterpriseUser = &payloads.EnterpriseUser{
+		EmployeeNumber: "701984",
+		Department:     "Tour Operations",
+		Manager: &payloads.EnterpriseUserManager{
+			Value:       "26118915-6090-4610-87e4-49d8ca9f808d",
+			Ref:         "../Users/26118915-6090-4610-87e4-49d8ca9f808d",
+			DisplayName: "John Smith",
+		},
+	}
+	db.users[user.ID] = user
+
+	return db, user
+}
+
+func patchBody(operations string) string {
+	return `{"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"], "Operations": [` + operations + `]}`
+}
+
+func TestV2PatchUser(t *testing.T) {
+	tests := []struct {
+		name         string
+		operations   string
+		wantStatus   int
+		wantScimType string
+		want         func(user database.User) database.User
+	}{
+		{
+			name:       "unrelated operation keeps the enterprise user",
+			operations: `{"op": "replace", "path": "active", "value": false}`,
+			wantStatus: http.StatusOK,
+			want: func(user database.User) database.User {
+				user.Active = false
+				return user
+			},
+		},
+		{
+			name:       "replace department keeps the manager",
+			operations: `{"op": "replace", "path": "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:department", "value": "Sales"}`,
+			wantStatus: http.StatusOK,
+			want: func(user database.User) database.User {
+				enterpriseUser := *user.EnterpriseUser
+				enterpriseUser.Department = "Sales"
+				user.EnterpriseUser = &enterpriseUser
+				return user
+			},
+		},
+		{
+			name:         "remove groups",
+			operations:   `{"op": "remove", "path": "groups"}`,
+			wantStatus:   http.StatusBadRequest,
+			wantScimType: "mutability",
+		},
+		{
+			name:         "second primary email",
+			operations:   `{"op": "add", "path": "emails", "value": [{"value": "babs@example.com", "type": "home", "primary": true}]}`,
+			wantStatus:   http.StatusBadRequest,
+			wantScimType: "invalidValue",
+		},
+		{
+			name:         "email type that is not canonical",
+			operations:   `{"op": "replace", "path": "emails[type eq \"work\"].type", "value": "office"}`,
+			wantStatus:   http.StatusBadRequest,
+			wantScimType: "invalidValue",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			db, user := patchFixture()
+			b := bridge.New(db, "https://example.com")
+
+			r := httptest.NewRequest(http.MethodPatch, "/scim/v2/Users/"+user.ID.String(), strings.NewReader(patchBody(tc.operations)))
+			r = r.WithContext(context.WithValue(r.Context(), middleware.User, user))
+			w := httptest.NewRecorder()
+			V2PatchUser(&b)(w, r)
+
+			assert.Equal(t, tc.wantStatus, w.Code)
+
+			if tc.wantScimType != "" {
+				var body map[string]interface{}
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+				assert.Equal(t, tc.wantScimType, body["scimType"])
+				assert.Equal(t, user, db.users[user.ID])
+				return
+			}
+
+			want := tc.want(user)
+			want.Version = db.users[user.ID].Version
+			assert.Equal(t, want, db.users[user.ID])
+		})
+	}
+}
+
+func TestV2PatchUserKeepsConcurrentChanges(t *testing.T) {
+	db, user := patchFixture()
+	b := bridge.New(db, "https://example.com")
+
+	// another request changes the user after the middleware read it
+	concurrent := db.users[user.ID]
+	concurrent.Title = sql.NullString{String: "Tour Guide", Valid: true}
+	concurrent.Version = db.nextVersion()
+	db.users[user.ID] = concurrent
+
+	r := httptest.NewRequest(http.MethodPatch, "/scim/v2/Users/"+user.ID.String(), strings.NewReader(patchBody(`{"op": "replace", "path": "active", "value": false}`)))
+	r = r.WithContext(context.WithValue(r.Context(), middleware.User, user))
+	w := httptest.NewRecorder()
+	V2PatchUser(&b)(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Tour Guide", db.users[user.ID].Title.String)
+	assert.False(t, db.users[user.ID].Active)
+}
+
+func TestV2UpdateUserWithRetrievedUser(t *testing.T) {
+	db, user := patchFixture()
+	db.addGroup("Tour Guides", database.Member{ID: user.ID, Type: database.UserMember})
+	b := bridge.New(db, "https://example.com")
+
+	// the retrieved user includes its groups, which are read-only
+	r := httptest.NewRequest(http.MethodGet, "/scim/v2/Users/"+user.ID.String(), nil)
+	r = r.WithContext(context.WithValue(r.Context(), middleware.User, user))
+	w := httptest.NewRecorder()
+	V2GetUser(&b)(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"groups"`)
+
+	r = httptest.NewRequest(http.MethodPut, "/scim/v2/Users/"+user.ID.String(), strings.NewReader(w.Body.String()))
+	r = r.WithContext(context.WithValue(r.Context(), middleware.User, user))
+	w = httptest.NewRecorder()
+	V2UpdateUser(&b)(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, user.Emails, db.users[user.ID].Emails)
+	if assert.NotNil(t, db.users[user.ID].EnterpriseUser) {
+		assert.Equal(t, user.EnterpriseUser.Department, db.users[user.ID].EnterpriseUser.Department)
+		assert.Equal(t, user.EnterpriseUser.Manager.Value, db.users[user.ID].EnterpriseUser.Manager.Value)
+	}
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/render"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/bridge"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/database"
+	responses2 "github.com/suse-skyscraper/openfga-scim-bridge/v2/internal/responses"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/validation"
+)
+
+// validatedBody reads the body of a POST or PUT request and validates it against the schemas of the
+// resource type. current holds the attributes of the resource being replaced, or nil for a new one.
+// The body is returned so it can be parsed into its payload, together with the attributes of the
+// custom extensions, which have no payload.
+func validatedBody(r *http.Request, bridge *bridge.Bridge, resourceType string, current map[string]interface{}) ([]byte, database.Extensions, render.Renderer) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, nil, responses2.ErrInvalidSyntax(err)
+	}
+
+	var resource map[string]interface{}
+	err = json.Unmarshal(body, &resource)
+	if err != nil {
+		return nil, nil, responses2.ErrInvalidSyntax(err)
+	}
+
+	core, extensions := bridge.ResourceSchemas(resourceType)
+	err = validation.Validate(resource, core, extensions, current)
+	if err != nil {
+		return nil, nil, responses2.ErrValidation(err)
+	}
+
+	return body, customExtensions(bridge, resourceType, resource), nil
+}
+
+// validatePatched validates the attributes of a resource after a patch against the schemas of the
+// resource type, like the body of a PUT request replacing the current attributes.
+func validatePatched(bridge *bridge.Bridge, resourceType string, attributes map[string]interface{}, current map[string]interface{}) error {
+	core, extensions := bridge.ResourceSchemas(resourceType)
+
+	schemas := []interface{}{core.ID}
+	resource := map[string]interface{}{}
+	for key, value := range attributes {
+		resource[key] = value
+
+		for _, extension := range extensions {
+			if strings.EqualFold(key, extension.ID) {
+				schemas = append(schemas, extension.ID)
+			}
+		}
+	}
+	resource["schemas"] = schemas
+
+	return validation.Validate(resource, core, extensions, current)
+}
+
+// customExtensions extracts the attributes of the custom extensions of the resource type from the
+// JSON representation of a resource. Extensions without attributes are left out.
+func customExtensions(bridge *bridge.Bridge, resourceType string, attributes map[string]interface{}) database.Extensions {
+	var extensions database.Extensions
+	for _, s := range bridge.CustomExtensions(resourceType) {
+		for key, value := range attributes {
+			values, ok := value.(map[string]interface{})
+			if !ok || len(values) == 0 || !strings.EqualFold(key, s.ID) {
+				continue
+			}
+
+			if extensions == nil {
+				extensions = database.Extensions{}
+			}
+			extensions[s.ID] = values
+		}
+	}
+
+	return extensions
+}
@@ -0,0 +1,33 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/bridge"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/database"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/schema"
+)
+
+func TestCustomExtensions(t *testing.T) {
+	b := bridge.New(nil, "https://example.com")
+	err := b.RegisterExtension("User", schema.Schema{
+		ID:         "urn:suse:skyscraper:2.0:User",
+		Attributes: []schema.Attribute{{Name: "costCenterRouting", Type: schema.String}},
+	})
+	assert.NoError(t, err)
+
+	attributes := map[string]interface{}{
+		"userName": "bjensen",
+		"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": map[string]interface{}{"employeeNumber": "701984"},
+		"URN:SUSE:SKYSCRAPER:2.0:USER":                               map[string]interface{}{"costCenterRouting": "emea"},
+	}
+
+	assert.Equal(t, database.Extensions{
+		"urn:suse:skyscraper:2.0:User": {"costCenterRouting": "emea"},
+	}, customExtensions(&b, "User", attributes))
+	assert.Nil(t, customExtensions(&b, "Group", attributes))
+	assert.Nil(t, customExtensions(&b, "User", map[string]interface{}{
+		"urn:suse:skyscraper:2.0:User": map[string]interface{}{},
+	}))
+}
@@ -0,0 +1,129 @@
+package sorting
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/database"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/filters"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/schema"
+)
+
+// Attributes are the attribute paths resources can be sorted by, and the URIs of the core schemas
+// that paths may be qualified with.
+type Attributes struct {
+	Schemas []string
+	Paths   []string
+}
+
+// UserAttributes are the attribute paths users can be sorted by.
+var UserAttributes = Attributes{
+	Schemas: []string{schema.UserURN},
+	Paths:   userPaths,
+}
+
+// GroupAttributes are the attribute paths groups can be sorted by.
+var GroupAttributes = Attributes{
+	Schemas: []string{schema.GroupURN},
+	Paths:   groupPaths,
+}
+
+// ResourceAttributes are the attribute paths shared by users and groups, which a search across
+// resource types can be sorted by.
+var ResourceAttributes = Attributes{
+	Schemas: []string{schema.UserURN, schema.GroupURN},
+	Paths:   groupPaths,
+}
+
+var userPaths = []string{
+	"id",
+	"externalId",
+	"userName",
+	"displayName",
+	"name.formatted",
+	"name.familyName",
+	"name.givenName",
+	"nickName",
+	"title",
+	"userType",
+	"preferredLanguage",
+	"locale",
+	"timezone",
+	"emails",
+	"emails.value",
+	"meta.created",
+	"meta.lastModified",
+}
+
+var groupPaths = []string{
+	"id",
+	"externalId",
+	"displayName",
+	"meta.created",
+	"meta.lastModified",
+}
+
+type Params struct {
+	// SortBy is the attribute path as spelled in the list of known attributes, or empty when the
+	// request is not sorted.
+	SortBy    string
+	SortOrder database.SortOrder
+}
+
+// Sort reads the sortBy and sortOrder query parameters of a list request, as described in
+// RFC 7644 section 3.4.2.3. sortBy must be one of the given attribute paths.
+func Sort(r *http.Request, attributes Attributes) (Params, error) {
+	query := r.URL.Query()
+
+	return Parse(query.Get("sortBy"), query.Get("sortOrder"), attributes)
+}
+
+// Parse validates sortBy against the given attribute paths and parses sortOrder, which defaults to
+// ascending. sortBy may be qualified with the URI of one of the given schemas, but not with the URI
+// of an extension, whose attributes cannot be sorted by.
+func Parse(sortBy string, sortOrder string, attributes Attributes) (Params, error) {
+	var params Params
+
+	switch strings.ToLower(sortOrder) {
+	case "", "ascending":
+		params.SortOrder = database.Ascending
+	case "descending":
+		params.SortOrder = database.Descending
+	default:
+		return Params{}, errors.Errorf("sortOrder must be ascending or descending, got %q", sortOrder)
+	}
+
+	if sortBy == "" {
+		return params, nil
+	}
+
+	path, err := filters.ParseAttributePath(sortBy)
+	if err != nil {
+		return Params{}, err
+	}
+
+	if path.URI != "" && !contains(attributes.Schemas, path.URI) {
+		return Params{}, errors.Errorf("cannot sort by %q", sortBy)
+	}
+
+	for _, attribute := range attributes.Paths {
+		if path.Matches(attribute) {
+			params.SortBy = attribute
+			return params, nil
+		}
+	}
+
+	return Params{}, errors.Errorf("cannot sort by %q", sortBy)
+}
+
+// contains reports whether the list of schema URIs contains the URI, ignoring case.
+func contains(uris []string, uri string) bool {
+	for _, candidate := range uris {
+		if strings.EqualFold(candidate, uri) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,79 @@
+package sorting
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/database"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name          string
+		sortBy        string
+		sortOrder     string
+		want          Params
+		errorExpected bool
+	}{
+		{
+			name: "not sorted",
+			want: Params{SortOrder: database.Ascending},
+		},
+		{
+			name:   "canonical attribute name",
+			sortBy: "USERNAME",
+			want:   Params{SortBy: "userName", SortOrder: database.Ascending},
+		},
+		{
+			name:      "descending sub-attribute with schema URI",
+			sortBy:    "urn:ietf:params:scim:schemas:core:2.0:User:name.familyName",
+			sortOrder: "Descending",
+			want:      Params{SortBy: "name.familyName", SortOrder: database.Descending},
+		},
+		{
+			name:          "attribute of another schema",
+			sortBy:        "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:displayName",
+			errorExpected: true,
+		},
+		{
+			name:          "unknown attribute",
+			sortBy:        "password",
+			errorExpected: true,
+		},
+		{
+			name:          "invalid sort order",
+			sortBy:        "userName",
+			sortOrder:     "up",
+			errorExpected: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Parse(tc.sortBy, tc.sortOrder, UserAttributes)
+			if tc.errorExpected {
+				assert.NotNil(t, err)
+			} else {
+				assert.Nil(t, err)
+			}
+
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestParseSchemaURI(t *testing.T) {
+	groupPath := "urn:ietf:params:scim:schemas:core:2.0:Group:displayName"
+	userPath := "urn:ietf:params:scim:schemas:core:2.0:User:displayName"
+
+	_, err := Parse(groupPath, "", GroupAttributes)
+	assert.Nil(t, err)
+	_, err = Parse(userPath, "", GroupAttributes)
+	assert.NotNil(t, err)
+
+	// a search across resource types can be sorted by the attributes of either core schema
+	_, err = Parse(groupPath, "", ResourceAttributes)
+	assert.Nil(t, err)
+	_, err = Parse(userPath, "", ResourceAttributes)
+	assert.Nil(t, err)
+}
@@ -0,0 +1,30 @@
+package patch
+
+import (
+	"fmt"
+)
+
+// The scimType values of the errors returned by PATCH requests, as defined in RFC 7644 section 3.12.
+const (
+	InvalidPath  = "invalidPath"
+	NoTarget     = "noTarget"
+	Mutability   = "mutability"
+	InvalidValue = "invalidValue"
+)
+
+// Error is returned when an operation cannot be applied. ScimType tells the client why.
+type Error struct {
+	ScimType string
+	Detail   string
+}
+
+func (e *Error) Error() string {
+	return e.Detail
+}
+
+func newError(scimType string, format string, args ...interface{}) *Error {
+	return &Error{
+		ScimType: scimType,
+		Detail:   fmt.Sprintf(format, args...),
+	}
+}
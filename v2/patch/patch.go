@@ -0,0 +1,657 @@
+// Package patch applies the operations of a PATCH request to a resource, as described in RFC 7644
+// section 3.5.2.
+package patch
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/filters"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/schema"
+)
+
+const (
+	Add     = "add"
+	Remove  = "remove"
+	Replace = "replace"
+)
+
+// Operation is an operation of a PATCH request. Value is decoded by encoding/json.
+type Operation struct {
+	Op    string
+	Path  string
+	Value interface{}
+}
+
+// Resource is a resource in its JSON representation, as produced by encoding/json, together with the
+// schemas that describe it. Extension attributes are nested below their schema URI.
+type Resource struct {
+	Attributes map[string]interface{}
+	Schema     schema.Schema
+	Extensions []schema.Schema
+}
+
+// Apply applies the operations to the attributes of the resource in order. The operations are
+// applied as a unit: if any of them fails, the attributes are left unchanged.
+func Apply(resource Resource, operations []Operation) error {
+	working := resource
+	working.Attributes = deepCopy(resource.Attributes).(map[string]interface{})
+
+	for _, operation := range operations {
+		err := working.apply(operation)
+		if err != nil {
+			return err
+		}
+	}
+
+	// extensions without attributes are dropped, so that they are not reported in the schemas
+	for _, extension := range working.Extensions {
+		key := findKey(working.Attributes, extension.ID)
+		if nested, ok := working.Attributes[key].(map[string]interface{}); ok && len(nested) == 0 {
+			delete(working.Attributes, key)
+		}
+	}
+
+	for key := range resource.Attributes {
+		delete(resource.Attributes, key)
+	}
+	for key, value := range working.Attributes {
+		resource.Attributes[key] = value
+	}
+
+	return nil
+}
+
+// deepCopy copies the maps and slices of a value decoded by encoding/json.
+func deepCopy(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for key, nested := range v {
+			copied[key] = deepCopy(nested)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, nested := range v {
+			copied[i] = deepCopy(nested)
+		}
+		return copied
+	default:
+		return value
+	}
+}
+
+// target is the location an operation applies to.
+type target struct {
+	// container is the map holding the attribute: the resource itself or one of its extensions.
+	container map[string]interface{}
+	attribute schema.Attribute
+	path      filters.Path
+}
+
+func (r Resource) apply(operation Operation) error {
+	op := operation.Op
+	if op != Add && op != Remove && op != Replace {
+		return newError(InvalidValue, "unsupported operation %q", operation.Op)
+	}
+
+	if operation.Path == "" {
+		if op == Remove {
+			return newError(NoTarget, "remove operations require a path")
+		}
+
+		values, ok := operation.Value.(map[string]interface{})
+		if !ok {
+			return newError(InvalidValue, "operations without a path require an object value")
+		}
+
+		return r.applyMap(op, values)
+	}
+
+	if extension, ok := r.extension(operation.Path); ok {
+		return r.applyExtension(op, extension, operation.Value)
+	}
+
+	t, err := r.resolve(operation.Path)
+	if err != nil {
+		return err
+	}
+
+	if op == Remove {
+		return t.remove(operation.Value)
+	}
+
+	return t.set(op, operation.Value)
+}
+
+// applyMap applies an operation without a path. Every key of the value is treated as the path of an
+// operation of its own.
+func (r Resource) applyMap(op string, values map[string]interface{}) error {
+	for key, value := range values {
+		if strings.EqualFold(key, "schemas") {
+			continue
+		}
+
+		err := r.apply(Operation{Op: op, Path: key, Value: value})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// applyExtension applies an operation targeting an extension schema as a whole.
+func (r Resource) applyExtension(op string, extension schema.Schema, value interface{}) error {
+	if op == Remove {
+		delete(r.Attributes, findKey(r.Attributes, extension.ID))
+		return nil
+	}
+
+	values, ok := value.(map[string]interface{})
+	if !ok {
+		return newError(InvalidValue, "the value of %s must be an object", extension.ID)
+	}
+
+	if op == Replace {
+		delete(r.Attributes, findKey(r.Attributes, extension.ID))
+	}
+
+	for key, value := range values {
+		err := r.apply(Operation{Op: op, Path: extension.ID + ":" + key, Value: value})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r Resource) extension(id string) (schema.Schema, bool) {
+	for _, extension := range r.Extensions {
+		if strings.EqualFold(extension.ID, id) {
+			return extension, true
+		}
+	}
+
+	return schema.Schema{}, false
+}
+
+// resolve finds the attribute and the map holding it for the path of an operation.
+func (r Resource) resolve(text string) (target, error) {
+	path, err := filters.ParsePath(text)
+	if err != nil {
+		return target{}, newError(InvalidPath, "%s", err.Error())
+	}
+
+	var s schema.Schema
+	container := r.Attributes
+	if path.URI == "" || strings.EqualFold(path.URI, r.Schema.ID) {
+		s = r.Schema
+	} else if extension, ok := r.extension(path.URI); ok {
+		s = extension
+
+		key := findKey(r.Attributes, extension.ID)
+		if key == "" {
+			key = extension.ID
+		}
+
+		nested, ok := r.Attributes[key].(map[string]interface{})
+		if !ok {
+			nested = map[string]interface{}{}
+			r.Attributes[key] = nested
+		}
+		container = nested
+	} else {
+		return target{}, newError(InvalidPath, "unknown schema %q", path.URI)
+	}
+
+	attribute, ok := s.Attribute(path.Name)
+	if !ok && s.ID == r.Schema.ID {
+		attribute, ok = schema.FindAttribute(path.Name)
+		ok = ok && isCommonAttribute(attribute.Name)
+	}
+	if !ok {
+		return target{}, newError(InvalidPath, "unknown attribute %q", path.Name)
+	}
+
+	if path.SubAttribute != "" {
+		if _, ok := attribute.SubAttribute(path.SubAttribute); !ok {
+			return target{}, newError(InvalidPath, "unknown attribute %q", path.String())
+		}
+	}
+
+	if path.Filter != nil && !attribute.MultiValued {
+		return target{}, newError(InvalidPath, "value filters require a multi-valued attribute, %q is not", path.Name)
+	}
+
+	return target{container: container, attribute: attribute, path: path}, nil
+}
+
+func isCommonAttribute(name string) bool {
+	for _, attribute := range schema.CommonAttributes {
+		if attribute.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// set applies an add or replace operation.
+func (t target) set(op string, value interface{}) error {
+	key := t.key()
+	current, exists := t.container[key]
+
+	if t.path.SubAttribute == "" && t.path.Filter == nil {
+		err := checkMutability(t.attribute, current, exists)
+		if err != nil {
+			return err
+		}
+
+		if value == nil {
+			// a null value removes the attribute, see RFC 7644 section 3.5.2.3
+			if t.attribute.Required {
+				return newError(Mutability, "required attribute %q cannot be removed", t.attribute.Name)
+			}
+
+			delete(t.container, key)
+			return nil
+		}
+
+		switch {
+		case t.attribute.MultiValued:
+			elements, err := multiValue(t.attribute, value)
+			if err != nil {
+				return err
+			}
+
+			if op == Add {
+				existing, _ := current.([]interface{})
+				elements = appendMissing(existing, elements)
+			}
+
+			t.container[key] = elements
+		case t.attribute.Type == schema.Complex:
+			values, ok := value.(map[string]interface{})
+			if !ok {
+				return newError(InvalidValue, "the value of %q must be an object", t.attribute.Name)
+			}
+
+			nested, _ := current.(map[string]interface{})
+			merged, err := merge(t.attribute, nested, values)
+			if err != nil {
+				return err
+			}
+
+			t.container[key] = merged
+		default:
+			err := checkType(t.attribute, value)
+			if err != nil {
+				return err
+			}
+
+			t.container[key] = value
+		}
+
+		return nil
+	}
+
+	if t.attribute.Mutability == schema.ReadOnly {
+		return newError(Mutability, "attribute %q is read-only", t.attribute.Name)
+	}
+
+	if subAttribute, ok := t.attribute.SubAttribute(t.path.SubAttribute); ok && subAttribute.Mutability == schema.ReadOnly {
+		return newError(Mutability, "attribute %q is read-only", t.path.String())
+	}
+
+	if !t.attribute.MultiValued {
+		// a sub-attribute of a single-valued complex attribute, e.g. name.givenName
+		subAttribute, _ := t.attribute.SubAttribute(t.path.SubAttribute)
+		nested, _ := current.(map[string]interface{})
+		merged, err := merge(t.attribute, nested, map[string]interface{}{subAttribute.Name: value})
+		if err != nil {
+			return err
+		}
+
+		t.container[key] = merged
+		return nil
+	}
+
+	elements, _ := current.([]interface{})
+	matches, err := t.matches(elements)
+	if err != nil {
+		return err
+	}
+
+	if len(matches) == 0 {
+		// the filter describes the element to add, e.g. emails[type eq "work"].value
+		element := map[string]interface{}{}
+		if t.path.Filter != nil && !equalities(t.path.Filter, element) {
+			return newError(NoTarget, "no value of %q matches the filter", t.attribute.Name)
+		}
+
+		elements = append(elements, element)
+		matches = []int{len(elements) - 1}
+	}
+
+	for _, i := range matches {
+		element, _ := elements[i].(map[string]interface{})
+
+		values, ok := value.(map[string]interface{})
+		if t.path.SubAttribute != "" {
+			subAttribute, _ := t.attribute.SubAttribute(t.path.SubAttribute)
+			values, ok = map[string]interface{}{subAttribute.Name: value}, true
+		} else if !ok {
+			return newError(InvalidValue, "the value of %q must be an object", t.attribute.Name)
+		}
+
+		merged, err := merge(t.attribute, element, values)
+		if err != nil {
+			return err
+		}
+
+		elements[i] = merged
+	}
+
+	t.container[key] = elements
+	return nil
+}
+
+// remove applies a remove operation. Multi-valued attributes without a filter remove the elements
+// listed in the value, or every element if there is no value.
+func (t target) remove(value interface{}) error {
+	if t.attribute.Mutability == schema.ReadOnly {
+		return newError(Mutability, "attribute %q is read-only", t.attribute.Name)
+	}
+
+	if subAttribute, ok := t.attribute.SubAttribute(t.path.SubAttribute); ok && subAttribute.Mutability == schema.ReadOnly {
+		return newError(Mutability, "attribute %q is read-only", t.path.String())
+	}
+
+	key := t.key()
+	current, exists := t.container[key]
+	if !exists && t.path.Filter != nil {
+		return newError(NoTarget, "no value of %q matches the filter", t.attribute.Name)
+	} else if !exists {
+		return nil
+	}
+
+	if t.attribute.Mutability == schema.Immutable {
+		return newError(Mutability, "attribute %q cannot be removed", t.attribute.Name)
+	} else if t.attribute.Required && t.path.SubAttribute == "" && t.path.Filter == nil {
+		return newError(Mutability, "required attribute %q cannot be removed", t.attribute.Name)
+	}
+
+	if !t.attribute.MultiValued {
+		if t.path.SubAttribute == "" {
+			delete(t.container, key)
+			return nil
+		}
+
+		if nested, ok := current.(map[string]interface{}); ok {
+			delete(nested, findKey(nested, t.path.SubAttribute))
+			if len(nested) == 0 {
+				delete(t.container, key)
+			}
+		}
+
+		return nil
+	}
+
+	elements, _ := current.([]interface{})
+	if t.path.Filter == nil && t.path.SubAttribute == "" && value != nil {
+		removed, err := multiValue(t.attribute, value)
+		if err != nil {
+			return err
+		}
+
+		elements = removeElements(elements, removed)
+	} else {
+		matches, err := t.matches(elements)
+		if err != nil {
+			return err
+		}
+
+		if t.path.Filter != nil && len(matches) == 0 {
+			return newError(NoTarget, "no value of %q matches the filter", t.attribute.Name)
+		}
+
+		remaining := make([]interface{}, 0, len(elements))
+		for i, element := range elements {
+			if !containsIndex(matches, i) {
+				remaining = append(remaining, element)
+				continue
+			}
+
+			if nested, ok := element.(map[string]interface{}); ok && t.path.SubAttribute != "" {
+				delete(nested, findKey(nested, t.path.SubAttribute))
+				remaining = append(remaining, nested)
+			}
+		}
+		elements = remaining
+	}
+
+	if len(elements) == 0 {
+		delete(t.container, key)
+	} else {
+		t.container[key] = elements
+	}
+
+	return nil
+}
+
+// matches returns the indexes of the elements selected by the filter of the path, or of every element
+// if there is no filter.
+func (t target) matches(elements []interface{}) ([]int, error) {
+	var matches []int
+	for i, element := range elements {
+		attributes, ok := element.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		match, err := filters.Evaluate(t.path.Filter, elementResource(attributes))
+		if err != nil {
+			return nil, newError(InvalidPath, "%s", err.Error())
+		} else if match {
+			matches = append(matches, i)
+		}
+	}
+
+	return matches, nil
+}
+
+// key returns the key of the attribute in the container, using the canonical name for new attributes.
+func (t target) key() string {
+	if key := findKey(t.container, t.attribute.Name); key != "" {
+		return key
+	}
+
+	return t.attribute.Name
+}
+
+type elementResource map[string]interface{}
+
+func (e elementResource) Attributes() map[string]interface{} {
+	return e
+}
+
+func checkMutability(attribute schema.Attribute, current interface{}, exists bool) error {
+	switch attribute.Mutability {
+	case schema.ReadOnly:
+		return newError(Mutability, "attribute %q is read-only", attribute.Name)
+	case schema.Immutable:
+		if exists && current != nil {
+			return newError(Mutability, "attribute %q is immutable", attribute.Name)
+		}
+	}
+
+	return nil
+}
+
+// checkType verifies that the value matches the type of a simple attribute.
+func checkType(attribute schema.Attribute, value interface{}) error {
+	if !attribute.Accepts(value) {
+		return newError(InvalidValue, "attribute %q must be of type %s", attribute.Name, attribute.Type)
+	}
+
+	return nil
+}
+
+// multiValue returns the elements of a value for a multi-valued attribute, accepting a single
+// element as well as a list.
+func multiValue(attribute schema.Attribute, value interface{}) ([]interface{}, error) {
+	elements, ok := value.([]interface{})
+	if !ok {
+		elements = []interface{}{value}
+	}
+
+	result := make([]interface{}, 0, len(elements))
+	for _, element := range elements {
+		if attribute.Type != schema.Complex {
+			err := checkType(attribute, element)
+			if err != nil {
+				return nil, err
+			}
+
+			result = append(result, element)
+			continue
+		}
+
+		values, ok := element.(map[string]interface{})
+		if !ok {
+			return nil, newError(InvalidValue, "the values of %q must be objects", attribute.Name)
+		}
+
+		merged, err := merge(attribute, nil, values)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, merged)
+	}
+
+	return result, nil
+}
+
+// merge sets the values on a copy of the complex value current, validating them against the
+// sub-attributes of the attribute. Null values remove the sub-attribute.
+func merge(attribute schema.Attribute, current map[string]interface{}, values map[string]interface{}) (map[string]interface{}, error) {
+	merged := map[string]interface{}{}
+	for key, value := range current {
+		merged[key] = value
+	}
+
+	for key, value := range values {
+		subAttribute, ok := attribute.SubAttribute(key)
+		if !ok {
+			return nil, newError(InvalidValue, "unknown attribute %q", attribute.Name+"."+key)
+		}
+
+		if existing := findKey(merged, subAttribute.Name); existing != "" {
+			delete(merged, existing)
+		}
+
+		if value == nil {
+			continue
+		}
+
+		err := checkType(subAttribute, value)
+		if err != nil {
+			return nil, err
+		}
+
+		merged[subAttribute.Name] = value
+	}
+
+	return merged, nil
+}
+
+// equalities sets the attributes compared with "eq" in a filter joined by "and" on the element, and
+// reports whether the filter consists of such comparisons only.
+func equalities(filter filters.Expression, element map[string]interface{}) bool {
+	switch e := filter.(type) {
+	case filters.AttributeExpression:
+		if e.Operator != filters.Eq || e.Path.SubAttribute != "" || e.Path.URI != "" || e.Value == nil {
+			return false
+		}
+
+		element[e.Path.Name] = e.Value
+		return true
+	case filters.LogicalExpression:
+		return e.Operator == filters.And && equalities(e.Left, element) && equalities(e.Right, element)
+	default:
+		return false
+	}
+}
+
+func appendMissing(elements []interface{}, added []interface{}) []interface{} {
+	result := append([]interface{}{}, elements...)
+	for _, element := range added {
+		if !containsElement(result, element) {
+			result = append(result, element)
+		}
+	}
+
+	return result
+}
+
+func removeElements(elements []interface{}, removed []interface{}) []interface{} {
+	var result []interface{}
+	for _, element := range elements {
+		if !containsElement(removed, element) {
+			result = append(result, element)
+		}
+	}
+
+	return result
+}
+
+// containsElement reports whether the element is part of the list. Complex elements are compared by
+// their "value" sub-attribute if they have one.
+func containsElement(elements []interface{}, element interface{}) bool {
+	for _, candidate := range elements {
+		if reflect.DeepEqual(identity(candidate), identity(element)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func identity(element interface{}) interface{} {
+	if attributes, ok := element.(map[string]interface{}); ok {
+		if value, ok := attributes["value"]; ok {
+			return value
+		}
+	}
+
+	return element
+}
+
+func containsIndex(indexes []int, index int) bool {
+	for _, i := range indexes {
+		if i == index {
+			return true
+		}
+	}
+
+	return false
+}
+
+// findKey returns the key of the attribute in the map, ignoring case.
+func findKey(attributes map[string]interface{}, name string) string {
+	if _, ok := attributes[name]; ok {
+		return name
+	}
+
+	for key := range attributes {
+		if strings.EqualFold(key, name) {
+			return key
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,224 @@
+package patch
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/schema"
+)
+
+const user = `{
+	"id": "2819c223-7f76-453a-919d-413861904646",
+	"userName": "bjensen@example.com",
+	"active": true,
+	"name": {"givenName": "Barbara", "familyName": "Jensen"},
+	"emails": [
+		{"value": "bjensen@example.com", "type": "work", "primary": true},
+		{"value": "babs@jensen.org", "type": "home", "primary": false}
+	],
+	"meta": {"resourceType": "User"}
+}`
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name      string
+		operation Operation
+		want      string
+		scimType  string
+	}{
+		{
+			name:      "replace simple attribute",
+			operation: Operation{Op: "replace", Path: "active", Value: false},
+			want:      `{"active": false}`,
+		},
+		{
+			name:      "replace sub-attribute",
+			operation: Operation{Op: "replace", Path: "name.givenName", Value: "Babs"},
+			want:      `{"name": {"givenName": "Babs", "familyName": "Jensen"}}`,
+		},
+		{
+			name:      "replace without path",
+			operation: Operation{Op: "replace", Value: map[string]interface{}{"displayName": "Babs Jensen", "active": false}},
+			want:      `{"displayName": "Babs Jensen", "active": false}`,
+		},
+		{
+			name:      "replace filtered sub-attribute",
+			operation: Operation{Op: "replace", Path: `emails[type eq "work"].value`, Value: "barbara@example.com"},
+			want: `{"emails": [
+				{"value": "barbara@example.com", "type": "work", "primary": true},
+				{"value": "babs@jensen.org", "type": "home", "primary": false}
+			]}`,
+		},
+		{
+			name:      "add filtered sub-attribute without a match",
+			operation: Operation{Op: "add", Path: `emails[type eq "other"].value`, Value: "b@example.org"},
+			want: `{"emails": [
+				{"value": "bjensen@example.com", "type": "work", "primary": true},
+				{"value": "babs@jensen.org", "type": "home", "primary": false},
+				{"value": "b@example.org", "type": "other"}
+			]}`,
+		},
+		{
+			name: "add to multi-valued attribute",
+			operation: Operation{Op: "add", Path: "emails", Value: []interface{}{
+				map[string]interface{}{"value": "babs@jensen.org", "type": "home"},
+				map[string]interface{}{"value": "b@example.org", "type": "other"},
+			}},
+			want: `{"emails": [
+				{"value": "bjensen@example.com", "type": "work", "primary": true},
+				{"value": "babs@jensen.org", "type": "home", "primary": false},
+				{"value": "b@example.org", "type": "other"}
+			]}`,
+		},
+		{
+			name:      "remove filtered values",
+			operation: Operation{Op: "remove", Path: `emails[type eq "home"]`},
+			want:      `{"emails": [{"value": "bjensen@example.com", "type": "work", "primary": true}]}`,
+		},
+		{
+			name:      "remove sub-attribute",
+			operation: Operation{Op: "remove", Path: "name.givenName"},
+			want:      `{"name": {"familyName": "Jensen"}}`,
+		},
+		{
+			name:      "add extension attribute",
+			operation: Operation{Op: "add", Path: schema.EnterpriseUserURN + ":department", Value: "Tour Operations"},
+			want:      `{"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": {"department": "Tour Operations"}}`,
+		},
+		{
+			name: "add extension without path",
+			operation: Operation{Op: "add", Value: map[string]interface{}{
+				schema.EnterpriseUserURN: map[string]interface{}{
+					"manager": map[string]interface{}{"value": "26118915-6090-4610-87e4-49d8ca9f808d"},
+				},
+			}},
+			want: `{"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": {
+				"manager": {"value": "26118915-6090-4610-87e4-49d8ca9f808d"}
+			}}`,
+		},
+		{
+			name:      "unknown attribute",
+			operation: Operation{Op: "replace", Path: "unknown", Value: "value"},
+			scimType:  InvalidPath,
+		},
+		{
+			name:      "malformed path",
+			operation: Operation{Op: "replace", Path: `emails[type eq]`, Value: "value"},
+			scimType:  InvalidPath,
+		},
+		{
+			name:      "read-only attribute",
+			operation: Operation{Op: "replace", Path: "id", Value: "a6c7a2f0-8c1f-4a4a-9d36-d1a3b2a5c1b0"},
+			scimType:  Mutability,
+		},
+		{
+			name:      "remove unassigned read-only attribute",
+			operation: Operation{Op: "remove", Path: "groups"},
+			scimType:  Mutability,
+		},
+		{
+			name:      "required attribute",
+			operation: Operation{Op: "remove", Path: "userName"},
+			scimType:  Mutability,
+		},
+		{
+			name:      "invalid value type",
+			operation: Operation{Op: "replace", Path: "active", Value: "yes"},
+			scimType:  InvalidValue,
+		},
+		{
+			name:      "remove without path",
+			operation: Operation{Op: "remove"},
+			scimType:  NoTarget,
+		},
+		{
+			name:      "replace without a match",
+			operation: Operation{Op: "replace", Path: `emails[value co "example.net"].type`, Value: "work"},
+			scimType:  NoTarget,
+		},
+		{
+			name:      "remove without a match",
+			operation: Operation{Op: "remove", Path: `emails[value co "example.net"]`},
+			scimType:  NoTarget,
+		},
+		{
+			name:      "remove filtered sub-attribute without a match",
+			operation: Operation{Op: "remove", Path: `emails[type eq "other"].primary`},
+			scimType:  NoTarget,
+		},
+		{
+			name:      "remove from missing attribute with a filter",
+			operation: Operation{Op: "remove", Path: `phoneNumbers[type eq "work"]`},
+			scimType:  NoTarget,
+		},
+		{
+			name:      "unsupported operation",
+			operation: Operation{Op: "move", Path: "active", Value: true},
+			scimType:  InvalidValue,
+		},
+		{
+			name:      "replace required attribute with null",
+			operation: Operation{Op: "replace", Path: "userName", Value: nil},
+			scimType:  Mutability,
+		},
+		{
+			name:      "replace required attribute with null without path",
+			operation: Operation{Op: "replace", Value: map[string]interface{}{"userName": nil}},
+			scimType:  Mutability,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var attributes map[string]interface{}
+			assert.Nil(t, json.Unmarshal([]byte(user), &attributes))
+
+			resource := Resource{
+				Attributes: attributes,
+				Schema:     schema.User,
+				Extensions: []schema.Schema{schema.EnterpriseUser},
+			}
+
+			err := Apply(resource, []Operation{tc.operation})
+			if tc.scimType != "" {
+				var patchErr *Error
+				assert.ErrorAs(t, err, &patchErr)
+				assert.Equal(t, tc.scimType, patchErr.ScimType)
+				return
+			}
+
+			assert.Nil(t, err)
+
+			var want map[string]interface{}
+			assert.Nil(t, json.Unmarshal([]byte(user), &want))
+			var changes map[string]interface{}
+			assert.Nil(t, json.Unmarshal([]byte(tc.want), &changes))
+			for key, value := range changes {
+				want[key] = value
+			}
+
+			assert.Equal(t, want, resource.Attributes)
+		})
+	}
+}
+
+func TestApplyLeavesResourceUnchangedOnError(t *testing.T) {
+	var attributes map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(user), &attributes))
+
+	resource := Resource{Attributes: attributes, Schema: schema.User}
+	err := Apply(resource, []Operation{
+		{Op: "replace", Path: "name.givenName", Value: "Babs"},
+		{Op: "remove", Path: `emails[type eq "home"]`},
+		{Op: "replace", Path: "active", Value: "yes"},
+	})
+
+	var patchErr *Error
+	assert.ErrorAs(t, err, &patchErr)
+	assert.Equal(t, InvalidValue, patchErr.ScimType)
+
+	var want map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(user), &want))
+	assert.Equal(t, want, resource.Attributes)
+}
@@ -0,0 +1,31 @@
+package payloads
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// BulkOperation is an operation of a bulk request. Data is the body of the equivalent single request.
+type BulkOperation struct {
+	Method  string          `json:"method"`
+	BulkID  string          `json:"bulkId"`
+	Version string          `json:"version"`
+	Path    string          `json:"path"`
+	Data    json.RawMessage `json:"data"`
+}
+
+type BulkPayload struct {
+	Schemas      []string        `json:"schemas"`
+	FailOnErrors int             `json:"failOnErrors"`
+	Operations   []BulkOperation `json:"Operations"`
+}
+
+func BulkPayloadFromJSON(r io.Reader) (*BulkPayload, error) {
+	var payload BulkPayload
+	err := decodeJSON(r, &payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &payload, nil
+}
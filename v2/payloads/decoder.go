@@ -3,8 +3,13 @@ package payloads
 import (
 	"encoding/json"
 	"io"
+
+	"github.com/pkg/errors"
 )
 
+// ErrNullOperation is returned for PATCH requests listing null operations.
+var ErrNullOperation = errors.New("PATCH operations must not be null")
+
 func decodeJSON(r io.Reader, v interface{}) error {
 	defer func(dst io.Writer, src io.Reader) {
 		_, _ = io.Copy(dst, src)
@@ -5,8 +5,17 @@ import (
 )
 
 type CreateScimGroupPayload struct {
-	Schemas     []string `json:"schemas"`
-	DisplayName string   `json:"displayName"`
+	Schemas     []string             `json:"schemas"`
+	ExternalID  string               `json:"externalId"`
+	DisplayName string               `json:"displayName"`
+	Members     []GroupMemberPayload `json:"members"`
+}
+
+type GroupMemberPayload struct {
+	Value   string `json:"value"`
+	Ref     string `json:"$ref,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Display string `json:"display,omitempty"`
 }
 
 func GroupPayloadFromJSON(r io.Reader) (*CreateScimGroupPayload, error) {
@@ -0,0 +1,120 @@
+package payloads
+
+import (
+	"strings"
+
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/filters"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/schema"
+)
+
+// normalizeOperation works around the quirks of identity providers in PATCH operations, most
+// notably Azure AD:
+//   - operation names are capitalized, e.g. "Replace"
+//   - boolean attributes are sent as strings, e.g. "False"
+//   - the path is omitted and the value is a map of attribute paths, e.g. {"name.givenName": "Babs"}
+//
+// extensions are the custom extension schemas of the resource type, whose attributes are normalized
+// like those of the schemas of the schema package.
+func normalizeOperation(op string, path string, value interface{}, extensions []schema.Schema) (string, interface{}) {
+	op = strings.ToLower(op)
+
+	if path != "" {
+		if attribute, ok := attributeAt(path, extensions); ok {
+			value = normalizeValue(attribute, value)
+		}
+
+		return op, value
+	}
+
+	values, ok := value.(map[string]interface{})
+	if !ok {
+		return op, value
+	}
+
+	for key, nested := range values {
+		if extension, ok := nested.(map[string]interface{}); ok {
+			if s, ok := findSchema(key, extensions); ok {
+				for name, extensionValue := range extension {
+					if attribute, ok := schemaAttribute(s, name); ok {
+						extension[name] = normalizeValue(attribute, extensionValue)
+					}
+				}
+				continue
+			}
+		}
+
+		if attribute, ok := attributeAt(key, extensions); ok {
+			values[key] = normalizeValue(attribute, nested)
+		}
+	}
+
+	return op, value
+}
+
+// attributeAt finds the attribute referenced by the path of an operation, ignoring its value filter.
+// Paths without a schema URI are looked up in the schemas of the schema package.
+func attributeAt(path string, extensions []schema.Schema) (schema.Attribute, bool) {
+	parsed, err := filters.ParsePath(path)
+	if err != nil {
+		return schema.Attribute{}, false
+	}
+
+	if parsed.URI != "" {
+		if s, ok := findSchema(parsed.URI, extensions); ok {
+			return schemaAttribute(s, parsed.AttributePath.String())
+		}
+	}
+
+	return schema.FindAttribute(parsed.AttributePath.String())
+}
+
+// findSchema finds a schema of the schema package or a custom extension by its URI.
+func findSchema(id string, extensions []schema.Schema) (schema.Schema, bool) {
+	for _, s := range append(schema.Schemas(), extensions...) {
+		if strings.EqualFold(s.ID, id) {
+			return s, true
+		}
+	}
+
+	return schema.Schema{}, false
+}
+
+// schemaAttribute finds the attribute at a path without schema URI, e.g. "name.givenName", in the
+// schema.
+func schemaAttribute(s schema.Schema, path string) (schema.Attribute, bool) {
+	name, subAttribute, hasSubAttribute := strings.Cut(path, ".")
+
+	attribute, ok := s.Attribute(name)
+	if !ok || !hasSubAttribute {
+		return attribute, ok
+	}
+
+	return attribute.SubAttribute(subAttribute)
+}
+
+// normalizeValue converts string booleans to booleans in the value of the attribute, including the
+// sub-attributes of complex values.
+func normalizeValue(attribute schema.Attribute, value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		if attribute.Type == schema.Boolean {
+			if strings.EqualFold(v, "true") {
+				return true
+			} else if strings.EqualFold(v, "false") {
+				return false
+			}
+		}
+	case map[string]interface{}:
+		for key, nested := range v {
+			if subAttribute, ok := attribute.SubAttribute(key); ok {
+				v[key] = normalizeValue(subAttribute, nested)
+			}
+		}
+	case []interface{}:
+		for i, element := range v {
+			v[i] = normalizeValue(attribute, element)
+		}
+	}
+
+	return value
+}
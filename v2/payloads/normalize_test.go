@@ -0,0 +1,118 @@
+package payloads
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/schema"
+)
+
+func TestUserPatchPayloadFromJSONNormalizesOperations(t *testing.T) {
+	tests := []struct {
+		name      string
+		operation string
+		want      UserPatchOperation
+	}{
+		{
+			name:      "capitalized operation",
+			operation: `{"op": "Replace", "path": "displayName", "value": "Babs"}`,
+			want:      UserPatchOperation{Op: "replace", Path: "displayName", Value: "Babs"},
+		},
+		{
+			name:      "string boolean",
+			operation: `{"op": "Replace", "path": "active", "value": "False"}`,
+			want:      UserPatchOperation{Op: "replace", Path: "active", Value: false},
+		},
+		{
+			name:      "string boolean in a sub-attribute",
+			operation: `{"op": "Add", "path": "emails", "value": [{"value": "b@example.org", "primary": "True"}]}`,
+			want: UserPatchOperation{Op: "add", Path: "emails", Value: []interface{}{
+				map[string]interface{}{"value": "b@example.org", "primary": true},
+			}},
+		},
+		{
+			name:      "string boolean in a filtered path",
+			operation: `{"op": "replace", "path": "emails[type eq \"work\"].primary", "value": "true"}`,
+			want:      UserPatchOperation{Op: "replace", Path: `emails[type eq "work"].primary`, Value: true},
+		},
+		{
+			name:      "path-less value map",
+			operation: `{"op": "Replace", "value": {"active": "False", "name.givenName": "Babs"}}`,
+			want: UserPatchOperation{Op: "replace", Value: map[string]interface{}{
+				"active":         false,
+				"name.givenName": "Babs",
+			}},
+		},
+		{
+			name:      "string that is not a boolean attribute",
+			operation: `{"op": "replace", "path": "title", "value": "False"}`,
+			want:      UserPatchOperation{Op: "replace", Path: "title", Value: "False"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			body := `{"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"], "Operations": [` + tc.operation + `]}`
+
+			payload, err := UserPatchPayloadFromJSON(strings.NewReader(body))
+			assert.Nil(t, err)
+			assert.Equal(t, []*UserPatchOperation{&tc.want}, payload.Operations)
+		})
+	}
+}
+
+func TestUserPatchPayloadFromJSONNormalizesExtensions(t *testing.T) {
+	extension := schema.Schema{
+		ID:   "urn:example:params:scim:schemas:extension:2.0:User",
+		Name: "ExampleUser",
+		Attributes: []schema.Attribute{
+			{Name: "remote", Type: schema.Boolean},
+			{Name: "active", Type: schema.String},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		operation string
+		want      UserPatchOperation
+	}{
+		{
+			name:      "extension attribute path",
+			operation: `{"op": "replace", "path": "urn:example:params:scim:schemas:extension:2.0:User:remote", "value": "True"}`,
+			want:      UserPatchOperation{Op: "replace", Path: "urn:example:params:scim:schemas:extension:2.0:User:remote", Value: true},
+		},
+		{
+			name:      "path-less extension value",
+			operation: `{"op": "replace", "value": {"urn:example:params:scim:schemas:extension:2.0:User": {"remote": "False", "active": "False"}}}`,
+			want: UserPatchOperation{Op: "replace", Value: map[string]interface{}{
+				"urn:example:params:scim:schemas:extension:2.0:User": map[string]interface{}{"remote": false, "active": "False"},
+			}},
+		},
+		{
+			name:      "enterprise user attribute",
+			operation: `{"op": "replace", "path": "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:department", "value": "True"}`,
+			want:      UserPatchOperation{Op: "replace", Path: "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:department", Value: "True"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			body := `{"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"], "Operations": [` + tc.operation + `]}`
+
+			payload, err := UserPatchPayloadFromJSON(strings.NewReader(body), extension)
+			assert.Nil(t, err)
+			assert.Equal(t, []*UserPatchOperation{&tc.want}, payload.Operations)
+		})
+	}
+}
+
+func TestPatchPayloadFromJSONRejectsNullOperations(t *testing.T) {
+	body := `{"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"], "Operations": [null]}`
+
+	_, err := UserPatchPayloadFromJSON(strings.NewReader(body))
+	assert.Equal(t, ErrNullOperation, err)
+
+	_, err = GroupPatchPayloadFromJSON(strings.NewReader(body))
+	assert.Equal(t, ErrNullOperation, err)
+}
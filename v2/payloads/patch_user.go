@@ -1,15 +1,15 @@
 package payloads
 
-import "io"
+import (
+	"io"
 
-type UserPatch struct {
-	Active bool `json:"active"`
-}
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/schema"
+)
 
 type UserPatchOperation struct {
-	Op    string    `json:"op"`
-	Path  string    `json:"path"`
-	Value UserPatch `json:"value"`
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
 }
 
 type UserPatchPayload struct {
@@ -17,12 +17,22 @@ type UserPatchPayload struct {
 	Operations []*UserPatchOperation `json:"Operations"`
 }
 
-func UserPatchPayloadFromJSON(r io.Reader) (*UserPatchPayload, error) {
+// UserPatchPayloadFromJSON decodes a PATCH request. String booleans are converted to booleans for
+// the boolean attributes of the schemas of the schema package, and of the custom extensions.
+func UserPatchPayloadFromJSON(r io.Reader, extensions ...schema.Schema) (*UserPatchPayload, error) {
 	var payload UserPatchPayload
 	err := decodeJSON(r, &payload)
 	if err != nil {
 		return nil, err
 	}
 
+	for _, operation := range payload.Operations {
+		if operation == nil {
+			return nil, ErrNullOperation
+		}
+
+		operation.Op, operation.Value = normalizeOperation(operation.Op, operation.Path, operation.Value, extensions)
+	}
+
 	return &payload, nil
 }
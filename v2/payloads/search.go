@@ -0,0 +1,28 @@
+package payloads
+
+import (
+	"io"
+)
+
+// SearchRequest is the body of a query sent with POST to a ".search" endpoint, as described in
+// RFC 7644 section 3.4.3. StartIndex and Count are nil when omitted.
+type SearchRequest struct {
+	Schemas            []string `json:"schemas"`
+	Attributes         []string `json:"attributes"`
+	ExcludedAttributes []string `json:"excludedAttributes"`
+	Filter             string   `json:"filter"`
+	SortBy             string   `json:"sortBy"`
+	SortOrder          string   `json:"sortOrder"`
+	StartIndex         *int     `json:"startIndex"`
+	Count              *int     `json:"count"`
+}
+
+func SearchRequestFromJSON(r io.Reader) (*SearchRequest, error) {
+	var payload SearchRequest
+	err := decodeJSON(r, &payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &payload, nil
+}
@@ -6,25 +6,74 @@ import (
 
 type UserEmail struct {
 	Value   string `json:"value"`
-	Type    string `json:"type"`
-	Primary bool   `json:"primary"`
+	Display string `json:"display,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// MultiValuedAttribute is an element of a multi-valued user attribute such as phoneNumbers, ims,
+// photos, entitlements, roles or x509Certificates.
+type MultiValuedAttribute struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// UserAddress is a physical mailing address of a user.
+type UserAddress struct {
+	Formatted     string `json:"formatted,omitempty"`
+	StreetAddress string `json:"streetAddress,omitempty"`
+	Locality      string `json:"locality,omitempty"`
+	Region        string `json:"region,omitempty"`
+	PostalCode    string `json:"postalCode,omitempty"`
+	Country       string `json:"country,omitempty"`
+	Type          string `json:"type,omitempty"`
+	Primary       bool   `json:"primary,omitempty"`
+}
+
+// EnterpriseUserManager references the manager of a user in the enterprise user extension.
+type EnterpriseUserManager struct {
+	Value       string `json:"value,omitempty"`
+	Ref         string `json:"$ref,omitempty"`
+	DisplayName string `json:"displayName,omitempty"`
+}
+
+// EnterpriseUser holds the attributes of the enterprise user extension, as defined in RFC 7643
+// section 4.3.
+type EnterpriseUser struct {
+	EmployeeNumber string                 `json:"employeeNumber,omitempty"`
+	CostCenter     string                 `json:"costCenter,omitempty"`
+	Organization   string                 `json:"organization,omitempty"`
+	Division       string                 `json:"division,omitempty"`
+	Department     string                 `json:"department,omitempty"`
+	Manager        *EnterpriseUserManager `json:"manager,omitempty"`
 }
 
 type CreateScimUserPayload struct {
-	Schemas           []string          `json:"schemas"`
-	Username          string            `json:"userName"`
-	ID                string            `json:"id"`
-	ExternalID        string            `json:"externalId"`
-	Name              map[string]string `json:"name"`
-	Emails            []UserEmail       `json:"emails"`
-	Nickname          string            `json:"nickName"`
-	DisplayName       string            `json:"displayName"`
-	Active            bool              `json:"active"`
-	PreferredLanguage string            `json:"preferredLanguage"`
-	Locale            string            `json:"locale"`
-	Title             string            `json:"title"`
-	UserType          string            `json:"userType"`
-	Timezone          string            `json:"timezone"`
+	Schemas           []string               `json:"schemas"`
+	Username          string                 `json:"userName"`
+	ID                string                 `json:"id"`
+	ExternalID        string                 `json:"externalId"`
+	Name              map[string]string      `json:"name"`
+	Emails            []UserEmail            `json:"emails"`
+	Nickname          string                 `json:"nickName"`
+	DisplayName       string                 `json:"displayName"`
+	Active            bool                   `json:"active"`
+	PreferredLanguage string                 `json:"preferredLanguage"`
+	Locale            string                 `json:"locale"`
+	Title             string                 `json:"title"`
+	UserType          string                 `json:"userType"`
+	Timezone          string                 `json:"timezone"`
+	ProfileURL        string                 `json:"profileUrl"`
+	PhoneNumbers      []MultiValuedAttribute `json:"phoneNumbers"`
+	Ims               []MultiValuedAttribute `json:"ims"`
+	Photos            []MultiValuedAttribute `json:"photos"`
+	Addresses         []UserAddress          `json:"addresses"`
+	Entitlements      []MultiValuedAttribute `json:"entitlements"`
+	Roles             []MultiValuedAttribute `json:"roles"`
+	X509Certificates  []MultiValuedAttribute `json:"x509Certificates"`
+	EnterpriseUser    *EnterpriseUser        `json:"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User,omitempty"`
 }
 
 func Parse(r io.Reader) (*CreateScimUserPayload, error) {
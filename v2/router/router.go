@@ -12,11 +12,32 @@ import (
 type AuthorizationMiddleware func(next http.Handler) http.Handler
 
 func Hook(r *chi.Mux, bridge *bridge.Bridge, authHandler AuthorizationMiddleware) {
+	// the operations of bulk requests are dispatched to the resource endpoints, relative to /scim/v2
+	bulkResources := chi.NewRouter()
+	bulkResources.Group(resources(bridge))
+
 	r.Route("/scim/v2", func(r chi.Router) {
 		r.Use(authHandler)
 
+		r.Get("/ServiceProviderConfig", server.V2GetServiceProviderConfig(bridge))
+		r.Get("/Schemas", server.V2ListSchemas(bridge))
+		r.Get("/Schemas/{id}", server.V2GetSchema(bridge))
+		r.Get("/ResourceTypes", server.V2ListResourceTypes(bridge))
+		r.Get("/ResourceTypes/{name}", server.V2GetResourceType(bridge))
+
+		r.Post("/Bulk", server.V2Bulk(bridge, bulkResources))
+		r.Post("/.search", server.V2Search(bridge))
+
+		r.Group(resources(bridge))
+	})
+}
+
+// resources registers the endpoints of the users and groups.
+func resources(bridge *bridge.Bridge) func(r chi.Router) {
+	return func(r chi.Router) {
 		r.Get("/Users", server.V2ListUsers(bridge))
 		r.Post("/Users", server.V2CreateUser(bridge))
+		r.Post("/Users/.search", server.V2SearchUsers(bridge))
 		r.Route("/Users/{id}", func(r chi.Router) {
 			scimUserCtx := middleware.UserCtx(bridge)
 
@@ -30,14 +51,16 @@ func Hook(r *chi.Mux, bridge *bridge.Bridge, authHandler AuthorizationMiddleware
 
 		r.Get("/Groups", server.V2ListGroups(bridge))
 		r.Post("/Groups", server.V2CreateGroup(bridge))
+		r.Post("/Groups/.search", server.V2SearchGroups(bridge))
 		r.Route("/Groups/{id}", func(r chi.Router) {
 			scimGroupCtx := middleware.GroupCtx(bridge)
 
 			r.Use(scimGroupCtx)
 
 			r.Get("/", server.V2GetGroup(bridge))
+			r.Put("/", server.V2UpdateGroup(bridge))
 			r.Patch("/", server.V2PatchGroup(bridge))
 			r.Delete("/", server.V2DeleteGroup(bridge))
 		})
-	})
+	}
 }
@@ -0,0 +1,70 @@
+package schema
+
+const (
+	UserURN           = "urn:ietf:params:scim:schemas:core:2.0:User"
+	GroupURN          = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	EnterpriseUserURN = "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"
+	SchemaURN         = "urn:ietf:params:scim:schemas:core:2.0:Schema"
+	ResourceTypeURN   = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+)
+
+// CommonAttributes are part of every resource, but not of any schema. See RFC 7643 section 3.1.
+var CommonAttributes = []Attribute{
+	{
+		Name:        "id",
+		Type:        String,
+		Description: "Unique identifier for the SCIM resource as defined by the service provider.",
+		CaseExact:   true,
+		Mutability:  ReadOnly,
+		Returned:    Always,
+		Uniqueness:  Server,
+	},
+	{
+		Name:        "externalId",
+		Type:        String,
+		Description: "Identifier for the resource as defined by the provisioning client.",
+		CaseExact:   true,
+	},
+	{
+		Name:        "meta",
+		Type:        Complex,
+		Description: "Metadata of the resource.",
+		Mutability:  ReadOnly,
+		SubAttributes: []Attribute{
+			{Name: "resourceType", Type: String, Description: "Name of the resource type of the resource.", CaseExact: true, Mutability: ReadOnly},
+			{Name: "created", Type: DateTime, Description: "Date and time the resource was added to the service provider.", Mutability: ReadOnly},
+			{Name: "lastModified", Type: DateTime, Description: "Most recent date and time the resource was modified.", Mutability: ReadOnly},
+			{Name: "location", Type: Reference, Description: "URI of the resource being returned.", CaseExact: true, Mutability: ReadOnly, ReferenceTypes: []string{"uri"}},
+			{Name: "version", Type: String, Description: "Version of the resource being returned.", CaseExact: true, Mutability: ReadOnly},
+		},
+	},
+}
+
+// Schemas returns the schemas supported by the bridge.
+func Schemas() []Schema {
+	return []Schema{User, Group, EnterpriseUser}
+}
+
+// ResourceTypes returns the resource types supported by the bridge.
+func ResourceTypes() []ResourceType {
+	return []ResourceType{UserResourceType, GroupResourceType}
+}
+
+var UserResourceType = ResourceType{
+	ID:          "User",
+	Name:        "User",
+	Endpoint:    "/Users",
+	Description: "User Account",
+	Schema:      UserURN,
+	SchemaExtensions: []SchemaExtension{
+		{Schema: EnterpriseUserURN, Required: false},
+	},
+}
+
+var GroupResourceType = ResourceType{
+	ID:          "Group",
+	Name:        "Group",
+	Endpoint:    "/Groups",
+	Description: "Group",
+	Schema:      GroupURN,
+}
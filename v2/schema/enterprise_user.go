@@ -0,0 +1,40 @@
+package schema
+
+// EnterpriseUser is the enterprise user extension, as defined in RFC 7643 section 4.3.
+var EnterpriseUser = Schema{
+	ID:          EnterpriseUserURN,
+	Name:        "EnterpriseUser",
+	Description: "Enterprise User",
+	Attributes: []Attribute{
+		{Name: "employeeNumber", Type: String, Description: "Numeric or alphanumeric identifier assigned to a person."},
+		{Name: "costCenter", Type: String, Description: "Identifies the name of a cost center."},
+		{Name: "organization", Type: String, Description: "Identifies the name of an organization."},
+		{Name: "division", Type: String, Description: "Identifies the name of a division."},
+		{Name: "department", Type: String, Description: "Identifies the name of a department."},
+		{
+			Name:        "manager",
+			Type:        Complex,
+			Description: "The User's manager.",
+			SubAttributes: []Attribute{
+				{
+					Name:        "value",
+					Type:        String,
+					Description: "The id of the SCIM resource representing the User's manager.",
+					Required:    true,
+				},
+				{
+					Name:           "$ref",
+					Type:           Reference,
+					Description:    "The URI of the SCIM resource representing the User's manager.",
+					ReferenceTypes: []string{"User"},
+				},
+				{
+					Name:        "displayName",
+					Type:        String,
+					Description: "The displayName of the User's manager.",
+					Mutability:  ReadOnly,
+				},
+			},
+		},
+	},
+}
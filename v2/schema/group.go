@@ -0,0 +1,50 @@
+package schema
+
+// Group is the core group schema, as defined in RFC 7643 section 4.2.
+var Group = Schema{
+	ID:          GroupURN,
+	Name:        "Group",
+	Description: "Group",
+	Attributes: []Attribute{
+		{
+			Name:        "displayName",
+			Type:        String,
+			Description: "A human-readable name for the Group.",
+			Required:    true,
+		},
+		{
+			Name:        "members",
+			Type:        Complex,
+			MultiValued: true,
+			Description: "A list of members of the Group.",
+			SubAttributes: []Attribute{
+				{
+					Name:        "value",
+					Type:        String,
+					Description: "Identifier of the member of this Group.",
+					Mutability:  Immutable,
+				},
+				{
+					Name:           "$ref",
+					Type:           Reference,
+					Description:    "The URI corresponding to a SCIM resource that is a member of this Group.",
+					Mutability:     Immutable,
+					ReferenceTypes: []string{"User", "Group"},
+				},
+				{
+					Name:            "type",
+					Type:            String,
+					Description:     "A label indicating the type of resource, e.g., 'User' or 'Group'.",
+					CanonicalValues: []string{"User", "Group"},
+					Mutability:      Immutable,
+				},
+				{
+					Name:        "display",
+					Type:        String,
+					Description: "A human-readable name for the member.",
+					Mutability:  ReadOnly,
+				},
+			},
+		},
+	},
+}
@@ -0,0 +1,54 @@
+package schema
+
+import (
+	"strings"
+)
+
+// FindAttribute finds the attribute at the path, e.g. "emails.value", in the common attributes and
+// the core and extension schemas. The path is matched ignoring case.
+func FindAttribute(path string) (Attribute, bool) {
+	name, subAttribute, hasSubAttribute := strings.Cut(path, ".")
+
+	attribute, ok := find(CommonAttributes, name)
+	if !ok {
+		for _, s := range Schemas() {
+			if attribute, ok = s.Attribute(name); ok {
+				break
+			}
+		}
+	}
+
+	if !ok || !hasSubAttribute {
+		return attribute, ok
+	}
+
+	return attribute.SubAttribute(subAttribute)
+}
+
+// IsCaseExact reports whether string values of the attribute at the path are compared
+// case-sensitively. Unknown attributes are compared case-insensitively.
+func IsCaseExact(path string) bool {
+	attribute, ok := FindAttribute(path)
+	return ok && attribute.CaseExact
+}
+
+// AlwaysReturned lists the top-level attributes that are returned regardless of the attributes and
+// excludedAttributes parameters of a request.
+func AlwaysReturned() []string {
+	var names []string
+	for _, attribute := range CommonAttributes {
+		if attribute.Returned == Always {
+			names = append(names, attribute.Name)
+		}
+	}
+
+	for _, s := range Schemas() {
+		for _, attribute := range s.Attributes {
+			if attribute.Returned == Always {
+				names = append(names, attribute.Name)
+			}
+		}
+	}
+
+	return names
+}
@@ -0,0 +1,63 @@
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindAttribute(t *testing.T) {
+	tests := []struct {
+		path      string
+		want      string
+		wantFound bool
+	}{
+		{path: "userName", want: "userName", wantFound: true},
+		{path: "USERNAME", want: "userName", wantFound: true},
+		{path: "emails.value", want: "value", wantFound: true},
+		{path: "meta.lastModified", want: "lastModified", wantFound: true},
+		{path: "members.$ref", want: "$ref", wantFound: true},
+		{path: "emails.unknown", wantFound: false},
+		{path: "unknown", wantFound: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.path, func(t *testing.T) {
+			got, found := FindAttribute(tc.path)
+			assert.Equal(t, tc.wantFound, found)
+			assert.Equal(t, tc.want, got.Name)
+		})
+	}
+}
+
+func TestIsCaseExact(t *testing.T) {
+	assert.True(t, IsCaseExact("id"))
+	assert.True(t, IsCaseExact("externalId"))
+	assert.True(t, IsCaseExact("x509Certificates.value"))
+	assert.False(t, IsCaseExact("userName"))
+	assert.False(t, IsCaseExact("emails.value"))
+	assert.False(t, IsCaseExact("unknown"))
+}
+
+func TestAlwaysReturned(t *testing.T) {
+	assert.Equal(t, []string{"id"}, AlwaysReturned())
+}
+
+func TestAttributeMarshalJSON(t *testing.T) {
+	attribute, _ := User.Attribute("password")
+
+	data, err := json.Marshal(attribute)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{
+		"name": "password",
+		"type": "string",
+		"multiValued": false,
+		"description": "The User's cleartext password.",
+		"required": false,
+		"caseExact": false,
+		"mutability": "writeOnly",
+		"returned": "never",
+		"uniqueness": "none"
+	}`, string(data))
+}
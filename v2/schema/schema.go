@@ -0,0 +1,241 @@
+package schema
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// AttributeType is the data type of an attribute, as defined in RFC 7643 section 2.3.
+type AttributeType int
+
+const (
+	String AttributeType = iota
+	Boolean
+	Decimal
+	Integer
+	DateTime
+	Binary
+	Reference
+	Complex
+)
+
+var attributeTypes = []string{"string", "boolean", "decimal", "integer", "dateTime", "binary", "reference", "complex"}
+
+func (t AttributeType) MarshalText() ([]byte, error) {
+	return []byte(attributeTypes[t]), nil
+}
+
+func (t AttributeType) String() string {
+	return attributeTypes[t]
+}
+
+// Mutability describes whether and how an attribute can be modified. The zero value is ReadWrite.
+type Mutability int
+
+const (
+	ReadWrite Mutability = iota
+	ReadOnly
+	Immutable
+	WriteOnly
+)
+
+var mutabilities = []string{"readWrite", "readOnly", "immutable", "writeOnly"}
+
+func (m Mutability) MarshalText() ([]byte, error) {
+	return []byte(mutabilities[m]), nil
+}
+
+func (m Mutability) String() string {
+	return mutabilities[m]
+}
+
+// Returned describes when an attribute is part of a response. The zero value is Default.
+type Returned int
+
+const (
+	Default Returned = iota
+	Always
+	Never
+	Request
+)
+
+var returnedValues = []string{"default", "always", "never", "request"}
+
+func (r Returned) MarshalText() ([]byte, error) {
+	return []byte(returnedValues[r]), nil
+}
+
+func (r Returned) String() string {
+	return returnedValues[r]
+}
+
+// Uniqueness describes how the service provider enforces uniqueness of an attribute. The zero value
+// is None.
+type Uniqueness int
+
+const (
+	None Uniqueness = iota
+	Server
+	Global
+)
+
+var uniquenessValues = []string{"none", "server", "global"}
+
+func (u Uniqueness) MarshalText() ([]byte, error) {
+	return []byte(uniquenessValues[u]), nil
+}
+
+func (u Uniqueness) String() string {
+	return uniquenessValues[u]
+}
+
+// Attribute describes an attribute of a schema, as defined in RFC 7643 section 7.
+type Attribute struct {
+	Name            string        `json:"name"`
+	Type            AttributeType `json:"type"`
+	SubAttributes   []Attribute   `json:"subAttributes,omitempty"`
+	MultiValued     bool          `json:"multiValued"`
+	Description     string        `json:"description"`
+	Required        bool          `json:"required"`
+	CanonicalValues []string      `json:"canonicalValues,omitempty"`
+	CaseExact       bool          `json:"caseExact"`
+	Mutability      Mutability    `json:"mutability"`
+	Returned        Returned      `json:"returned"`
+	Uniqueness      Uniqueness    `json:"uniqueness"`
+	ReferenceTypes  []string      `json:"referenceTypes,omitempty"`
+}
+
+// Accepts reports whether the value, as decoded by encoding/json, is of the type of the attribute.
+// The sub-attributes of complex values are not checked.
+func (a Attribute) Accepts(value interface{}) bool {
+	switch a.Type {
+	case String, Reference, Binary:
+		_, ok := value.(string)
+		return ok
+	case DateTime:
+		text, ok := value.(string)
+		if !ok {
+			return false
+		}
+
+		_, err := time.Parse(time.RFC3339, text)
+		return err == nil
+	case Boolean:
+		_, ok := value.(bool)
+		return ok
+	case Decimal:
+		_, ok := value.(float64)
+		return ok
+	case Integer:
+		number, ok := value.(float64)
+		return ok && number == float64(int64(number))
+	case Complex:
+		_, ok := value.(map[string]interface{})
+		return ok
+	}
+
+	return false
+}
+
+// SubAttribute finds a sub-attribute by name, ignoring case.
+func (a Attribute) SubAttribute(name string) (Attribute, bool) {
+	return find(a.SubAttributes, name)
+}
+
+// Schema describes a resource or an extension of a resource.
+type Schema struct {
+	ID          string
+	Name        string
+	Description string
+	Attributes  []Attribute
+}
+
+// Attribute finds an attribute by name, ignoring case.
+func (s Schema) Attribute(name string) (Attribute, bool) {
+	return find(s.Attributes, name)
+}
+
+// attributeName is the ATTRNAME rule of RFC 7643 section 2.1, or the "$ref" sub-attribute of references.
+var attributeName = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9_-]*|\$ref)$`)
+
+// Validate checks that the schema is well-formed: it has a URN, and its attributes have valid names
+// and characteristics. Like in the core schemas, complex attributes have sub-attributes, which are
+// not complex themselves.
+func (s Schema) Validate() error {
+	if !strings.HasPrefix(strings.ToLower(s.ID), "urn:") {
+		return errors.Errorf("the id of schema %q is not a URN", s.ID)
+	}
+
+	if len(s.Attributes) == 0 {
+		return errors.Errorf("schema %q has no attributes", s.ID)
+	}
+
+	return validateAttributes(s.ID+":", s.Attributes, false)
+}
+
+func validateAttributes(prefix string, attributes []Attribute, subAttributes bool) error {
+	names := map[string]bool{}
+	for _, attribute := range attributes {
+		path := prefix + attribute.Name
+
+		if !attributeName.MatchString(attribute.Name) {
+			return errors.Errorf("invalid attribute name %q", path)
+		}
+
+		if names[strings.ToLower(attribute.Name)] {
+			return errors.Errorf("duplicate attribute %q", path)
+		}
+		names[strings.ToLower(attribute.Name)] = true
+
+		if uint(attribute.Type) >= uint(len(attributeTypes)) || uint(attribute.Mutability) >= uint(len(mutabilities)) ||
+			uint(attribute.Returned) >= uint(len(returnedValues)) || uint(attribute.Uniqueness) >= uint(len(uniquenessValues)) {
+			return errors.Errorf("attribute %q has an invalid characteristic", path)
+		}
+
+		switch {
+		case attribute.Type == Complex && subAttributes:
+			return errors.Errorf("sub-attribute %q cannot be complex", path)
+		case attribute.Type == Complex && len(attribute.SubAttributes) == 0:
+			return errors.Errorf("complex attribute %q has no sub-attributes", path)
+		case attribute.Type != Complex && len(attribute.SubAttributes) > 0:
+			return errors.Errorf("attribute %q has sub-attributes but is not complex", path)
+		}
+
+		err := validateAttributes(path+".", attribute.SubAttributes, true)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SchemaExtension references an extension schema of a resource type.
+type SchemaExtension struct {
+	Schema   string `json:"schema"`
+	Required bool   `json:"required"`
+}
+
+// ResourceType describes a type of resource and the schemas it is made of, as defined in RFC 7643
+// section 6.
+type ResourceType struct {
+	ID               string
+	Name             string
+	Endpoint         string
+	Description      string
+	Schema           string
+	SchemaExtensions []SchemaExtension
+}
+
+func find(attributes []Attribute, name string) (Attribute, bool) {
+	for _, attribute := range attributes {
+		if strings.EqualFold(attribute.Name, name) {
+			return attribute, true
+		}
+	}
+
+	return Attribute{}, false
+}
@@ -0,0 +1,73 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchemaValidate(t *testing.T) {
+	for _, s := range Schemas() {
+		assert.NoError(t, s.Validate(), s.ID)
+	}
+
+	tests := []struct {
+		name   string
+		schema Schema
+		want   string
+	}{
+		{
+			name:   "not a URN",
+			schema: Schema{ID: "skyscraper", Attributes: []Attribute{{Name: "costCenter"}}},
+			want:   `the id of schema "skyscraper" is not a URN`,
+		},
+		{
+			name:   "no attributes",
+			schema: Schema{ID: "urn:suse:skyscraper:2.0:User"},
+			want:   `schema "urn:suse:skyscraper:2.0:User" has no attributes`,
+		},
+		{
+			name:   "invalid name",
+			schema: Schema{ID: "urn:suse:skyscraper:2.0:User", Attributes: []Attribute{{Name: "cost center"}}},
+			want:   `invalid attribute name "urn:suse:skyscraper:2.0:User:cost center"`,
+		},
+		{
+			name:   "duplicate attribute",
+			schema: Schema{ID: "urn:suse:skyscraper:2.0:User", Attributes: []Attribute{{Name: "costCenter"}, {Name: "CostCenter"}}},
+			want:   `duplicate attribute "urn:suse:skyscraper:2.0:User:CostCenter"`,
+		},
+		{
+			name:   "invalid type",
+			schema: Schema{ID: "urn:suse:skyscraper:2.0:User", Attributes: []Attribute{{Name: "costCenter", Type: AttributeType(42)}}},
+			want:   `attribute "urn:suse:skyscraper:2.0:User:costCenter" has an invalid characteristic`,
+		},
+		{
+			name:   "complex without sub-attributes",
+			schema: Schema{ID: "urn:suse:skyscraper:2.0:User", Attributes: []Attribute{{Name: "routing", Type: Complex}}},
+			want:   `complex attribute "urn:suse:skyscraper:2.0:User:routing" has no sub-attributes`,
+		},
+		{
+			name: "nested complex",
+			schema: Schema{ID: "urn:suse:skyscraper:2.0:User", Attributes: []Attribute{{
+				Name:          "routing",
+				Type:          Complex,
+				SubAttributes: []Attribute{{Name: "target", Type: Complex, SubAttributes: []Attribute{{Name: "value"}}}},
+			}}},
+			want: `sub-attribute "urn:suse:skyscraper:2.0:User:routing.target" cannot be complex`,
+		},
+		{
+			name:   "sub-attributes of a simple attribute",
+			schema: Schema{ID: "urn:suse:skyscraper:2.0:User", Attributes: []Attribute{{Name: "costCenter", SubAttributes: []Attribute{{Name: "value"}}}}},
+			want:   `attribute "urn:suse:skyscraper:2.0:User:costCenter" has sub-attributes but is not complex`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.schema.Validate()
+			if assert.Error(t, err) {
+				assert.Equal(t, tc.want, err.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package schema
+
+// User is the core user schema, as defined in RFC 7643 section 4.1.
+var User = Schema{
+	ID:          UserURN,
+	Name:        "User",
+	Description: "User Account",
+	Attributes: []Attribute{
+		{
+			Name:        "userName",
+			Type:        String,
+			Description: "Unique identifier for the User, typically used by the user to directly authenticate to the service provider.",
+			Required:    true,
+			Uniqueness:  Server,
+		},
+		{
+			Name:        "name",
+			Type:        Complex,
+			Description: "The components of the user's real name.",
+			SubAttributes: []Attribute{
+				{Name: "formatted", Type: String, Description: "The full name, including all middle names, titles, and suffixes as appropriate, formatted for display."},
+				{Name: "familyName", Type: String, Description: "The family name of the User, or last name in most Western languages."},
+				{Name: "givenName", Type: String, Description: "The given name of the User, or first name in most Western languages."},
+				{Name: "middleName", Type: String, Description: "The middle name(s) of the User."},
+				{Name: "honorificPrefix", Type: String, Description: "The honorific prefix(es) of the User, or title in most Western languages."},
+				{Name: "honorificSuffix", Type: String, Description: "The honorific suffix(es) of the User, or suffix in most Western languages."},
+			},
+		},
+		{Name: "displayName", Type: String, Description: "The name of the User, suitable for display to end-users."},
+		{Name: "nickName", Type: String, Description: "The casual way to address the user in real life."},
+		{
+			Name:           "profileUrl",
+			Type:           Reference,
+			Description:    "A fully qualified URL pointing to a page representing the User's online profile.",
+			ReferenceTypes: []string{"external"},
+		},
+		{Name: "title", Type: String, Description: "The user's title, such as \"Vice President.\""},
+		{Name: "userType", Type: String, Description: "Used to identify the relationship between the organization and the user."},
+		{Name: "preferredLanguage", Type: String, Description: "Indicates the User's preferred written or spoken language."},
+		{Name: "locale", Type: String, Description: "Used to indicate the User's default location for purposes of localizing items such as currency, date time format, or numerical representations."},
+		{Name: "timezone", Type: String, Description: "The User's time zone in the 'Olson' time zone database format, e.g., 'America/Los_Angeles'."},
+		{Name: "active", Type: Boolean, Description: "A Boolean value indicating the User's administrative status."},
+		{
+			Name:        "password",
+			Type:        String,
+			Description: "The User's cleartext password.",
+			Mutability:  WriteOnly,
+			Returned:    Never,
+		},
+		multiValued("emails", String, "Email addresses for the user.", []string{"work", "home", "other"}),
+		multiValued("phoneNumbers", String, "Phone numbers for the User.", []string{"work", "home", "mobile", "fax", "pager", "other"}),
+		multiValued("ims", String, "Instant messaging addresses for the User.", []string{"aim", "gtalk", "icq", "xmpp", "msn", "skype", "qq", "yahoo"}),
+		multiValued("photos", Reference, "URLs of photos of the User.", []string{"photo", "thumbnail"}),
+		{
+			Name:        "addresses",
+			Type:        Complex,
+			MultiValued: true,
+			Description: "A physical mailing address for this User.",
+			SubAttributes: []Attribute{
+				{Name: "formatted", Type: String, Description: "The full mailing address, formatted for display or use with a mailing label."},
+				{Name: "streetAddress", Type: String, Description: "The full street address component."},
+				{Name: "locality", Type: String, Description: "The city or locality component."},
+				{Name: "region", Type: String, Description: "The state or region component."},
+				{Name: "postalCode", Type: String, Description: "The zip code or postal code component."},
+				{Name: "country", Type: String, Description: "The country name component."},
+				{Name: "type", Type: String, Description: "A label indicating the attribute's function, e.g., 'work' or 'home'.", CanonicalValues: []string{"work", "home", "other"}},
+				{Name: "primary", Type: Boolean, Description: "A Boolean value indicating the 'primary' or preferred address."},
+			},
+		},
+		{
+			Name:        "groups",
+			Type:        Complex,
+			MultiValued: true,
+			Description: "A list of groups to which the user belongs, either through direct membership or through nested groups.",
+			Mutability:  ReadOnly,
+			SubAttributes: []Attribute{
+				{Name: "value", Type: String, Description: "The identifier of the User's group.", Mutability: ReadOnly},
+				{Name: "$ref", Type: Reference, Description: "The URI of the corresponding 'Group' resource to which the user belongs.", Mutability: ReadOnly, ReferenceTypes: []string{"User", "Group"}},
+				{Name: "display", Type: String, Description: "A human-readable name, primarily used for display purposes.", Mutability: ReadOnly},
+				{Name: "type", Type: String, Description: "A label indicating the attribute's function, e.g., 'direct' or 'indirect'.", CanonicalValues: []string{"direct", "indirect"}, Mutability: ReadOnly},
+			},
+		},
+		multiValued("entitlements", String, "A list of entitlements for the User that represent a thing the User has.", nil),
+		multiValued("roles", String, "A list of roles for the User that collectively represent who the User is.", nil),
+		multiValued("x509Certificates", Binary, "A list of certificates issued to the User.", nil),
+	},
+}
+
+// multiValued returns a multi-valued complex attribute with the value, display, type and primary
+// sub-attributes shared by most multi-valued attributes of the user schema.
+func multiValued(name string, valueType AttributeType, description string, types []string) Attribute {
+	value := Attribute{Name: "value", Type: valueType, Description: "The value of the attribute."}
+	if valueType == Binary {
+		value.CaseExact = true
+	}
+	if valueType == Reference {
+		value.ReferenceTypes = []string{"external"}
+	}
+
+	return Attribute{
+		Name:        name,
+		Type:        Complex,
+		MultiValued: true,
+		Description: description,
+		SubAttributes: []Attribute{
+			value,
+			{Name: "display", Type: String, Description: "A human-readable name, primarily used for display purposes."},
+			{Name: "type", Type: String, Description: "A label indicating the attribute's function.", CanonicalValues: types},
+			{Name: "primary", Type: Boolean, Description: "A Boolean value indicating the 'primary' or preferred attribute value."},
+		},
+	}
+}
@@ -0,0 +1,293 @@
+// Package validation checks the resources of POST and PUT requests against their schemas, as
+// described in RFC 7643 section 2.
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/schema"
+)
+
+// The scimType values of validation errors, as defined in RFC 7644 section 3.12.
+const (
+	InvalidSyntax = "invalidSyntax"
+	InvalidValue  = "invalidValue"
+	Mutability    = "mutability"
+)
+
+// Error is returned when a resource doesn't conform to its schemas. Path is the attribute the error
+// is about, e.g. "emails.type", if any.
+type Error struct {
+	ScimType string
+	Path     string
+	Detail   string
+}
+
+func (e *Error) Error() string {
+	if e.Path == "" {
+		return e.Detail
+	}
+
+	return fmt.Sprintf("%s: %s", e.Path, e.Detail)
+}
+
+func newError(scimType string, path string, format string, args ...interface{}) *Error {
+	return &Error{
+		ScimType: scimType,
+		Path:     path,
+		Detail:   fmt.Sprintf(format, args...),
+	}
+}
+
+// Validate checks a resource in its JSON representation, as decoded by encoding/json, against the
+// core schema and the extensions of its resource type. Extension attributes are nested below their
+// schema URI.
+//
+// current holds the attributes of the resource being replaced, or nil for a new resource. Immutable
+// attributes cannot change once they are set. Read-only attributes are rejected for a new resource,
+// and ignored when replacing one, as required by RFC 7644 section 3.5.1, so that clients can send a
+// resource back as they received it. The id and meta attributes and read-only sub-attributes, e.g.
+// the display name of group members, are always ignored.
+func Validate(resource map[string]interface{}, core schema.Schema, extensions []schema.Schema, current map[string]interface{}) error {
+	err := validateSchemas(resource, core, extensions)
+	if err != nil {
+		return err
+	}
+
+	attributes := append(append([]schema.Attribute{}, schema.CommonAttributes...), core.Attributes...)
+	values := map[string]interface{}{}
+
+	for _, key := range sortedKeys(resource) {
+		value := resource[key]
+
+		switch {
+		case strings.EqualFold(key, "schemas"):
+			continue
+		case isServiceProviderAttribute(key):
+			continue
+		case strings.Contains(key, ":"):
+			extension, ok := findSchema(extensions, key)
+			if !ok {
+				return newError(InvalidSyntax, key, "unknown schema extension")
+			}
+
+			if value == nil {
+				continue
+			}
+
+			extensionValues, ok := value.(map[string]interface{})
+			if !ok {
+				return newError(InvalidValue, key, "the value of a schema extension must be an object")
+			}
+
+			currentValues, _ := lookup(current, extension.ID).(map[string]interface{})
+
+			err = validateAttributes(extension.ID+":", extension.Attributes, extensionValues, currentValues, current != nil)
+			if err != nil {
+				return err
+			}
+		default:
+			values[key] = value
+		}
+	}
+
+	return validateAttributes("", attributes, values, current, current != nil)
+}
+
+// validateSchemas checks that the schemas attribute lists the core schema, and only known schemas.
+func validateSchemas(resource map[string]interface{}, core schema.Schema, extensions []schema.Schema) error {
+	elements, ok := lookup(resource, "schemas").([]interface{})
+	if !ok {
+		return newError(InvalidSyntax, "schemas", "the schemas attribute must list the schemas of the resource")
+	}
+
+	hasCore := false
+	for _, element := range elements {
+		uri, ok := element.(string)
+		if !ok {
+			return newError(InvalidValue, "schemas", "schema URIs must be strings")
+		}
+
+		if strings.EqualFold(uri, core.ID) {
+			hasCore = true
+		} else if _, ok := findSchema(extensions, uri); !ok {
+			return newError(InvalidValue, "schemas", "unknown schema %q", uri)
+		}
+	}
+
+	if !hasCore {
+		return newError(InvalidSyntax, "schemas", "the schemas attribute must contain %q", core.ID)
+	}
+
+	return nil
+}
+
+// validateAttributes checks the values of attributes, or of the sub-attributes of a complex value.
+// prefix is the path of the complex value, ignoreReadOnly reports whether read-only attributes are
+// skipped, i.e. for sub-attributes and for the attributes of a replaced resource.
+func validateAttributes(prefix string, attributes []schema.Attribute, values map[string]interface{}, current map[string]interface{}, ignoreReadOnly bool) error {
+	for _, key := range sortedKeys(values) {
+		path := prefix + key
+
+		attribute, ok := findAttribute(attributes, key)
+		if !ok {
+			return newError(InvalidSyntax, path, "unknown attribute")
+		}
+
+		value := values[key]
+		if isUnassigned(value) {
+			continue
+		}
+
+		if attribute.Mutability == schema.ReadOnly && ignoreReadOnly {
+			continue
+		}
+
+		err := validateValue(path, attribute, value, lookup(current, attribute.Name))
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, attribute := range attributes {
+		if attribute.Required && isUnassigned(lookup(values, attribute.Name)) {
+			return newError(InvalidValue, prefix+attribute.Name, "the attribute is required")
+		}
+	}
+
+	return nil
+}
+
+func validateValue(path string, attribute schema.Attribute, value interface{}, current interface{}) error {
+	switch attribute.Mutability {
+	case schema.ReadOnly:
+		return newError(Mutability, path, "the attribute is read-only")
+	case schema.Immutable:
+		if current != nil && !reflect.DeepEqual(value, current) {
+			return newError(Mutability, path, "the attribute is immutable")
+		}
+	}
+
+	elements, isList := value.([]interface{})
+	if !attribute.MultiValued {
+		if isList {
+			return newError(InvalidValue, path, "the attribute is single-valued")
+		}
+
+		return validateSingleValue(path, attribute, value, current)
+	}
+
+	if !isList {
+		return newError(InvalidValue, path, "the attribute is multi-valued, its value must be a list")
+	}
+
+	primaries := 0
+	for _, element := range elements {
+		// the elements of multi-valued attributes have no identity to compare them with
+		err := validateSingleValue(path, attribute, element, nil)
+		if err != nil {
+			return err
+		}
+
+		if values, ok := element.(map[string]interface{}); ok && lookup(values, "primary") == true {
+			primaries++
+		}
+	}
+
+	if primaries > 1 {
+		return newError(InvalidValue, path+".primary", "only one value can be primary")
+	}
+
+	return nil
+}
+
+func validateSingleValue(path string, attribute schema.Attribute, value interface{}, current interface{}) error {
+	if !attribute.Accepts(value) {
+		return newError(InvalidValue, path, "the value must be of type %s", attribute.Type)
+	}
+
+	if attribute.Type == schema.Complex {
+		currentValues, _ := current.(map[string]interface{})
+		return validateAttributes(path+".", attribute.SubAttributes, value.(map[string]interface{}), currentValues, true)
+	}
+
+	if text, ok := value.(string); ok && len(attribute.CanonicalValues) > 0 {
+		for _, canonical := range attribute.CanonicalValues {
+			if strings.EqualFold(text, canonical) {
+				return nil
+			}
+		}
+
+		return newError(InvalidValue, path, "the value must be one of %s", strings.Join(attribute.CanonicalValues, ", "))
+	}
+
+	return nil
+}
+
+// isServiceProviderAttribute reports whether the attribute is a read-only common attribute, which is
+// assigned by the service provider.
+func isServiceProviderAttribute(name string) bool {
+	attribute, ok := findAttribute(schema.CommonAttributes, name)
+	return ok && attribute.Mutability == schema.ReadOnly
+}
+
+// isUnassigned reports whether the value leaves the attribute unassigned, as described in RFC 7643
+// section 2.5.
+func isUnassigned(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+
+	elements, ok := value.([]interface{})
+	return ok && len(elements) == 0
+}
+
+// lookup returns the value of the attribute, ignoring the case of its name.
+func lookup(values map[string]interface{}, name string) interface{} {
+	if value, ok := values[name]; ok {
+		return value
+	}
+
+	for key, value := range values {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+
+	return nil
+}
+
+func findAttribute(attributes []schema.Attribute, name string) (schema.Attribute, bool) {
+	for _, attribute := range attributes {
+		if strings.EqualFold(attribute.Name, name) {
+			return attribute, true
+		}
+	}
+
+	return schema.Attribute{}, false
+}
+
+func findSchema(schemas []schema.Schema, id string) (schema.Schema, bool) {
+	for _, s := range schemas {
+		if strings.EqualFold(s.ID, id) {
+			return s, true
+		}
+	}
+
+	return schema.Schema{}, false
+}
+
+// sortedKeys returns the keys of the values in a stable order, so that the first error of a resource
+// is always the same.
+func sortedKeys(values map[string]interface{}) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,189 @@
+package validation
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/suse-skyscraper/openfga-scim-bridge/v2/schema"
+)
+
+func decode(t *testing.T, data string) map[string]interface{} {
+	var values map[string]interface{}
+	if err := json.Unmarshal([]byte(data), &values); err != nil {
+		t.Fatal(err)
+	}
+
+	return values
+}
+
+func TestValidateUser(t *testing.T) {
+	current := `{"id": "2819c223", "userName": "bjensen", "meta": {"resourceType": "User"}}`
+
+	tests := []struct {
+		name     string
+		resource string
+		current  string
+		err      *Error
+	}{
+		{
+			name:     "valid user",
+			resource: `{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"], "userName": "bjensen", "name": {"givenName": "Barbara"}, "emails": [{"value": "bjensen@example.com", "type": "Work", "primary": true}, {"value": "babs@example.com", "primary": false}]}`,
+		},
+		{
+			name:     "valid enterprise user",
+			resource: `{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User", "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"], "userName": "bjensen", "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": {"employeeNumber": "701984", "manager": {"value": "26118915", "displayName": "John Smith"}}}`,
+		},
+		{
+			name:     "id and meta are ignored",
+			resource: `{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"], "id": "2819c223", "userName": "bjensen", "meta": {"resourceType": "User"}}`,
+			current:  current,
+		},
+		{
+			name:     "missing schemas",
+			resource: `{"userName": "bjensen"}`,
+			err:      &Error{ScimType: InvalidSyntax, Path: "schemas", Detail: "the schemas attribute must list the schemas of the resource"},
+		},
+		{
+			name:     "missing core schema",
+			resource: `{"schemas": ["urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"], "userName": "bjensen"}`,
+			err:      &Error{ScimType: InvalidSyntax, Path: "schemas", Detail: `the schemas attribute must contain "urn:ietf:params:scim:schemas:core:2.0:User"`},
+		},
+		{
+			name:     "unknown schema",
+			resource: `{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User", "urn:example:unknown"], "userName": "bjensen"}`,
+			err:      &Error{ScimType: InvalidValue, Path: "schemas", Detail: `unknown schema "urn:example:unknown"`},
+		},
+		{
+			name:     "unknown attribute",
+			resource: `{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"], "userName": "bjensen", "shoeSize": 42}`,
+			err:      &Error{ScimType: InvalidSyntax, Path: "shoeSize", Detail: "unknown attribute"},
+		},
+		{
+			name:     "unknown sub-attribute",
+			resource: `{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"], "userName": "bjensen", "name": {"nickName": "Babs"}}`,
+			err:      &Error{ScimType: InvalidSyntax, Path: "name.nickName", Detail: "unknown attribute"},
+		},
+		{
+			name:     "missing required attribute",
+			resource: `{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"], "userName": null}`,
+			err:      &Error{ScimType: InvalidValue, Path: "userName", Detail: "the attribute is required"},
+		},
+		{
+			name:     "wrong type",
+			resource: `{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"], "userName": "bjensen", "active": "yes"}`,
+			err:      &Error{ScimType: InvalidValue, Path: "active", Detail: "the value must be of type boolean"},
+		},
+		{
+			name:     "single value for a multi-valued attribute",
+			resource: `{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"], "userName": "bjensen", "emails": {"value": "bjensen@example.com"}}`,
+			err:      &Error{ScimType: InvalidValue, Path: "emails", Detail: "the attribute is multi-valued, its value must be a list"},
+		},
+		{
+			name:     "list for a single-valued attribute",
+			resource: `{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"], "userName": ["bjensen"]}`,
+			err:      &Error{ScimType: InvalidValue, Path: "userName", Detail: "the attribute is single-valued"},
+		},
+		{
+			name:     "non-canonical value",
+			resource: `{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"], "userName": "bjensen", "emails": [{"value": "bjensen@example.com", "type": "private"}]}`,
+			err:      &Error{ScimType: InvalidValue, Path: "emails.type", Detail: "the value must be one of work, home, other"},
+		},
+		{
+			name:     "several primary values",
+			resource: `{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"], "userName": "bjensen", "emails": [{"value": "bjensen@example.com", "primary": true}, {"value": "babs@example.com", "primary": true}]}`,
+			err:      &Error{ScimType: InvalidValue, Path: "emails.primary", Detail: "only one value can be primary"},
+		},
+		{
+			name:     "read-only attribute",
+			resource: `{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"], "userName": "bjensen", "groups": [{"value": "e9e30dba"}]}`,
+			err:      &Error{ScimType: Mutability, Path: "groups", Detail: "the attribute is read-only"},
+		},
+		{
+			name:     "read-only attribute of a replaced resource",
+			resource: `{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"], "userName": "bjensen", "groups": [{"value": "e9e30dba", "display": "Tour Guides"}]}`,
+			current:  current,
+		},
+		{
+			name:     "extension attribute",
+			resource: `{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"], "userName": "bjensen", "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": {"employeeNumber": 701984}}`,
+			err:      &Error{ScimType: InvalidValue, Path: "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber", Detail: "the value must be of type string"},
+		},
+		{
+			name:     "unknown extension",
+			resource: `{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"], "userName": "bjensen", "urn:example:unknown": {}}`,
+			err:      &Error{ScimType: InvalidSyntax, Path: "urn:example:unknown", Detail: "unknown schema extension"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var currentValues map[string]interface{}
+			if tc.current != "" {
+				currentValues = decode(t, tc.current)
+			}
+
+			err := Validate(decode(t, tc.resource), schema.User, []schema.Schema{schema.EnterpriseUser}, currentValues)
+			if tc.err == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.Equal(t, tc.err, err)
+			}
+		})
+	}
+}
+
+func TestValidateGroup(t *testing.T) {
+	tests := []struct {
+		name     string
+		resource string
+		err      *Error
+	}{
+		{
+			name:     "valid group",
+			resource: `{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:Group"], "displayName": "Tour Guides", "members": [{"value": "2819c223", "display": "Babs Jensen", "type": "User"}]}`,
+		},
+		{
+			name:     "missing display name",
+			resource: `{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:Group"], "members": []}`,
+			err:      &Error{ScimType: InvalidValue, Path: "displayName", Detail: "the attribute is required"},
+		},
+		{
+			name:     "member is not an object",
+			resource: `{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:Group"], "displayName": "Tour Guides", "members": ["2819c223"]}`,
+			err:      &Error{ScimType: InvalidValue, Path: "members", Detail: "the value must be of type complex"},
+		},
+		{
+			name:     "non-canonical member type",
+			resource: `{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:Group"], "displayName": "Tour Guides", "members": [{"value": "2819c223", "type": "Robot"}]}`,
+			err:      &Error{ScimType: InvalidValue, Path: "members.type", Detail: "the value must be one of User, Group"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Validate(decode(t, tc.resource), schema.Group, nil, nil)
+			if tc.err == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.Equal(t, tc.err, err)
+			}
+		})
+	}
+}
+
+func TestImmutableAttribute(t *testing.T) {
+	immutable := schema.Schema{
+		ID: "urn:example:Device",
+		Attributes: []schema.Attribute{
+			{Name: "serialNumber", Type: schema.String, Mutability: schema.Immutable},
+		},
+	}
+	resource := map[string]interface{}{"schemas": []interface{}{"urn:example:Device"}, "serialNumber": "B"}
+
+	assert.NoError(t, Validate(resource, immutable, nil, nil))
+	assert.NoError(t, Validate(resource, immutable, nil, map[string]interface{}{"serialNumber": "B"}))
+	assert.Equal(t,
+		&Error{ScimType: Mutability, Path: "serialNumber", Detail: "the attribute is immutable"},
+		Validate(resource, immutable, nil, map[string]interface{}{"serialNumber": "A"}))
+}